@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// cliArgs holds positional arguments and flags parsed from a subcommand's arguments.
+type cliArgs struct {
+	positional []string
+	values     map[string][]string
+	bools      map[string]bool
+}
+
+// parseArgs splits args into positional arguments and flags. valueFlags take a
+// value (--name value or --name=value) and may be repeated; boolFlags take none.
+// Flags may appear anywhere; "--" ends flag parsing.
+func parseArgs(args []string, valueFlags, boolFlags []string) (cliArgs, error) {
+	result := cliArgs{
+		values: make(map[string][]string),
+		bools:  make(map[string]bool),
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			result.positional = append(result.positional, args[i+1:]...)
+			break
+		}
+		if !strings.HasPrefix(arg, "--") {
+			result.positional = append(result.positional, arg)
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		switch {
+		case slices.Contains(valueFlags, name):
+			if !hasValue {
+				if i+1 >= len(args) {
+					return result, fmt.Errorf("flag --%s requires a value", name)
+				}
+				i++
+				value = args[i]
+			}
+			result.values[name] = append(result.values[name], value)
+		case slices.Contains(boolFlags, name):
+			if hasValue {
+				return result, fmt.Errorf("flag --%s does not take a value", name)
+			}
+			result.bools[name] = true
+		default:
+			return result, fmt.Errorf("unknown flag --%s", name)
+		}
+	}
+
+	return result, nil
+}
+
+// value returns the last value given for a flag, or def if it was not set.
+func (a cliArgs) value(name, def string) string {
+	if v := a.values[name]; len(v) > 0 {
+		return v[len(v)-1]
+	}
+	return def
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/sammcj/bkmk/internal/config"
+)
+
+const backupUsage = "Usage: bkmk backup list | diff <n> | restore <n>"
+
+func manageBackups() {
+	parsed, err := parseArgs(os.Args[2:], nil, nil)
+	if err == nil && len(parsed.positional) == 0 {
+		err = fmt.Errorf("expected a subcommand")
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintln(os.Stderr, backupUsage)
+		os.Exit(1)
+	}
+
+	path, err := config.Path()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	backups, err := config.ListBackupsFor(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing backups: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch sub, args := parsed.positional[0], parsed.positional[1:]; sub {
+	case "list", "ls":
+		listBackups(path, backups)
+	case "diff":
+		backup := pickBackup(backups, args)
+		diff, err := config.DiffBackup(path, backup.Path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		printBackupDiff(backup, diff)
+	case "restore":
+		backup := pickBackup(backups, args)
+		if err := config.RestoreBackupTo(path, backup.Path); err != nil {
+			fmt.Fprintf(os.Stderr, "Error restoring backup: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Restored backup from %s (the previous config was backed up first)\n", formatBackupTime(backup.Time))
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown backup command: %s\n", sub)
+		fmt.Fprintln(os.Stderr, backupUsage)
+		os.Exit(1)
+	}
+}
+
+// pickBackup returns the backup numbered by args[0] as shown by `backup list`,
+// where 1 is the newest. Exits with usage on error.
+func pickBackup(backups []config.Backup, args []string) config.Backup {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, backupUsage)
+		os.Exit(1)
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 1 || n > len(backups) {
+		fmt.Fprintf(os.Stderr, "Error: no backup %q (run 'bkmk backup list' to see %d backup(s))\n", args[0], len(backups))
+		os.Exit(1)
+	}
+	return backups[n-1]
+}
+
+func listBackups(path string, backups []config.Backup) {
+	if len(backups) == 0 {
+		fmt.Println("No backups yet. One is taken each time the config is saved.")
+		return
+	}
+
+	header := []string{"N", "TIME", "CHANGES"}
+	rows := make([][]string, len(backups))
+	for i, b := range backups {
+		changes := "unreadable"
+		if diff, err := config.DiffBackup(path, b.Path); err == nil {
+			changes = diff.Summary()
+		}
+		rows[i] = []string{strconv.Itoa(i + 1), formatBackupTime(b.Time), changes}
+	}
+	if err := writeRows(os.Stdout, formatTable, header, rows); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("\nCHANGES counts commands added (+), removed (-) and changed (~) in the current config since the backup.")
+}
+
+func printBackupDiff(backup config.Backup, diff config.BackupDiff) {
+	fmt.Printf("Changes since backup of %s:\n", formatBackupTime(backup.Time))
+	if diff.Empty() {
+		fmt.Println("  (none)")
+		return
+	}
+	for _, cmd := range diff.Added {
+		fmt.Printf("+ [%d] %s/%s: %s\n", cmd.ID, cmd.GroupName, cmd.Name, cmd.Summary())
+	}
+	for _, cmd := range diff.Removed {
+		fmt.Printf("- [%d] %s/%s: %s\n", cmd.ID, cmd.GroupName, cmd.Name, cmd.Summary())
+	}
+	for _, change := range diff.Changed {
+		fmt.Printf("~ [%d] %s/%s: %s\n", change.Old.ID, change.Old.GroupName, change.Old.Name, change.Old.Summary())
+		fmt.Printf("    now %s/%s: %s\n", change.New.GroupName, change.New.Name, change.New.Summary())
+	}
+}
+
+func formatBackupTime(t time.Time) string {
+	return t.Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sammcj/bkmk/internal/config"
+	"github.com/sammcj/bkmk/internal/runlog"
+	"github.com/sammcj/bkmk/internal/runner"
+)
+
+// logRecord is one run in `bkmk log` output.
+type logRecord struct {
+	ID        int       `json:"id" yaml:"id"`
+	Name      string    `json:"name" yaml:"name"`
+	Step      string    `json:"step,omitempty" yaml:"step,omitempty"`
+	Command   string    `json:"command" yaml:"command"`
+	Dir       string    `json:"dir,omitempty" yaml:"dir,omitempty"`
+	Started   time.Time `json:"started" yaml:"started"`
+	Seconds   float64   `json:"duration_seconds" yaml:"duration_seconds"`
+	Exit      int       `json:"exit" yaml:"exit"`
+	Output    string    `json:"output,omitempty" yaml:"output,omitempty"`
+	Truncated bool      `json:"truncated,omitempty" yaml:"truncated,omitempty"`
+}
+
+// loadRunLog loads the run log, or returns nil if the config turns it off.
+// Like usage it is best-effort: if the log cannot be read, a warning is
+// printed and runs go unrecorded.
+func loadRunLog(cfg *config.Config) *runlog.Log {
+	if cfg.Log.Disabled {
+		return nil
+	}
+	log, err := runlog.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: run log unavailable: %v\n", err)
+		return nil
+	}
+	warnSkipped(log)
+	if cfg.Log.MaxOutput > 0 {
+		log.MaxOutput = cfg.Log.MaxOutput
+	}
+	return log
+}
+
+// warnSkipped mentions any lines of the log that could not be read.
+func warnSkipped(log *runlog.Log) {
+	if n := log.Skipped(); n > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: skipped %d unreadable line(s) in %s\n", n, log.Path())
+	}
+}
+
+// runLogged runs command like runner.RunCommandWith and records the run of
+// cmd, or of its step, in log. Output is also captured on its way to the
+// terminal when the config asks for it.
+func runLogged(cfg *config.Config, log *runlog.Log, cmd config.FlatCommand, step, command string, env config.Environment) error {
+	c := runner.ShellCommandWith(command, runner.Options(env))
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	var output *runlog.TailWriter
+	if log != nil && cfg.Log.CaptureOutput {
+		output = runlog.NewTailWriter(log.MaxOutput)
+		c.Stdout = io.MultiWriter(os.Stdout, output)
+		c.Stderr = io.MultiWriter(os.Stderr, output)
+	}
+
+	started := time.Now()
+	err := c.Run()
+	if log == nil {
+		return err
+	}
+	entry := runlog.Entry{
+		ID: cmd.ID, Name: cmd.Name, Step: step, Command: command, Dir: runlog.WorkDir(env.Dir),
+		Started: started, Duration: time.Since(started), Exit: runner.ExitCode(err),
+	}
+	if output != nil {
+		entry.Output = output.String()
+	}
+	if logErr := log.Append(entry); logErr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record run: %v\n", logErr)
+	}
+	return err
+}
+
+func showLog() {
+	usage := "Usage: bkmk log [--id <id>] [--failed] [--limit <n>] [--output] [--format text|table|tsv|json|yaml]"
+	parsed, err := parseArgs(os.Args[2:], []string{"id", "limit", "format"}, []string{"failed", "output"})
+	if err == nil && len(parsed.positional) > 0 {
+		err = fmt.Errorf("unexpected argument %q", parsed.positional[0])
+	}
+	var format string
+	if err == nil {
+		format, err = parseFormat(parsed.value("format", formatText))
+	}
+	id := 0
+	if err == nil && parsed.value("id", "") != "" {
+		if id, err = strconv.Atoi(parsed.value("id", "")); err != nil {
+			err = fmt.Errorf("--id must be a command ID, got %q", parsed.value("id", ""))
+		}
+	}
+	limit := 0
+	if err == nil {
+		if limit, err = strconv.Atoi(parsed.value("limit", "20")); err == nil && limit < 0 {
+			err = fmt.Errorf("--limit cannot be negative")
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(1)
+	}
+
+	log, err := runlog.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading run log: %v\n", err)
+		os.Exit(1)
+	}
+	warnSkipped(log)
+
+	records := []logRecord{}
+	entries := log.Entries()
+	for _, e := range slices.Backward(entries) {
+		if (id != 0 && e.ID != id) || (parsed.bools["failed"] && !e.Failed()) {
+			continue
+		}
+		records = append(records, logRecord{
+			ID: e.ID, Name: e.Name, Step: e.Step, Command: e.Command, Dir: e.Dir,
+			Started: e.Started, Seconds: e.Duration.Seconds(), Exit: e.Exit,
+			Output: e.Output, Truncated: e.Truncated,
+		})
+		if limit > 0 && len(records) == limit {
+			break
+		}
+	}
+
+	switch format {
+	case formatJSON:
+		err = writeJSON(os.Stdout, records)
+	case formatYAML:
+		err = writeYAML(os.Stdout, records)
+	case formatText:
+		if len(records) == 0 {
+			fmt.Println("No runs recorded yet. Run a bookmark and it will show up here.")
+			return
+		}
+		for i, r := range records {
+			if i > 0 {
+				fmt.Println()
+			}
+			printLogRecord(r, parsed.bools["output"])
+		}
+	default:
+		header := []string{"STARTED", "ID", "NAME", "EXIT", "DURATION", "DIR", "COMMAND"}
+		rows := make([][]string, len(records))
+		for i, r := range records {
+			name := r.Name
+			if r.Step != "" {
+				name += " › " + r.Step
+			}
+			rows[i] = []string{r.Started.Local().Format(time.RFC3339), strconv.Itoa(r.ID), name, strconv.Itoa(r.Exit),
+				formatDuration(r.Seconds), r.Dir, r.Command}
+		}
+		err = writeRows(os.Stdout, format, header, rows)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// printLogRecord prints a run as a heading line followed by its command and,
+// if asked for, its captured output.
+func printLogRecord(r logRecord, output bool) {
+	name := r.Name
+	if r.Step != "" {
+		name += " › " + r.Step
+	}
+	status := "ok"
+	if r.Exit != 0 {
+		status = fmt.Sprintf("exit %d", r.Exit)
+	}
+	fmt.Printf("%s  [%d] %s  %s after %s", r.Started.Local().Format("2006-01-02 15:04:05"), r.ID, name, status, formatDuration(r.Seconds))
+	if r.Dir != "" {
+		fmt.Printf("  in %s", r.Dir)
+	}
+	fmt.Println()
+	for line := range strings.SplitSeq(r.Command, "\n") {
+		fmt.Println("  $ " + line)
+	}
+	if !output {
+		return
+	}
+	if r.Output == "" {
+		fmt.Println("    (no output captured)")
+		return
+	}
+	if r.Truncated {
+		fmt.Println("    (only the end of the output was kept)")
+	}
+	for line := range strings.SplitSeq(strings.TrimRight(r.Output, "\n"), "\n") {
+		fmt.Println("    " + line)
+	}
+}
+
+func formatDuration(seconds float64) string {
+	return time.Duration(seconds * float64(time.Second)).Round(time.Millisecond).String()
+}
@@ -8,7 +8,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/sammcj/bkmk/internal/config"
 	"github.com/sammcj/bkmk/internal/history"
-	"github.com/sammcj/bkmk/internal/runner"
+	"github.com/sammcj/bkmk/internal/params"
 	"github.com/sammcj/bkmk/internal/tui"
 )
 
@@ -20,8 +20,15 @@ var (
 )
 
 func main() {
-	if len(os.Args) < 2 {
-		runTUI()
+	args, err := takeConfigFlag(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	os.Args = append(os.Args[:1], args...)
+
+	if len(os.Args) < 2 || strings.HasPrefix(os.Args[1], "--set") {
+		runTUI(os.Args[1:])
 		return
 	}
 
@@ -34,14 +41,34 @@ func main() {
 		removeGroup()
 	case "remove", "rm", "--remove":
 		removeCommand()
+	case "run", "r", "--run":
+		runBookmark()
+	case "copy", "cp", "--copy":
+		copyBookmark()
+	case "show", "--show":
+		showBookmark()
+	case "select", "--select":
+		selectCommand()
+	case "init", "--init":
+		initShell()
 	case "list", "ls", "--list":
 		listAll()
+	case "export", "--export":
+		exportCommands()
+	case "import", "--import":
+		importCommands()
 	case "history", "hist", "--history":
 		runHistoryTUI()
 	case "last", "-l", "--last":
 		addLastCommand()
 	case "suggest", "freq", "--suggest":
 		suggestCommands()
+	case "stats":
+		showStats()
+	case "log":
+		showLog()
+	case "backup", "backups":
+		manageBackups()
 	case "version", "-v", "--version":
 		printVersion()
 	case "help", "-h", "--help":
@@ -53,14 +80,50 @@ func main() {
 	}
 }
 
-func runTUI() {
+// takeConfigFlag applies a leading --config <path>, which selects the config
+// file for any subcommand, and returns the remaining arguments.
+func takeConfigFlag(args []string) ([]string, error) {
+	for len(args) > 0 {
+		name, value, hasValue := strings.Cut(args[0], "=")
+		if name != "--config" {
+			break
+		}
+		if !hasValue {
+			if len(args) < 2 {
+				return nil, fmt.Errorf("flag --config requires a value")
+			}
+			value = args[1]
+			args = args[1:]
+		}
+		config.SetPath(value)
+		args = args[1:]
+	}
+	return args, nil
+}
+
+func runTUI(args []string) {
+	parsed, err := parseArgs(args, []string{"set"}, nil)
+	if err == nil && len(parsed.positional) > 0 {
+		err = fmt.Errorf("unexpected argument %q", parsed.positional[0])
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	values, err := params.ParseAssignments(parsed.values["set"])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	cfg, err := config.Load()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
 		os.Exit(1)
 	}
 
-	m := tui.New(cfg)
+	runLog := loadRunLog(cfg)
+	m := tui.New(cfg, tui.WithParamValues(values), tui.WithUsage(loadUsage()), tui.WithRunLog(runLog))
 	p := tea.NewProgram(m, tea.WithAltScreen())
 
 	finalModel, err := p.Run()
@@ -85,25 +148,63 @@ func runTUI() {
 	if selected != nil {
 		switch result {
 		case "run":
-			fmt.Printf("Running: %s\n", selected.Command)
-			if err := runner.RunCommand(selected.Command); err != nil {
-				fmt.Fprintf(os.Stderr, "Error running command: %v\n", err)
+			env, err := cfg.Environment(*selected)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 				os.Exit(1)
 			}
+			fmt.Printf("Running%s: %s\n", runningIn(env), selected.Command)
+			if err := runLogged(cfg, runLog, *selected, "", selected.Command, env); err != nil {
+				exitForRun(err)
+			}
 		case "Copied to clipboard":
 			fmt.Println("Copied to clipboard:", selected.Command)
+		case "insert":
+			fmt.Println(selected.Command)
 		}
 	}
 }
 
+// shellUsage documents the --shell flag shared by the history commands.
+var shellUsage = "[--shell " + strings.Join(history.Names(), "|") + "]"
+
+// historyArgs parses the arguments of a command that reads shell history,
+// applying --shell. It exits with usage on error.
+func historyArgs(usage string, valueFlags, boolFlags []string) cliArgs {
+	parsed, err := parseArgs(os.Args[2:], append(valueFlags, "shell"), boolFlags)
+	if err == nil && len(parsed.positional) > 0 {
+		err = fmt.Errorf("unexpected argument %q", parsed.positional[0])
+	}
+	if shell := parsed.value("shell", ""); err == nil && shell != "" {
+		err = history.SetShell(shell)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(1)
+	}
+	return parsed
+}
+
 func runHistoryTUI() {
+	parsed := historyArgs("Usage: bkmk history [--success] [--here] "+shellUsage, nil, []string{"success", "here"})
+	filter := history.Filter{Successful: parsed.bools["success"]}
+	if parsed.bools["here"] {
+		dir, err := os.Getwd()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		filter.Dir = dir
+	}
+
 	cfg, err := config.Load()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
 		os.Exit(1)
 	}
 
-	m := tui.NewWithHistory(cfg)
+	m := tui.NewWithHistory(cfg, tui.WithUsage(loadUsage()), tui.WithHistoryFilter(filter))
 	p := tea.NewProgram(m, tea.WithAltScreen())
 
 	_, err = p.Run()
@@ -114,6 +215,8 @@ func runHistoryTUI() {
 }
 
 func addLastCommand() {
+	parsed := historyArgs("Usage: bkmk last [--auto] "+shellUsage, nil, []string{"auto"})
+
 	// Get recent commands from shell history, filtering out bkmk commands
 	entries, err := history.ReadHistory(20)
 	if err != nil {
@@ -135,13 +238,37 @@ func addLastCommand() {
 		os.Exit(1)
 	}
 
+	if parsed.bools["auto"] {
+		// Save with the suggested group and name, without asking
+		var group, name string
+		var id int
+		if _, err := config.Update(func(cfg *config.Config) error {
+			group = cfg.SuggestGroup(lastCmd)
+			name = cfg.SuggestName(group, lastCmd)
+			if err := cfg.AddCommand(group, name, lastCmd, ""); err != nil {
+				return err
+			}
+			cmd, err := cfg.GetCommand(group, name)
+			if err != nil {
+				return err
+			}
+			id = cmd.ID
+			return nil
+		}); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Command %q added to group %q as [%d]: %s\n", name, group, id, lastCmd)
+		return
+	}
+
 	cfg, err := config.Load()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
 		os.Exit(1)
 	}
 
-	m := tui.NewWithLastCommand(cfg, lastCmd)
+	m := tui.NewWithLastCommand(cfg, lastCmd, tui.WithUsage(loadUsage()))
 	p := tea.NewProgram(m, tea.WithAltScreen())
 
 	_, err = p.Run()
@@ -158,55 +285,39 @@ func addGroup() {
 	}
 
 	name := os.Args[2]
-	cfg, err := config.Load()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
-		os.Exit(1)
-	}
-
-	if err := cfg.AddGroup(name); err != nil {
+	if _, err := config.Update(func(cfg *config.Config) error {
+		return cfg.AddGroup(name)
+	}); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	if err := cfg.Save(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error saving config: %v\n", err)
-		os.Exit(1)
-	}
-
 	fmt.Printf("Group %q created\n", name)
 }
 
 func addCommand() {
-	if len(os.Args) < 5 {
-		fmt.Fprintln(os.Stderr, "Usage: bkmk add <group> <name> <command> [description]")
+	parsed, err := parseArgs(os.Args[2:], []string{"tag"}, nil)
+	if err != nil || len(parsed.positional) < 3 {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		fmt.Fprintln(os.Stderr, "Usage: bkmk add <group> <name> <command> [description] [--tag <tag>]...")
 		os.Exit(1)
 	}
 
-	groupName := os.Args[2]
-	cmdName := os.Args[3]
-	command := os.Args[4]
-	var description string
-	if len(os.Args) > 5 {
-		description = strings.Join(os.Args[5:], " ")
-	}
-
-	cfg, err := config.Load()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
-		os.Exit(1)
-	}
+	groupName := parsed.positional[0]
+	cmdName := parsed.positional[1]
+	command := parsed.positional[2]
+	description := strings.Join(parsed.positional[3:], " ")
+	tags := config.ParseTags(strings.Join(parsed.values["tag"], ","))
 
-	if err := cfg.AddCommand(groupName, cmdName, command, description); err != nil {
+	if _, err := config.Update(func(cfg *config.Config) error {
+		return cfg.AddCommandWithTags(groupName, cmdName, command, description, config.ActionNone, tags)
+	}); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	if err := cfg.Save(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error saving config: %v\n", err)
-		os.Exit(1)
-	}
-
 	fmt.Printf("Command %q added to group %q\n", cmdName, groupName)
 }
 
@@ -217,22 +328,13 @@ func removeGroup() {
 	}
 
 	name := os.Args[2]
-	cfg, err := config.Load()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
-		os.Exit(1)
-	}
-
-	if err := cfg.RemoveGroup(name); err != nil {
+	if _, err := config.Update(func(cfg *config.Config) error {
+		return cfg.RemoveGroup(name)
+	}); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	if err := cfg.Save(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error saving config: %v\n", err)
-		os.Exit(1)
-	}
-
 	fmt.Printf("Group %q removed\n", name)
 }
 
@@ -245,83 +347,91 @@ func removeCommand() {
 	groupName := os.Args[2]
 	cmdName := os.Args[3]
 
-	cfg, err := config.Load()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
-		os.Exit(1)
-	}
-
-	if err := cfg.RemoveCommand(groupName, cmdName); err != nil {
+	if _, err := config.Update(func(cfg *config.Config) error {
+		return cfg.RemoveCommand(groupName, cmdName)
+	}); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	if err := cfg.Save(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error saving config: %v\n", err)
-		os.Exit(1)
-	}
-
 	fmt.Printf("Command %q removed from group %q\n", cmdName, groupName)
 }
 
 func listAll() {
+	parsed, err := parseArgs(os.Args[2:], []string{"format", "group", "tag", "match"}, nil)
+	if err == nil && len(parsed.positional) > 0 {
+		err = fmt.Errorf("unexpected argument %q", parsed.positional[0])
+	}
+	var format string
+	if err == nil {
+		format, err = parseFormat(parsed.value("format", formatText))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintln(os.Stderr, "Usage: bkmk list [--format text|table|tsv|json|yaml] [--group <group>] [--tag <tag>]... [--match <text>]")
+		os.Exit(1)
+	}
+	group := parsed.value("group", "")
+	tags := config.ParseTags(strings.Join(parsed.values["tag"], ","))
+	match := parsed.value("match", "")
+
 	cfg, err := config.Load()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
 		os.Exit(1)
 	}
 
+	if format != formatText {
+		cmds := filterCommands(cfg.FlatCommands(), group, tags, match)
+		if err := writeCommands(os.Stdout, format, cmds); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	if len(cfg.Groups) == 0 {
 		fmt.Println("No groups configured.")
 		return
 	}
 
-	for _, g := range cfg.Groups {
-		fmt.Printf("\n[%s]\n", g.Name)
-		if len(g.Commands) == 0 {
-			fmt.Println("  (no commands)")
+	filtered := group != "" || len(tags) > 0 || match != ""
+	for _, path := range cfg.GroupPaths() {
+		if group != "" && !config.IsWithin(path, group) {
 			continue
 		}
+		g := cfg.GetGroup(path)
+		var cmds []config.FlatCommand
 		for _, cmd := range g.Commands {
-			fmt.Printf("  [%d] %s: %s\n", cmd.ID, cmd.Name, cmd.Command)
+			cmds = append(cmds, cmd.Flatten(path))
+		}
+		cmds = filterCommands(cmds, "", tags, match)
+		// Groups that only hold subgroups are shown through their subgroups
+		if (filtered || len(g.Groups) > 0) && len(cmds) == 0 {
+			continue
+		}
+
+		fmt.Printf("\n[%s]\n", path)
+		if len(cmds) == 0 {
+			fmt.Println("  (no commands)")
+			continue
+		}
+		for _, cmd := range cmds {
+			fmt.Printf("  [%d] %s: %s\n", cmd.ID, cmd.Name, cmd.Summary())
 			if cmd.Description != "" {
 				fmt.Printf("      # %s\n", cmd.Description)
 			}
+			if len(cmd.Tags) > 0 {
+				fmt.Printf("      tags: %s\n", strings.Join(cmd.Tags, ", "))
+			}
+			if cmd.Source != "" {
+				fmt.Printf("      from: %s (read-only)\n", cmd.Source)
+			}
 		}
 	}
 	fmt.Println()
 }
 
-func suggestCommands() {
-	const (
-		defaultDays    = 90
-		defaultMinArgs = 2
-		defaultLimit   = 40
-	)
-
-	commands, err := history.GetFrequentCommands(defaultDays, defaultMinArgs, defaultLimit)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error reading shell history: %v\n", err)
-		os.Exit(1)
-	}
-
-	if len(commands) == 0 {
-		fmt.Println("No frequently used commands found matching criteria.")
-		fmt.Println("(Looking for commands with 2+ arguments from the last 60 days)")
-		return
-	}
-
-	fmt.Println("Frequently used commands (good candidates for bookmarking):")
-	fmt.Println()
-
-	for i, cmd := range commands {
-		fmt.Printf("%2d. [%dx] %s\n", i+1, cmd.Count, cmd.Command)
-	}
-
-	fmt.Println()
-	fmt.Println("Add one with: bkmk add <group> \"<name>\" \"<command>\"")
-}
-
 func printVersion() {
 	fmt.Printf("bkmk %s (commit: %s, built: %s)\n", Version, Commit, BuildDate)
 }
@@ -330,16 +440,49 @@ func printHelp() {
 	help := `bkmk - Command Bookmark Manager
 
 Usage:
+  bkmk [--config <file>] <command>  Use another config file (or set BKMK_CONFIG)
   bkmk                              Launch interactive TUI
+       [--set name=value ...]       Pre-fill {{placeholder}} values
   bkmk add-group <name>             Create a new group (alias: ag)
+                                    Use a path such as cloud/aws to nest groups
   bkmk add <group> <name> <cmd>     Add a command to a group (alias: a)
-       [description]
+       [description] [--tag <tag> ...]
   bkmk remove-group <name>          Remove a group (alias: rg)
   bkmk remove <group> <name>        Remove a command (alias: rm)
+  bkmk run <id>                     Run a command or workflow by ID (alias: r)
+  bkmk run <group> <name>           Run a command by group and name
+       [--set name=value ...]       Fill in {{placeholder}} values
+                                    Runs in the bookmark's cwd with its env
+       [--yes]                      Skip confirming a dangerous command
+  bkmk copy <id>                    Copy a command to the clipboard (alias: cp)
+  bkmk show <id>                    Print a command without running it
+  bkmk select                       Pick a command and print it to stdout
+  bkmk init <zsh|bash|fish>         Print shell integration (Ctrl+B widget)
+       [--key ctrl-<letter>|alt-<letter>]  Bind the widget to another key
   bkmk list                         List all groups and commands (alias: ls)
+       [--group <group>] [--tag <tag> ...] [--match <text>]
+  bkmk export [--group <group> ...] Write bookmarks as YAML to stdout
+       [--output <file>]
+  bkmk import <file|->              Import bookmarks exported by bkmk
+       [--merge|--replace] [--into <group>] [--dry-run]
+  bkmk import --from <format> <path> Import pet, navi or markdown (tldr) snippets
   bkmk history                      Browse shell history to add commands (alias: hist)
+       [--success] [--here]         Only commands that succeeded / ran in this directory
   bkmk last                         Bookmark the last command from shell history (alias: -l)
+       [--auto]                     Save it with a suggested name and group, without asking
   bkmk suggest                      Show frequently used commands to bookmark (alias: freq)
+       [--format text|table|tsv|json|yaml] [--match <text>]
+       [--days <n>] [--min-args <n>] [--limit <n>]  Defaults: 90 days, 2 args, 40 results
+       [--interactive]              Pick suggestions and bookmark them in the TUI
+       history, last and suggest accept --shell zsh|bash|fish|nushell|powershell|atuin
+  bkmk stats                        Show how often and how recently bookmarks were used
+       [--sort uses|recent] [--limit <n>] [--format ...]
+  bkmk log                          Show past runs with exit codes and durations, newest first
+       [--id <id>] [--failed]       Only runs of one bookmark / runs that failed
+       [--limit <n>] [--output] [--format ...]  Defaults to 20 runs; --output shows captured output
+  bkmk backup list                  List config backups, newest first, with changes since
+  bkmk backup diff <n>              Show commands changed since backup <n>
+  bkmk backup restore <n>           Restore backup <n> (the current config is backed up first)
   bkmk version                      Show version information
   bkmk help                         Show this help message
 
@@ -348,6 +491,10 @@ TUI Controls:
   Enter, Tab   Select group / execute command
   /            Search all commands (fuzzy)
   h            Browse shell history
+  t            Browse commands by tag
+  u            Most used / recently used commands (tab switches)
+  b            Browse and restore config backups
+  L            Past runs: re-run one or show its output
   a            Add group/command
   e            Edit command
   d            Delete (with confirmation)
@@ -357,12 +504,23 @@ TUI Controls:
 
 Examples:
   bkmk add-group docker
+  bkmk add cloud/aws whoami "aws sts get-caller-identity"
   bkmk add docker ps "docker ps -a" "List all containers"
   bkmk add docker logs "docker logs -f" "Follow container logs"
+  bkmk run 3                        # Exit status is the command's own
+  bkmk run docker ps
+  bkmk list --format json --group docker
+  bkmk list --tag debugging
   bkmk history
   bkmk last                         # Bookmark the command you just ran
-
-Config: ~/.config/bkmk/config.yaml
+  bkmk last --auto                  # ...straight into e.g. docker/docker-ps-a
+  bkmk suggest --shell fish
+  bkmk suggest --interactive --days 30
+  bkmk add k8s logs "kubectl logs -n {{ns:default}} {{pod}}"
+  bkmk --set ns=prod                # Prompt only for {{pod}}
+
+Config: ~/.config/bkmk/config.yaml, or $BKMK_CONFIG
+  include: [~/team-bkmk/*.yaml]     Merge in read-only commands from other files
 `
 	fmt.Print(help)
 }
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sammcj/bkmk/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats accepted by --format. formatText is the default human output.
+const (
+	formatText  = "text"
+	formatTable = "table"
+	formatTSV   = "tsv"
+	formatJSON  = "json"
+	formatYAML  = "yaml"
+)
+
+// parseFormat validates a --format value.
+func parseFormat(format string) (string, error) {
+	switch format {
+	case formatText, formatTable, formatTSV, formatJSON, formatYAML:
+		return format, nil
+	}
+	return "", fmt.Errorf("invalid format %q (valid: text, table, tsv, json, yaml)", format)
+}
+
+// filterCommands keeps commands in group or its subgroups (if set) that carry every tag in tags
+// and whose group, name, command, description or tags contain match
+// (case-insensitive, if set).
+func filterCommands(cmds []config.FlatCommand, group string, tags []string, match string) []config.FlatCommand {
+	match = strings.ToLower(match)
+	var result []config.FlatCommand
+	for _, cmd := range cmds {
+		if group != "" && !config.IsWithin(cmd.GroupName, group) {
+			continue
+		}
+		if slices.ContainsFunc(tags, func(tag string) bool { return !cmd.HasTag(tag) }) {
+			continue
+		}
+		if match != "" {
+			haystack := strings.ToLower(cmd.GroupName + " " + cmd.Name + " " + cmd.Command + " " + cmd.Description + " " + strings.Join(cmd.Tags, " "))
+			if !strings.Contains(haystack, match) {
+				continue
+			}
+		}
+		result = append(result, cmd)
+	}
+	return result
+}
+
+// writeCommands writes commands in a machine-readable format.
+func writeCommands(w io.Writer, format string, cmds []config.FlatCommand) error {
+	if cmds == nil {
+		cmds = []config.FlatCommand{}
+	}
+	switch format {
+	case formatJSON:
+		return writeJSON(w, cmds)
+	case formatYAML:
+		return writeYAML(w, cmds)
+	}
+
+	header := []string{"ID", "GROUP", "NAME", "COMMAND", "DESCRIPTION", "DEFAULT_ACTION", "TAGS"}
+	rows := make([][]string, len(cmds))
+	for i, cmd := range cmds {
+		rows[i] = []string{strconv.Itoa(cmd.ID), cmd.GroupName, cmd.Name, cmd.Summary(), cmd.Description, string(cmd.DefaultAction), strings.Join(cmd.Tags, ",")}
+	}
+	return writeRows(w, format, header, rows)
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func writeYAML(w io.Writer, v any) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// writeRows writes tab-separated rows without a header (tsv), or aligned
+// columns with a header (table).
+func writeRows(w io.Writer, format string, header []string, rows [][]string) error {
+	if format == formatTSV {
+		for _, row := range rows {
+			for i := range row {
+				row[i] = escapeTSV(row[i])
+			}
+			if _, err := fmt.Fprintln(w, strings.Join(row, "\t")); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		for i := range row {
+			row[i] = escapeTSV(row[i])
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// escapeTSV escapes characters that would break a tab-separated row.
+func escapeTSV(s string) string {
+	return strings.NewReplacer("\\", `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`).Replace(s)
+}
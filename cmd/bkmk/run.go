@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/sammcj/bkmk/internal/config"
+	"github.com/sammcj/bkmk/internal/params"
+	"github.com/sammcj/bkmk/internal/runner"
+)
+
+// resolveCommand finds a bookmark from either "<id>" or "<group> <name|id>".
+func resolveCommand(cfg *config.Config, args []string) (config.FlatCommand, error) {
+	switch len(args) {
+	case 1:
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			return config.FlatCommand{}, fmt.Errorf("%q is not a command ID (use <group> <name> to select by name)", args[0])
+		}
+		cmd, groupName := cfg.GetCommandByID(id)
+		if cmd == nil {
+			return config.FlatCommand{}, fmt.Errorf("command with ID %d not found", id)
+		}
+		return cmd.Flatten(groupName), nil
+	case 2:
+		cmd, err := cfg.GetCommand(args[0], args[1])
+		if err != nil {
+			return config.FlatCommand{}, err
+		}
+		return cmd.Flatten(args[0]), nil
+	}
+	return config.FlatCommand{}, fmt.Errorf("expected <id> or <group> <name>")
+}
+
+// loadCommandArgs parses a run/copy/show invocation and resolves the bookmark.
+// Exits with usage on error.
+func loadCommandArgs(usage string, valueFlags, boolFlags []string) (*config.Config, config.FlatCommand, cliArgs) {
+	parsed, err := parseArgs(os.Args[2:], append([]string{"set"}, valueFlags...), boolFlags)
+	if err != nil || len(parsed.positional) < 1 || len(parsed.positional) > 2 {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	cmd, err := resolveCommand(cfg, parsed.positional)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return cfg, cmd, parsed
+}
+
+// renderCommand fills in placeholders from --set values, prompting on the
+// terminal for any that are missing.
+func renderCommand(command string, sets []string) (string, error) {
+	values, err := promptValues(command, sets)
+	if err != nil {
+		return "", err
+	}
+	return params.Render(command, values)
+}
+
+// promptValues returns the values for the placeholders in command: those
+// given with --set, then any others read from the terminal.
+func promptValues(command string, sets []string) (map[string]string, error) {
+	values, err := params.ParseAssignments(sets)
+	if err != nil {
+		return nil, err
+	}
+
+	var reader *bufio.Reader
+	for _, p := range params.Parse(command) {
+		if _, ok := values[p.Name]; ok {
+			continue
+		}
+		if !isTerminal(os.Stdin) {
+			if p.HasDefault {
+				continue
+			}
+			return nil, fmt.Errorf("missing value for %s (use --set %s=value)", p.Name, p.Name)
+		}
+		if reader == nil {
+			reader = bufio.NewReader(os.Stdin)
+		}
+		prompt := p.Name
+		if p.HasDefault {
+			prompt += " [" + p.Default + "]"
+		}
+		fmt.Fprintf(os.Stderr, "%s: ", prompt)
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
+			return nil, fmt.Errorf("failed to read value for %s: %w", p.Name, err)
+		}
+		values[p.Name] = strings.TrimRight(line, "\r\n")
+	}
+	return values, nil
+}
+
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(f.Fd())
+}
+
+func runBookmark() {
+	cfg, cmd, parsed := loadCommandArgs("Usage: bkmk run <id> | <group> <name> [--set name=value ...] [--yes]", nil, []string{"yes"})
+	if cmd.IsWorkflow() {
+		runWorkflow(cfg, cmd, parsed.values["set"], parsed.bools["yes"])
+		return
+	}
+
+	env, err := cfg.Environment(cmd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	command, err := renderCommand(cmd.Command, parsed.values["set"])
+	if err == nil {
+		cmd.Command = command
+		err = confirmDanger(cfg, cmd, parsed.bools["yes"])
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	trackUsage(cmd.ID, config.ActionRun)
+	fmt.Fprintf(os.Stderr, "Running%s: %s\n", runningIn(env), command)
+	if err := runLogged(cfg, loadRunLog(cfg), cmd, "", command, env); err != nil {
+		exitForRun(err)
+	}
+}
+
+// exitForRun exits with the status of a failed run. Failures to start the
+// command at all, such as a missing shell, are printed first, since nothing
+// else reports them.
+func exitForRun(err error) {
+	reportRunError(err)
+	os.Exit(runner.ExitCode(err))
+}
+
+// reportRunError prints err unless it is only the command's own non-zero
+// exit, which the command has already had its say about.
+func reportRunError(err error) {
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+}
+
+// confirmDanger asks for a dangerous command's name to be typed before it
+// runs. Without a terminal to ask on, --yes is needed instead.
+func confirmDanger(cfg *config.Config, cmd config.FlatCommand, yes bool) error {
+	reason := cfg.Danger(cmd)
+	if reason == "" || yes {
+		return nil
+	}
+	if !isTerminal(os.Stdin) {
+		return fmt.Errorf("%q needs confirmation because %s (use --yes to run it anyway)", cmd.Name, reason)
+	}
+	fmt.Fprintf(os.Stderr, "%q is dangerous because %s.\nType its name to run it: ", cmd.Name, reason)
+	line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if strings.TrimSpace(line) != cmd.Name {
+		return fmt.Errorf("not confirmed, %q was not run", cmd.Name)
+	}
+	return nil
+}
+
+// runningIn describes a command's working directory for "Running" messages.
+func runningIn(env config.Environment) string {
+	if env.Dir == "" {
+		return ""
+	}
+	return " in " + env.Dir
+}
+
+func copyBookmark() {
+	_, cmd, parsed := loadCommandArgs("Usage: bkmk copy <id> | <group> <name> [--set name=value ...]", nil, nil)
+	if cmd.IsWorkflow() {
+		fmt.Fprintf(os.Stderr, "Error: %q is a workflow; run it with 'bkmk run %d'\n", cmd.Name, cmd.ID)
+		os.Exit(1)
+	}
+
+	command, err := renderCommand(cmd.Command, parsed.values["set"])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := runner.CopyToClipboard(command); err != nil {
+		fmt.Fprintf(os.Stderr, "Error copying to clipboard: %v\n", err)
+		os.Exit(1)
+	}
+	trackUsage(cmd.ID, config.ActionCopy)
+	fmt.Println("Copied to clipboard:", command)
+}
+
+func showBookmark() {
+	cfg, cmd, parsed := loadCommandArgs("Usage: bkmk show <id> | <group> <name> [--set name=value ...] [--format json|yaml|tsv|table]", []string{"format"}, nil)
+
+	// Only substitute placeholders when values are given, so scripts can read
+	// the raw template.
+	command := cmd.Command
+	if sets := parsed.values["set"]; len(sets) > 0 {
+		values, err := params.ParseAssignments(sets)
+		if err == nil {
+			command, err = params.Render(cmd.Command, values)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	format := parsed.value("format", formatText)
+	if format == formatText && cmd.IsWorkflow() {
+		if err := showWorkflow(cfg, cmd, parsed.values["set"]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if format == formatText {
+		fmt.Println(command)
+		return
+	}
+	if _, err := parseFormat(format); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	cmd.Command = command
+	var err error
+	switch format {
+	case formatJSON:
+		err = writeJSON(os.Stdout, cmd)
+	case formatYAML:
+		err = writeYAML(os.Stdout, cmd)
+	default:
+		err = writeCommands(os.Stdout, format, []config.FlatCommand{cmd})
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sammcj/bkmk/internal/config"
+	"github.com/sammcj/bkmk/internal/params"
+	"github.com/sammcj/bkmk/internal/shell"
+	"github.com/sammcj/bkmk/internal/tui"
+)
+
+func initShell() {
+	usage := "Usage: bkmk init <zsh|bash|fish> [--key ctrl-<letter>|alt-<letter>]"
+	parsed, err := parseArgs(os.Args[2:], []string{"key"}, nil)
+	if err == nil && len(parsed.positional) != 1 {
+		err = fmt.Errorf("expected one shell name")
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(1)
+	}
+
+	script, err := shell.ScriptWithKey(parsed.positional[0], parsed.value("key", shell.DefaultKey))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Print(script)
+}
+
+// selectCommand runs the TUI on /dev/tty and prints the chosen command to
+// stdout, so shell widgets can capture it with command substitution.
+func selectCommand() {
+	// --print was what widgets from earlier versions passed; it is the only
+	// behaviour, so it is still accepted for shells that loaded one of them.
+	parsed, err := parseArgs(os.Args[2:], []string{"set"}, []string{"print"})
+	if err == nil && len(parsed.positional) > 0 {
+		err = fmt.Errorf("unexpected argument %q", parsed.positional[0])
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintln(os.Stderr, "Usage: bkmk select [--set name=value ...]")
+		os.Exit(1)
+	}
+	values, err := params.ParseAssignments(parsed.values["set"])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening terminal: %v\n", err)
+		os.Exit(1)
+	}
+	defer tty.Close()
+
+	// Detect colours from the terminal, not the captured stdout
+	lipgloss.SetDefaultRenderer(lipgloss.NewRenderer(tty))
+
+	m := tui.New(cfg, tui.WithSelectMode(), tui.WithParamValues(values), tui.WithUsage(loadUsage()))
+	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithInput(tty), tea.WithOutput(tty))
+
+	finalModel, err := p.Run()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error running TUI: %v\n", err)
+		os.Exit(1)
+	}
+
+	var selected *config.FlatCommand
+	switch m := finalModel.(type) {
+	case tui.Model:
+		selected = m.Selected()
+	case *tui.Model:
+		selected = m.Selected()
+	}
+
+	if selected == nil {
+		// Non-zero so widgets can tell a cancelled selection apart
+		os.Exit(1)
+	}
+	fmt.Println(selected.Command)
+}
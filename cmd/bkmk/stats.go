@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/sammcj/bkmk/internal/config"
+	"github.com/sammcj/bkmk/internal/usage"
+)
+
+// statRecord is one row of `bkmk stats` output.
+type statRecord struct {
+	ID       int       `json:"id" yaml:"id"`
+	Group    string    `json:"group" yaml:"group"`
+	Name     string    `json:"name" yaml:"name"`
+	Runs     int       `json:"runs" yaml:"runs"`
+	Copies   int       `json:"copies" yaml:"copies"`
+	Inserts  int       `json:"inserts" yaml:"inserts"`
+	LastUsed time.Time `json:"last_used" yaml:"last_used"`
+}
+
+// loadUsage loads usage stats for the TUI. Usage is best-effort: if the file
+// cannot be read, a warning is printed and bkmk carries on without it.
+func loadUsage() *usage.Store {
+	store, err := usage.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: usage stats unavailable: %v\n", err)
+		return nil
+	}
+	return store
+}
+
+// trackUsage records one use of a bookmark from the CLI, warning on failure.
+func trackUsage(id int, action config.ActionType) {
+	if err := usage.Track(id, action); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record usage: %v\n", err)
+	}
+}
+
+func showStats() {
+	parsed, err := parseArgs(os.Args[2:], []string{"format", "sort", "limit"}, nil)
+	if err == nil && len(parsed.positional) > 0 {
+		err = fmt.Errorf("unexpected argument %q", parsed.positional[0])
+	}
+	var format string
+	if err == nil {
+		format, err = parseFormat(parsed.value("format", formatText))
+	}
+	sortBy := parsed.value("sort", "uses")
+	if err == nil && sortBy != "uses" && sortBy != "recent" {
+		err = fmt.Errorf("invalid sort %q (valid: uses, recent)", sortBy)
+	}
+	limit := 0
+	if err == nil {
+		if limit, err = strconv.Atoi(parsed.value("limit", "0")); err == nil && limit < 0 {
+			err = fmt.Errorf("--limit cannot be negative")
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintln(os.Stderr, "Usage: bkmk stats [--sort uses|recent] [--limit <n>] [--format text|table|tsv|json|yaml]")
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	store, err := usage.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading usage: %v\n", err)
+		os.Exit(1)
+	}
+
+	stats := store.All()
+	if sortBy == "recent" {
+		usage.SortByRecent(stats)
+	} else {
+		usage.SortByUses(stats)
+	}
+
+	var records []statRecord
+	for _, stat := range stats {
+		cmd, group := cfg.GetCommandByID(stat.ID)
+		if cmd == nil || stat.Uses() == 0 {
+			continue
+		}
+		records = append(records, statRecord{
+			ID: stat.ID, Group: group, Name: cmd.Name,
+			Runs: stat.Runs, Copies: stat.Copies, Inserts: stat.Inserts,
+			LastUsed: stat.LastUsed,
+		})
+		if limit > 0 && len(records) == limit {
+			break
+		}
+	}
+	if records == nil {
+		records = []statRecord{}
+	}
+
+	switch format {
+	case formatJSON:
+		err = writeJSON(os.Stdout, records)
+	case formatYAML:
+		err = writeYAML(os.Stdout, records)
+	case formatText:
+		if len(records) == 0 {
+			fmt.Println("No usage recorded yet. Run or copy a bookmark and it will show up here.")
+			return
+		}
+		format = formatTable
+		fallthrough
+	default:
+		header := []string{"ID", "GROUP", "NAME", "RUNS", "COPIES", "INSERTS", "LAST_USED"}
+		rows := make([][]string, len(records))
+		for i, r := range records {
+			rows[i] = []string{strconv.Itoa(r.ID), r.Group, r.Name, strconv.Itoa(r.Runs), strconv.Itoa(r.Copies),
+				strconv.Itoa(r.Inserts), r.LastUsed.Local().Format(time.RFC3339)}
+		}
+		err = writeRows(os.Stdout, format, header, rows)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+		os.Exit(1)
+	}
+}
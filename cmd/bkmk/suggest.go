@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sammcj/bkmk/internal/config"
+	"github.com/sammcj/bkmk/internal/history"
+	"github.com/sammcj/bkmk/internal/params"
+	"github.com/sammcj/bkmk/internal/tui"
+)
+
+// suggestion is a frequently used command with the bookmarks that already
+// run it, exactly or through placeholders.
+type suggestion struct {
+	history.FrequentCommand `yaml:",inline"`
+	CoveredBy               []int `json:"covered_by,omitempty" yaml:"covered_by,omitempty"`
+}
+
+func suggestCommands() {
+	const (
+		defaultDays    = 90
+		defaultMinArgs = 2
+		defaultLimit   = 40
+	)
+
+	const usage = "Usage: bkmk suggest [--interactive] [--days <n>] [--min-args <n>] [--limit <n>]\n" +
+		"                    [--format text|table|tsv|json|yaml] [--match <text>] "
+	parsed := historyArgs(usage+shellUsage, []string{"format", "match", "days", "min-args", "limit"}, []string{"interactive"})
+	format, err := parseFormat(parsed.value("format", formatText))
+	if err == nil && parsed.bools["interactive"] && format != formatText {
+		err = fmt.Errorf("--format cannot be used with --interactive")
+	}
+	var days, minArgs, limit int
+	if err == nil {
+		days, err = positiveFlag(parsed, "days", defaultDays)
+	}
+	if err == nil {
+		minArgs, err = positiveFlag(parsed, "min-args", defaultMinArgs)
+	}
+	if err == nil {
+		limit, err = positiveFlag(parsed, "limit", defaultLimit)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintln(os.Stderr, usage+shellUsage)
+		os.Exit(1)
+	}
+
+	commands, err := history.GetFrequentCommands(days, minArgs, 0)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading shell history: %v\n", err)
+		os.Exit(1)
+	}
+	commands = filterFrequent(commands, parsed.value("match", ""))
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	bookmarks := cfg.FlatCommands()
+	fresh, saved := splitCovered(commands, bookmarks)
+	if len(fresh) > limit {
+		fresh = fresh[:limit]
+	}
+
+	if parsed.bools["interactive"] {
+		runSuggestTUI(cfg, fresh)
+		return
+	}
+
+	if format != formatText {
+		if err := writeSuggestions(os.Stdout, format, append(fresh, saved...)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(fresh) == 0 {
+		fmt.Println("No frequently used commands found matching criteria.")
+		fmt.Printf("(Looking for commands with %d+ arguments from the last %d days)\n", minArgs, days)
+	} else {
+		fmt.Println("Frequently used commands (good candidates for bookmarking):")
+		fmt.Println()
+
+		for i, s := range fresh {
+			fmt.Printf("%2d. [%dx] %s\n", i+1, s.Count, s.Command)
+			if s.Template != "" {
+				fmt.Printf("             as template: %s (%d variants)\n", s.Template, s.Variants)
+			}
+		}
+	}
+
+	if len(saved) > 0 {
+		byID := make(map[int]config.FlatCommand, len(bookmarks))
+		for _, b := range bookmarks {
+			byID[b.ID] = b
+		}
+		fmt.Println()
+		fmt.Println("Already bookmarked:")
+		for _, s := range saved {
+			names := make([]string, len(s.CoveredBy))
+			for i, id := range s.CoveredBy {
+				names[i] = fmt.Sprintf("[%d] %s/%s", id, byID[id].GroupName, byID[id].Name)
+			}
+			fmt.Printf("    [%dx] %s -> %s\n", s.Count, s.Command, strings.Join(names, ", "))
+		}
+	}
+
+	if len(fresh) > 0 {
+		fmt.Println()
+		fmt.Println("Add one with: bkmk add <group> \"<name>\" \"<command>\"")
+		fmt.Println("or pick several with: bkmk suggest --interactive")
+	}
+}
+
+// positiveFlag returns the value of the named flag as a number of at least
+// one, or def if it was not given.
+func positiveFlag(parsed cliArgs, name string, def int) (int, error) {
+	value := parsed.value(name, "")
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("--%s must be a positive number, got %q", name, value)
+	}
+	return n, nil
+}
+
+// runSuggestTUI lists the suggestions in the TUI to be picked and bookmarked.
+func runSuggestTUI(cfg *config.Config, fresh []suggestion) {
+	if len(fresh) == 0 {
+		fmt.Println("No frequently used commands left to bookmark.")
+		return
+	}
+	commands := make([]history.FrequentCommand, len(fresh))
+	for i, s := range fresh {
+		commands[i] = s.FrequentCommand
+	}
+
+	m := tui.NewWithSuggestions(cfg, commands, tui.WithUsage(loadUsage()))
+	p := tea.NewProgram(m, tea.WithAltScreen())
+	if _, err := p.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error running TUI: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// filterFrequent keeps suggestions whose command or template contains match
+// (case-insensitive).
+func filterFrequent(cmds []history.FrequentCommand, match string) []history.FrequentCommand {
+	if match == "" {
+		return cmds
+	}
+	match = strings.ToLower(match)
+	var result []history.FrequentCommand
+	for _, cmd := range cmds {
+		if strings.Contains(strings.ToLower(cmd.Command), match) || strings.Contains(strings.ToLower(cmd.Template), match) {
+			result = append(result, cmd)
+		}
+	}
+	return result
+}
+
+// splitCovered separates the commands not yet bookmarked from those that are,
+// keeping their order.
+func splitCovered(cmds []history.FrequentCommand, bookmarks []config.FlatCommand) (fresh, saved []suggestion) {
+	for _, cmd := range cmds {
+		s := suggestion{FrequentCommand: cmd, CoveredBy: coveringBookmarks(cmd, bookmarks)}
+		if len(s.CoveredBy) > 0 {
+			saved = append(saved, s)
+		} else {
+			fresh = append(fresh, s)
+		}
+	}
+	return fresh, saved
+}
+
+// coveringBookmarks returns the IDs of the bookmarks that run cmd: those with
+// the same command or template, or whose placeholders can be filled in to give
+// the command.
+func coveringBookmarks(cmd history.FrequentCommand, bookmarks []config.FlatCommand) []int {
+	var ids []int
+	for _, b := range bookmarks {
+		if b.Command == cmd.Command || b.Command == cmd.Template || params.Matches(b.Command, cmd.Command) {
+			ids = append(ids, b.ID)
+		}
+	}
+	return ids
+}
+
+// writeSuggestions writes suggestions in a machine-readable format.
+func writeSuggestions(w io.Writer, format string, suggestions []suggestion) error {
+	if suggestions == nil {
+		suggestions = []suggestion{}
+	}
+	switch format {
+	case formatJSON:
+		return writeJSON(w, suggestions)
+	case formatYAML:
+		return writeYAML(w, suggestions)
+	}
+
+	header := []string{"COUNT", "COMMAND", "TEMPLATE", "COVERED_BY"}
+	rows := make([][]string, len(suggestions))
+	for i, s := range suggestions {
+		ids := make([]string, len(s.CoveredBy))
+		for j, id := range s.CoveredBy {
+			ids[j] = strconv.Itoa(id)
+		}
+		rows[i] = []string{strconv.Itoa(s.Count), s.Command, s.Template, strings.Join(ids, ",")}
+	}
+	return writeRows(w, format, header, rows)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/sammcj/bkmk/internal/config"
+	"github.com/sammcj/bkmk/internal/importer"
+)
+
+func exportCommands() {
+	parsed, err := parseArgs(os.Args[2:], []string{"group", "output"}, nil)
+	if err == nil && len(parsed.positional) > 0 {
+		err = fmt.Errorf("unexpected argument %q", parsed.positional[0])
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintln(os.Stderr, "Usage: bkmk export [--group <group> ...] [--output <file>]")
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	exported, err := cfg.Export(parsed.values["group"])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	data, err := exported.Marshal()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if output := parsed.value("output", ""); output != "" && output != "-" {
+		if err := os.WriteFile(output, data, 0o644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing export: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Exported %d group(s) to %s\n", len(exported.Groups), output)
+		return
+	}
+	os.Stdout.Write(data)
+}
+
+func importCommands() {
+	const usage = "Usage: bkmk import [--from pet|navi|markdown] <path|-> [--merge|--replace] [--into <group>] [--dry-run]"
+
+	parsed, err := parseArgs(os.Args[2:], []string{"into", "from"}, []string{"merge", "replace", "dry-run"})
+	if err == nil && len(parsed.positional) != 1 {
+		err = fmt.Errorf("expected exactly one file")
+	}
+	if err == nil && parsed.bools["merge"] && parsed.bools["replace"] {
+		err = fmt.Errorf("--merge and --replace are mutually exclusive")
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(1)
+	}
+
+	path := parsed.positional[0]
+	var src *config.Config
+	if from := parsed.value("from", ""); from != "" && from != "bkmk" {
+		var imp importer.Importer
+		imp, err = importer.Get(from)
+		if err == nil {
+			src, err = importer.Import(imp, path)
+		}
+	} else {
+		src, err = readImportFile(path)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	opts := config.ImportOptions{Mode: config.ImportMerge, Into: parsed.value("into", "")}
+	if parsed.bools["replace"] {
+		opts.Mode = config.ImportReplace
+	}
+
+	if parsed.bools["dry-run"] {
+		cfg, err := config.Load()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+			os.Exit(1)
+		}
+		printImportReport(cfg.Import(src, opts), true)
+		return
+	}
+
+	var report config.ImportReport
+	if _, err := config.Update(func(cfg *config.Config) error {
+		report = cfg.Import(src, opts)
+		return nil
+	}); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	printImportReport(report, false)
+}
+
+// readImportFile parses a bkmk config file, or stdin when path is "-".
+func readImportFile(path string) (*config.Config, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+		path = "stdin"
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return config.Parse(data, path)
+}
+
+func printImportReport(report config.ImportReport, dryRun bool) {
+	prefix := ""
+	if dryRun {
+		prefix = "(dry run) "
+	}
+
+	for _, item := range report.Added {
+		fmt.Printf("%sadded     [%d] %s/%s\n", prefix, item.ID, item.Group, item.Name)
+	}
+	for _, item := range report.Renamed {
+		note := ""
+		if item.Included {
+			note = " (an included command has that name)"
+		}
+		fmt.Printf("%srenamed   [%d] %s/%s -> %s%s\n", prefix, item.ID, item.Group, item.Name, item.NewName, note)
+	}
+	for _, item := range report.Replaced {
+		fmt.Printf("%sreplaced  [%d] %s/%s\n", prefix, item.ID, item.Group, item.Name)
+	}
+	for _, item := range report.Skipped {
+		fmt.Printf("%sskipped   [%d] %s/%s (already exists)\n", prefix, item.ID, item.Group, item.Name)
+	}
+
+	fmt.Printf("%s%d added, %d renamed, %d replaced, %d skipped\n", prefix,
+		len(report.Added), len(report.Renamed), len(report.Replaced), len(report.Skipped))
+}
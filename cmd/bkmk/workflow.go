@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sammcj/bkmk/internal/config"
+	"github.com/sammcj/bkmk/internal/params"
+	"github.com/sammcj/bkmk/internal/workflow"
+)
+
+// workflowSteps resolves the steps of a workflow and fills in their
+// placeholders, prompting once for each value any of the steps needs.
+func workflowSteps(cfg *config.Config, cmd config.FlatCommand, sets []string) ([]config.Step, error) {
+	steps, err := cfg.ResolveSteps(cmd)
+	if err != nil {
+		return nil, err
+	}
+	commands := make([]string, len(steps))
+	for i, step := range steps {
+		commands[i] = step.Command
+	}
+	values, err := promptValues(strings.Join(commands, "\n"), sets)
+	if err != nil {
+		return nil, err
+	}
+	for i := range steps {
+		if steps[i].Command, err = params.Render(steps[i].Command, values); err != nil {
+			return nil, fmt.Errorf("step %d: %w", i+1, err)
+		}
+	}
+	return steps, nil
+}
+
+// runWorkflow runs the steps of a workflow in order, asking before those that
+// need confirmation, then exits with the status of the first failed step.
+// A dangerous workflow is confirmed as a whole first, unless yes is set.
+func runWorkflow(cfg *config.Config, cmd config.FlatCommand, sets []string, yes bool) {
+	steps, err := workflowSteps(cfg, cmd, sets)
+	if err == nil {
+		checked := cmd
+		checked.Steps = steps
+		err = confirmDanger(cfg, checked, yes)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	envs := make([]config.Environment, len(steps))
+	for i, step := range steps {
+		if envs[i], err = cfg.StepEnvironment(cmd, step); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: step %d: %v\n", i+1, err)
+			os.Exit(1)
+		}
+	}
+
+	trackUsage(cmd.ID, config.ActionRun)
+	log := loadRunLog(cfg)
+	run := workflow.New(steps)
+	var reader *bufio.Reader
+	for i, ok := run.Next(); ok; i, ok = run.Next() {
+		step := steps[i]
+		prefix := fmt.Sprintf("[%d/%d] %s", i+1, len(steps), step.Label())
+
+		if step.Confirm {
+			if !isTerminal(os.Stdin) {
+				fmt.Fprintf(os.Stderr, "Error: %s needs confirmation, which requires a terminal\n", prefix)
+				run.Abort()
+				break
+			}
+			if reader == nil {
+				reader = bufio.NewReader(os.Stdin)
+			}
+			fmt.Fprintf(os.Stderr, "%s: run %s? [y/N/q] ", prefix, step.Command)
+			answer, _ := reader.ReadString('\n')
+			switch strings.ToLower(strings.TrimSpace(answer)) {
+			case "y", "yes":
+			case "q", "quit":
+				run.Abort()
+				continue
+			default:
+				run.Skip()
+				continue
+			}
+		}
+
+		fmt.Fprintf(os.Stderr, "%s%s: %s\n", prefix, runningIn(envs[i]), step.Command)
+		err := runLogged(cfg, log, cmd, step.Label(), step.Command, envs[i])
+		reportRunError(err)
+		run.Finish(err)
+	}
+
+	fmt.Fprintln(os.Stderr)
+	for i, res := range run.Results {
+		status := res.Status.String()
+		if res.Status == workflow.Failed {
+			status = fmt.Sprintf("failed (exit %d)", res.Exit)
+		}
+		fmt.Fprintf(os.Stderr, "  %d. %s: %s\n", i+1, steps[i].Label(), status)
+	}
+	if code := run.ExitCode(); code != 0 {
+		os.Exit(code)
+	}
+}
+
+// showWorkflow prints the resolved steps of a workflow, each under a comment
+// naming it. Placeholders are only filled in when values are given.
+func showWorkflow(cfg *config.Config, cmd config.FlatCommand, sets []string) error {
+	steps, err := cfg.ResolveSteps(cmd)
+	if err != nil {
+		return err
+	}
+	values, err := params.ParseAssignments(sets)
+	if err != nil {
+		return err
+	}
+	for i, step := range steps {
+		command := step.Command
+		if len(sets) > 0 {
+			if command, err = params.Render(command, values); err != nil {
+				return fmt.Errorf("step %d: %w", i+1, err)
+			}
+		}
+		fmt.Printf("# %d. %s\n%s\n", i+1, step.Label(), command)
+	}
+	return nil
+}
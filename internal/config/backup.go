@@ -0,0 +1,239 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+const backupTimeFormat = "20060102-150405.000000"
+
+// Backup is a copy of the config file taken before it was overwritten.
+type Backup struct {
+	Path string
+	Time time.Time
+}
+
+// BackupDiff lists the commands that differ between a backup and the
+// current config, matched by ID.
+type BackupDiff struct {
+	Added   []FlatCommand // in the current config only
+	Removed []FlatCommand // in the backup only
+	Changed []CommandChange
+}
+
+// CommandChange is a command present in both a backup and the current config
+// with different contents.
+type CommandChange struct {
+	Old FlatCommand // the backup's version
+	New FlatCommand
+}
+
+// Empty reports whether the backup and current config hold the same commands.
+func (d BackupDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Summary describes the diff in counts, e.g. "+2 -1 ~0".
+func (d BackupDiff) Summary() string {
+	return fmt.Sprintf("+%d -%d ~%d", len(d.Added), len(d.Removed), len(d.Changed))
+}
+
+// backupDirFor returns the backup directory path for a given config path
+func backupDirFor(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), "backup")
+}
+
+// createBackup creates a timestamped backup and prunes old backups beyond maxBackups.
+func createBackup(path string) error {
+	backupDirPath := backupDirFor(path)
+	base := filepath.Base(path)
+
+	// Ensure backup directory exists
+	if err := os.MkdirAll(backupDirPath, 0o755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	// Read existing file
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	// Create backup with timestamp (including microseconds for uniqueness)
+	timestamp := time.Now().Format(backupTimeFormat)
+	backupName := fmt.Sprintf("%s.bak.%s", base, timestamp)
+	backupPath := filepath.Join(backupDirPath, backupName)
+
+	if err := os.WriteFile(backupPath, data, 0o644); err != nil {
+		return err
+	}
+
+	// Prune old backups
+	return pruneBackups(backupDirPath, base)
+}
+
+// pruneBackups removes oldest backups if count exceeds maxBackups.
+func pruneBackups(dir, base string) error {
+	pattern := filepath.Join(dir, base+".bak.*")
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return err
+	}
+
+	if len(matches) <= maxBackups {
+		return nil
+	}
+
+	// Sort by filename (timestamp in name ensures chronological order)
+	sort.Strings(matches)
+
+	// Remove oldest backups
+	toDelete := matches[:len(matches)-maxBackups]
+	for _, path := range toDelete {
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove old backup %s: %w", path, err)
+		}
+	}
+
+	return nil
+}
+
+// ListBackups returns the backups of the config at Path, newest first.
+func ListBackups() ([]Backup, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	return ListBackupsFor(path)
+}
+
+// ListBackupsFor returns the backups of the config at configPath, newest first.
+func ListBackupsFor(configPath string) ([]Backup, error) {
+	base := filepath.Base(configPath)
+	pattern := filepath.Join(backupDirFor(configPath), base+".bak.*")
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Sort(sort.Reverse(sort.StringSlice(matches)))
+	backups := make([]Backup, 0, len(matches))
+	for _, match := range matches {
+		stamp := strings.TrimPrefix(filepath.Base(match), base+".bak.")
+		t, err := time.ParseInLocation(backupTimeFormat, stamp, time.Local)
+		if err != nil {
+			continue // not one of ours
+		}
+		backups = append(backups, Backup{Path: match, Time: t})
+	}
+	return backups, nil
+}
+
+// loadBackup reads a config file without its includes, as backups only hold
+// the config file itself.
+func loadBackup(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Config{Groups: []Group{}, NextID: 1}, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return Parse(data, path)
+}
+
+// DiffBackup compares a backup with the config at configPath.
+func DiffBackup(configPath, backupPath string) (BackupDiff, error) {
+	old, err := loadBackup(backupPath)
+	if err != nil {
+		return BackupDiff{}, err
+	}
+	current, err := loadBackup(configPath)
+	if err != nil {
+		return BackupDiff{}, err
+	}
+	return diffCommands(old.FlatCommands(), current.FlatCommands()), nil
+}
+
+// diffCommands compares two sets of commands by ID.
+func diffCommands(old, current []FlatCommand) BackupDiff {
+	var diff BackupDiff
+	byID := make(map[int]FlatCommand, len(current))
+	for _, cmd := range current {
+		byID[cmd.ID] = cmd
+	}
+	for _, cmd := range old {
+		now, ok := byID[cmd.ID]
+		switch {
+		case !ok:
+			diff.Removed = append(diff.Removed, cmd)
+		case !sameCommand(cmd, now):
+			diff.Changed = append(diff.Changed, CommandChange{Old: cmd, New: now})
+		}
+		delete(byID, cmd.ID)
+	}
+	for _, cmd := range current {
+		if _, ok := byID[cmd.ID]; ok {
+			diff.Added = append(diff.Added, cmd)
+		}
+	}
+	return diff
+}
+
+func sameCommand(a, b FlatCommand) bool {
+	return a.GroupName == b.GroupName && a.Name == b.Name && a.Command == b.Command &&
+		a.Description == b.Description && a.DefaultAction == b.DefaultAction && slices.Equal(a.Tags, b.Tags) &&
+		slices.Equal(a.Steps, b.Steps) && a.OnFailure == b.OnFailure && a.Cwd == b.Cwd && maps.Equal(a.Env, b.Env) &&
+		a.Confirm == b.Confirm && a.AllowDefaultRun == b.AllowDefaultRun
+}
+
+// RestoreBackup restores the config at Path from a backup file.
+func RestoreBackup(backupPath string) error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	return RestoreBackupTo(path, backupPath)
+}
+
+// RestoreBackupTo restores the config at configPath from one of its backups.
+// The backup must pass the same validation as LoadFrom, and the current
+// config is backed up first so a restore can itself be undone.
+func RestoreBackupTo(configPath, backupPath string) error {
+	unlock, err := Lock(configPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Verify backup has correct prefix
+	base := filepath.Base(configPath)
+	if !strings.HasPrefix(filepath.Base(backupPath), base+".bak.") {
+		return fmt.Errorf("invalid backup file: %s", backupPath)
+	}
+
+	data, err := os.ReadFile(backupPath)
+	if err != nil {
+		return fmt.Errorf("failed to read backup: %w", err)
+	}
+
+	if _, err := Parse(data, backupPath); err != nil {
+		return fmt.Errorf("backup contains invalid config: %w", err)
+	}
+
+	// Create backup of current before restoring
+	if _, err := os.Stat(configPath); err == nil {
+		if err := createBackup(configPath); err != nil {
+			return fmt.Errorf("failed to backup current config: %w", err)
+		}
+	}
+
+	return WriteFileAtomic(configPath, data, 0o644)
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestListAndDiffBackups(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "config.yaml")
+
+	cfg := &Config{Groups: []Group{}, NextID: 1}
+	if err := cfg.AddCommand("git", "st", "git status", ""); err != nil {
+		t.Fatalf("AddCommand failed: %v", err)
+	}
+	if err := cfg.AddCommand("git", "lg", "git log", ""); err != nil {
+		t.Fatalf("AddCommand failed: %v", err)
+	}
+	if err := cfg.SaveTo(path); err != nil {
+		t.Fatalf("SaveTo failed: %v", err)
+	}
+
+	// Change one command, remove one and add one
+	if err := cfg.UpdateCommand("git", "st", "st", "git status -sb", ""); err != nil {
+		t.Fatalf("UpdateCommand failed: %v", err)
+	}
+	if err := cfg.RemoveCommand("git", "lg"); err != nil {
+		t.Fatalf("RemoveCommand failed: %v", err)
+	}
+	if err := cfg.AddCommand("git", "co", "git checkout", ""); err != nil {
+		t.Fatalf("AddCommand failed: %v", err)
+	}
+	if err := cfg.SaveTo(path); err != nil {
+		t.Fatalf("SaveTo failed: %v", err)
+	}
+	if err := cfg.SaveTo(path); err != nil {
+		t.Fatalf("SaveTo failed: %v", err)
+	}
+
+	backups, err := ListBackupsFor(path)
+	if err != nil {
+		t.Fatalf("ListBackupsFor failed: %v", err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("expected 2 backups, got %d", len(backups))
+	}
+	if !backups[0].Time.After(backups[1].Time) || backups[1].Time.IsZero() {
+		t.Errorf("expected newest first with parsed times, got %v then %v", backups[0].Time, backups[1].Time)
+	}
+
+	diff, err := DiffBackup(path, backups[0].Path)
+	if err != nil {
+		t.Fatalf("DiffBackup failed: %v", err)
+	}
+	if !diff.Empty() {
+		t.Errorf("expected no changes since the newest backup, got %s", diff.Summary())
+	}
+
+	diff, err = DiffBackup(path, backups[1].Path)
+	if err != nil {
+		t.Fatalf("DiffBackup failed: %v", err)
+	}
+	if diff.Summary() != "+1 -1 ~1" {
+		t.Fatalf("expected one of each change, got %s", diff.Summary())
+	}
+	if diff.Added[0].Name != "co" || diff.Removed[0].Name != "lg" {
+		t.Errorf("unexpected added/removed: %+v / %+v", diff.Added, diff.Removed)
+	}
+	if change := diff.Changed[0]; change.Old.Command != "git status" || change.New.Command != "git status -sb" {
+		t.Errorf("unexpected change: %+v", change)
+	}
+}
+
+func TestRestoreBackupTo_Validates(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "config.yaml")
+	backupDir := filepath.Join(tmpDir, "backup")
+
+	cfg := &Config{Groups: []Group{{Name: "original"}}}
+	if err := cfg.SaveTo(path); err != nil {
+		t.Fatalf("SaveTo failed: %v", err)
+	}
+	cfg.Groups[0].Name = "updated"
+	if err := cfg.SaveTo(path); err != nil {
+		t.Fatalf("SaveTo failed: %v", err)
+	}
+
+	backups, err := ListBackupsFor(path)
+	if err != nil || len(backups) != 1 {
+		t.Fatalf("expected one backup, got %d (%v)", len(backups), err)
+	}
+
+	// A backup with an unknown key is rejected like it would be on load
+	bad := filepath.Join(backupDir, "config.yaml.bak.20000101-000000.000000")
+	if err := os.WriteFile(bad, []byte("groups: []\nbogus: true\n"), 0o644); err != nil {
+		t.Fatalf("failed to write bad backup: %v", err)
+	}
+	if err := RestoreBackupTo(path, bad); err == nil || !strings.Contains(err.Error(), "invalid config") {
+		t.Errorf("expected invalid backup to be rejected, got %v", err)
+	}
+	if err := RestoreBackupTo(path, filepath.Join(tmpDir, "other.yaml")); err == nil {
+		t.Error("expected a file that is not a backup to be rejected")
+	}
+
+	if err := RestoreBackupTo(path, backups[0].Path); err != nil {
+		t.Fatalf("RestoreBackupTo failed: %v", err)
+	}
+	loaded, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom failed: %v", err)
+	}
+	if loaded.Groups[0].Name != "original" {
+		t.Errorf("expected restored group 'original', got %q", loaded.Groups[0].Name)
+	}
+
+	// The config that was replaced is kept as a backup too
+	after, _ := ListBackupsFor(path)
+	if len(after) != 3 {
+		t.Errorf("expected restore to back up the current config, got %d backups", len(after))
+	}
+}
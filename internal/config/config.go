@@ -1,13 +1,12 @@
 package config
 
 import (
+	"crypto/sha256"
 	"fmt"
 	"os"
 	"path/filepath"
-	"sort"
 	"strconv"
 	"strings"
-	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -17,28 +16,71 @@ const maxBackups = 20
 type ActionType string
 
 const (
-	ActionNone ActionType = "none"
-	ActionCopy ActionType = "copy"
-	ActionRun  ActionType = "run"
+	ActionNone   ActionType = "none"
+	ActionCopy   ActionType = "copy"
+	ActionRun    ActionType = "run"
+	ActionInsert ActionType = "insert"
 )
 
 type Command struct {
 	ID            int        `yaml:"id"`
 	Name          string     `yaml:"name"`
-	Command       string     `yaml:"command"`
+	Command       string     `yaml:"command,omitempty"`
 	Description   string     `yaml:"description,omitempty"`
 	DefaultAction ActionType `yaml:"default_action,omitempty"`
-}
-
+	Tags          []string   `yaml:"tags,omitempty"`
+	// Steps make the command a workflow, run in order in place of Command.
+	Steps     []Step        `yaml:"steps,omitempty"`
+	OnFailure FailurePolicy `yaml:"on_failure,omitempty"`
+	// Cwd and Env say where the command runs, over its groups' defaults.
+	Cwd string            `yaml:"cwd,omitempty"`
+	Env map[string]string `yaml:"env,omitempty"`
+	// Confirm makes running the command need a typed confirmation, as for
+	// commands matching a danger pattern. AllowDefaultRun lets such a command
+	// keep a default action of run.
+	Confirm         bool `yaml:"confirm,omitempty"`
+	AllowDefaultRun bool `yaml:"allow_default_run,omitempty"`
+	// Source is the included file the command was loaded from, empty for
+	// commands owned by the config file itself.
+	Source string `yaml:"-"`
+}
+
+// Group holds commands and optional subgroups. A group is addressed by its
+// path, the names from the top-level group down joined by "/".
 type Group struct {
-	Name     string    `yaml:"name"`
-	Commands []Command `yaml:"commands"`
-}
-
+	Name string `yaml:"name"`
+	// Cwd and Env are defaults for the commands in the group and its subgroups.
+	Cwd      string            `yaml:"cwd,omitempty"`
+	Env      map[string]string `yaml:"env,omitempty"`
+	Commands []Command         `yaml:"commands"`
+	Groups   []Group           `yaml:"groups,omitempty"`
+	Source   string            `yaml:"-"` // set when the group only exists in an included file
+}
+
+// LogSettings configure the log kept of every run. Output is kept for
+// commands run in the TUI's output pane, and for 'bkmk run' only when
+// CaptureOutput is set, since capturing it takes the terminal away from them.
+type LogSettings struct {
+	Disabled      bool `yaml:"disabled,omitempty"`
+	CaptureOutput bool `yaml:"capture_output,omitempty"`
+	MaxOutput     int  `yaml:"max_output,omitempty"` // bytes of output kept per run
+}
+
+// Config is the personal config file, with the commands of any included
+// files merged in read-only.
 type Config struct {
-	Groups []Group `yaml:"groups"`
-	NextID int     `yaml:"next_id,omitempty"`
-	Editor string  `yaml:"editor,omitempty"`
+	Groups  []Group  `yaml:"groups"`
+	NextID  int      `yaml:"next_id,omitempty"`
+	Editor  string   `yaml:"editor,omitempty"`
+	Include []string `yaml:"include,omitempty"`
+	// Guardrails decide which commands need confirming before they run.
+	Guardrails Guardrails `yaml:"guardrails,omitempty"`
+	// Log configures the log of runs.
+	Log LogSettings `yaml:"log,omitempty"`
+
+	// disk is the file the config was loaded from as it was then, to detect
+	// changes made by other processes before saving.
+	disk fileState
 }
 
 func DefaultPath() (string, error) {
@@ -49,29 +91,46 @@ func DefaultPath() (string, error) {
 	return filepath.Join(home, ".config", "bkmk", "config.yaml"), nil
 }
 
+// Load reads the config at Path together with its includes.
 func Load() (*Config, error) {
-	path, err := DefaultPath()
+	path, err := Path()
 	if err != nil {
 		return nil, err
 	}
 	return LoadFrom(path)
 }
 
+// LoadFrom reads the config at path and merges in the files it includes.
 func LoadFrom(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return &Config{Groups: []Group{}, NextID: 1}, nil
+			return &Config{Groups: []Group{}, NextID: 1, disk: fileState{path: path}}, nil
 		}
 		return nil, fmt.Errorf("failed to read config: %w", err)
 	}
 
+	cfg, err := Parse(data, path)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.loadIncludes(path); err != nil {
+		return nil, err
+	}
+	cfg.disk = fileState{path: path, exists: true, sum: sha256.Sum256(data)}
+	return cfg, nil
+}
+
+// Parse decodes config data with the same strict rules as LoadFrom: unknown
+// keys are rejected and values are validated. source is used in error messages.
+// Includes are not loaded.
+func Parse(data []byte, source string) (*Config, error) {
 	var cfg Config
 	decoder := yaml.NewDecoder(strings.NewReader(string(data)))
 	decoder.KnownFields(true) // Reject unknown fields
 
 	if err := decoder.Decode(&cfg); err != nil {
-		return nil, formatYAMLError(err, path)
+		return nil, formatYAMLError(err, source)
 	}
 
 	// Validate config values
@@ -91,7 +150,7 @@ func formatYAMLError(err error, path string) error {
 
 	// Check for unknown field errors
 	if strings.Contains(errStr, "field") && strings.Contains(errStr, "not found") {
-		return fmt.Errorf("invalid config key in %s: %w\nValid top-level keys: groups, next_id, editor\nValid group keys: name, commands\nValid command keys: id, name, command, description, default_action", path, err)
+		return fmt.Errorf("invalid config key in %s: %w\nValid top-level keys: groups, next_id, editor, include, guardrails, log\nValid group keys: name, cwd, env, commands, groups\nValid command keys: id, name, command, description, default_action, tags, steps, on_failure, cwd, env, confirm, allow_default_run", path, err)
 	}
 
 	// Check for syntax errors
@@ -105,28 +164,63 @@ func formatYAMLError(err error, path string) error {
 // validate checks config values are valid
 func (c *Config) validate() error {
 	validActions := map[ActionType]bool{
-		ActionNone: true,
-		ActionCopy: true,
-		ActionRun:  true,
-		"":         true, // Empty is allowed (defaults to none)
+		ActionNone:   true,
+		ActionCopy:   true,
+		ActionRun:    true,
+		ActionInsert: true,
+		"":           true, // Empty is allowed (defaults to none)
+	}
+
+	if err := validateGuardrails(c.Guardrails); err != nil {
+		return err
 	}
+	if c.Log.MaxOutput < 0 {
+		return fmt.Errorf("log: max_output cannot be negative")
+	}
+	return validateGroups(c.Groups, "", validActions)
+}
 
-	for gi, g := range c.Groups {
+// validateGroups checks groups and their subgroups, reporting groups by path
+func validateGroups(groups []Group, parent string, validActions map[ActionType]bool) error {
+	for gi, g := range groups {
 		if g.Name == "" {
+			if parent != "" {
+				return fmt.Errorf("group at index %d in group %q has empty name", gi, parent)
+			}
 			return fmt.Errorf("group at index %d has empty name", gi)
 		}
+		if strings.Contains(g.Name, PathSeparator) {
+			return fmt.Errorf("group name %q cannot contain %q; nest groups with 'groups:' instead", g.Name, PathSeparator)
+		}
+		path := JoinPath(parent, g.Name)
+		if err := validateEnv(g.Env); err != nil {
+			return fmt.Errorf("group %q: %w", path, err)
+		}
 
 		for ci, cmd := range g.Commands {
 			if cmd.Name == "" {
-				return fmt.Errorf("command at index %d in group %q has empty name", ci, g.Name)
+				return fmt.Errorf("command at index %d in group %q has empty name", ci, path)
 			}
-			if cmd.Command == "" {
-				return fmt.Errorf("command %q in group %q has empty command", cmd.Name, g.Name)
+			if cmd.Command == "" && !cmd.IsWorkflow() {
+				return fmt.Errorf("command %q in group %q has empty command", cmd.Name, path)
+			}
+			if err := validateWorkflow(cmd); err != nil {
+				return fmt.Errorf("command %q in group %q: %w", cmd.Name, path, err)
+			}
+			if err := validateEnv(cmd.Env); err != nil {
+				return fmt.Errorf("command %q in group %q: %w", cmd.Name, path, err)
 			}
 			if !validActions[cmd.DefaultAction] {
-				return fmt.Errorf("command %q in group %q has invalid default_action %q (valid: none, copy, run)", cmd.Name, g.Name, cmd.DefaultAction)
+				return fmt.Errorf("command %q in group %q has invalid default_action %q (valid: none, copy, run, insert)", cmd.Name, path, cmd.DefaultAction)
+			}
+			if err := validateTags(cmd.Tags); err != nil {
+				return fmt.Errorf("command %q in group %q: %w", cmd.Name, path, err)
 			}
 		}
+
+		if err := validateGroups(g.Groups, path, validActions); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -135,13 +229,13 @@ func (c *Config) validate() error {
 func (c *Config) migrateIDs() {
 	// Find max existing ID
 	maxID := 0
-	for _, g := range c.Groups {
+	walkGroups(c.Groups, "", func(_ string, g *Group) {
 		for _, cmd := range g.Commands {
 			if cmd.ID > maxID {
 				maxID = cmd.ID
 			}
 		}
-	}
+	})
 
 	// Set NextID if not set or too low
 	if c.NextID <= maxID {
@@ -149,175 +243,102 @@ func (c *Config) migrateIDs() {
 	}
 
 	// Assign IDs to commands without them
-	for i := range c.Groups {
-		for j := range c.Groups[i].Commands {
-			if c.Groups[i].Commands[j].ID == 0 {
-				c.Groups[i].Commands[j].ID = c.NextID
-				c.NextID++
+	walkGroups(c.Groups, "", func(_ string, g *Group) {
+		for j := range g.Commands {
+			if g.Commands[j].ID == 0 {
+				g.Commands[j].ID = c.newID()
 			}
 		}
+	})
+}
+
+// newID returns NextID for a new command and advances it, skipping the IDs
+// kept for included files.
+func (c *Config) newID() int {
+	if c.NextID >= includeIDStride && c.NextID < includeIDEnd {
+		c.NextID = includeIDEnd
 	}
+	id := c.NextID
+	c.NextID++
+	return id
 }
 
+// Save writes the config to Path, leaving out commands from included files.
 func (c *Config) Save() error {
-	path, err := DefaultPath()
+	path, err := Path()
 	if err != nil {
 		return err
 	}
 	return c.SaveTo(path)
 }
 
+// SaveTo writes the config to path under the config lock. If the config was
+// loaded from path and the file has changed since, it returns ErrModified
+// instead of overwriting the other change.
 func (c *Config) SaveTo(path string) error {
-	dir := filepath.Dir(path)
-	if err := os.MkdirAll(dir, 0o755); err != nil {
-		return fmt.Errorf("failed to create config directory: %w", err)
-	}
-
-	// Create backup of existing config if it exists
-	if _, err := os.Stat(path); err == nil {
-		if err := createBackup(path); err != nil {
-			return fmt.Errorf("failed to create backup: %w", err)
-		}
-	}
-
-	var buf strings.Builder
-	enc := yaml.NewEncoder(&buf)
-	enc.SetIndent(2)
-	if err := enc.Encode(c); err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
-	}
-	if err := enc.Close(); err != nil {
-		return fmt.Errorf("failed to close encoder: %w", err)
-	}
-
-	if err := os.WriteFile(path, []byte(buf.String()), 0o644); err != nil {
-		return fmt.Errorf("failed to write config: %w", err)
-	}
-
-	return nil
-}
-
-// backupDirFor returns the backup directory path for a given config path
-func backupDirFor(configPath string) string {
-	return filepath.Join(filepath.Dir(configPath), "backup")
-}
-
-// createBackup creates a timestamped backup and prunes old backups beyond maxBackups.
-func createBackup(path string) error {
-	backupDirPath := backupDirFor(path)
-	base := filepath.Base(path)
-
-	// Ensure backup directory exists
-	if err := os.MkdirAll(backupDirPath, 0o755); err != nil {
-		return fmt.Errorf("failed to create backup directory: %w", err)
-	}
-
-	// Read existing file
-	data, err := os.ReadFile(path)
+	unlock, err := Lock(path)
 	if err != nil {
 		return err
 	}
+	defer unlock()
+	return c.saveLocked(path)
+}
 
-	// Create backup with timestamp (including microseconds for uniqueness)
-	timestamp := time.Now().Format("20060102-150405.000000")
-	backupName := fmt.Sprintf("%s.bak.%s", base, timestamp)
-	backupPath := filepath.Join(backupDirPath, backupName)
-
-	if err := os.WriteFile(backupPath, data, 0o644); err != nil {
-		return err
+// saveLocked is SaveTo for a caller already holding the config lock.
+func (c *Config) saveLocked(path string) error {
+	if c.disk.path == path {
+		current, err := stateOf(path)
+		if err != nil {
+			return err
+		}
+		if current != c.disk {
+			return fmt.Errorf("%w: %s", ErrModified, path)
+		}
 	}
 
-	// Prune old backups
-	return pruneBackups(backupDirPath, base)
-}
-
-// pruneBackups removes oldest backups if count exceeds maxBackups.
-func pruneBackups(dir, base string) error {
-	pattern := filepath.Join(dir, base+".bak.*")
-	matches, err := filepath.Glob(pattern)
+	data, err := c.writable().Marshal()
 	if err != nil {
 		return err
 	}
 
-	if len(matches) <= maxBackups {
-		return nil
+	// Create backup of existing config if it exists
+	if _, err := os.Stat(path); err == nil {
+		if err := createBackup(path); err != nil {
+			return fmt.Errorf("failed to create backup: %w", err)
+		}
 	}
 
-	// Sort by filename (timestamp in name ensures chronological order)
-	sort.Strings(matches)
-
-	// Remove oldest backups
-	toDelete := matches[:len(matches)-maxBackups]
-	for _, path := range toDelete {
-		if err := os.Remove(path); err != nil {
-			return fmt.Errorf("failed to remove old backup %s: %w", path, err)
-		}
+	if err := WriteFileAtomic(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
 	}
 
+	c.disk = fileState{path: path, exists: true, sum: sha256.Sum256(data)}
 	return nil
 }
 
-// ListBackups returns available backup files sorted oldest to newest.
-func ListBackups() ([]string, error) {
-	path, err := DefaultPath()
-	if err != nil {
-		return nil, err
+// Marshal encodes the config as YAML in the format used on disk.
+func (c *Config) Marshal() ([]byte, error) {
+	var buf strings.Builder
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
 	}
-
-	backupDirPath := backupDirFor(path)
-	base := filepath.Base(path)
-	pattern := filepath.Join(backupDirPath, base+".bak.*")
-
-	matches, err := filepath.Glob(pattern)
-	if err != nil {
-		return nil, err
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close encoder: %w", err)
 	}
-
-	sort.Strings(matches)
-	return matches, nil
+	return []byte(buf.String()), nil
 }
 
-// RestoreBackup restores config from a backup file.
-func RestoreBackup(backupPath string) error {
-	path, err := DefaultPath()
-	if err != nil {
-		return err
-	}
-
-	// Verify backup exists and has correct prefix
-	base := filepath.Base(path)
-	if !strings.HasPrefix(filepath.Base(backupPath), base+".bak.") {
-		return fmt.Errorf("invalid backup file: %s", backupPath)
-	}
-
-	data, err := os.ReadFile(backupPath)
-	if err != nil {
-		return fmt.Errorf("failed to read backup: %w", err)
-	}
-
-	// Validate it's valid YAML config
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return fmt.Errorf("backup contains invalid config: %w", err)
-	}
-
-	// Create backup of current before restoring
-	if _, err := os.Stat(path); err == nil {
-		if err := createBackup(path); err != nil {
-			return fmt.Errorf("failed to backup current config: %w", err)
-		}
+// AddGroup creates the group at path along with any missing parents
+func (c *Config) AddGroup(path string) error {
+	if len(SplitPath(path)) == 0 {
+		return fmt.Errorf("group name cannot be empty")
 	}
-
-	return os.WriteFile(path, data, 0o644)
-}
-
-func (c *Config) AddGroup(name string) error {
-	for _, g := range c.Groups {
-		if g.Name == name {
-			return fmt.Errorf("group %q already exists", name)
-		}
+	if c.GetGroup(path) != nil {
+		return fmt.Errorf("group %q already exists", JoinPath(path))
 	}
-	c.Groups = append(c.Groups, Group{Name: name, Commands: []Command{}})
+	c.EnsureGroup(path)
 	return nil
 }
 
@@ -326,90 +347,117 @@ func (c *Config) AddCommand(groupName, cmdName, command, description string) err
 }
 
 func (c *Config) AddCommandWithAction(groupName, cmdName, command, description string, action ActionType) error {
-	// Find or create the group
-	groupIdx := -1
-	for i, g := range c.Groups {
-		if g.Name == groupName {
-			groupIdx = i
-			break
-		}
+	return c.AddCommandWithTags(groupName, cmdName, command, description, action, nil)
+}
+
+// AddCommandWithTags adds a command with an action and tags
+func (c *Config) AddCommandWithTags(groupName, cmdName, command, description string, action ActionType, tags []string) error {
+	if err := validateTags(tags); err != nil {
+		return err
 	}
 
-	if groupIdx == -1 {
-		// Auto-create the group
-		c.Groups = append(c.Groups, Group{Name: groupName, Commands: []Command{}})
-		groupIdx = len(c.Groups) - 1
+	// Find or create the group, including any missing parents
+	group := c.EnsureGroup(groupName)
+	if group == nil {
+		return fmt.Errorf("group name cannot be empty")
 	}
 
 	// Check for duplicate command name
-	for _, cmd := range c.Groups[groupIdx].Commands {
+	for _, cmd := range group.Commands {
 		if cmd.Name == cmdName {
 			return fmt.Errorf("command %q already exists in group %q", cmdName, groupName)
 		}
 	}
 
-	c.Groups[groupIdx].Commands = append(c.Groups[groupIdx].Commands, Command{
-		ID:            c.NextID,
+	group.Commands = append(group.Commands, Command{
+		ID:            c.newID(),
 		Name:          cmdName,
 		Command:       command,
 		Description:   description,
 		DefaultAction: action,
+		Tags:          tags,
 	})
-	c.NextID++
 	return nil
 }
 
-func (c *Config) RemoveGroup(name string) error {
-	for i, g := range c.Groups {
+// RemoveGroup removes the group at path together with its subgroups
+func (c *Config) RemoveGroup(path string) error {
+	siblings, err := c.siblingsOf(path)
+	if err != nil {
+		return err
+	}
+	name := BaseName(path)
+	for i, g := range *siblings {
 		if g.Name == name {
-			c.Groups = append(c.Groups[:i], c.Groups[i+1:]...)
+			if cmd, ok := readOnlyIn(&g); ok {
+				return fmt.Errorf("group %q cannot be removed: %w", path, readOnlyError(cmd))
+			}
+			*siblings = append((*siblings)[:i], (*siblings)[i+1:]...)
 			return nil
 		}
 	}
-	return fmt.Errorf("group %q not found", name)
+	return fmt.Errorf("group %q not found", path)
 }
 
-func (c *Config) RenameGroup(oldName, newName string) error {
-	if oldName == newName {
+// RenameGroup renames the group at oldPath, keeping it under the same parent
+func (c *Config) RenameGroup(oldPath, newName string) error {
+	if strings.Contains(newName, PathSeparator) {
+		return fmt.Errorf("group name %q cannot contain %q", newName, PathSeparator)
+	}
+	if BaseName(oldPath) == newName {
 		return nil
 	}
+	siblings, err := c.siblingsOf(oldPath)
+	if err != nil {
+		return err
+	}
 	// Check new name doesn't already exist
-	for _, g := range c.Groups {
+	for _, g := range *siblings {
 		if g.Name == newName {
-			return fmt.Errorf("group %q already exists", newName)
+			return fmt.Errorf("group %q already exists", JoinPath(ParentPath(oldPath), newName))
 		}
 	}
 	// Find and rename the group
-	for i, g := range c.Groups {
+	oldName := BaseName(oldPath)
+	for i, g := range *siblings {
 		if g.Name == oldName {
-			c.Groups[i].Name = newName
+			if cmd, ok := readOnlyIn(&g); ok {
+				return fmt.Errorf("group %q cannot be renamed: %w", oldPath, readOnlyError(cmd))
+			}
+			(*siblings)[i].Name = newName
 			return nil
 		}
 	}
-	return fmt.Errorf("group %q not found", oldName)
+	return fmt.Errorf("group %q not found", oldPath)
 }
 
 func (c *Config) RemoveCommand(groupName, cmdNameOrID string) error {
-	for i, g := range c.Groups {
-		if g.Name == groupName {
-			for j, cmd := range g.Commands {
-				if cmd.Name == cmdNameOrID || strconv.Itoa(cmd.ID) == cmdNameOrID {
-					c.Groups[i].Commands = append(g.Commands[:j], g.Commands[j+1:]...)
-					return nil
-				}
+	group := c.GetGroup(groupName)
+	if group == nil {
+		return fmt.Errorf("group %q not found", groupName)
+	}
+	for j, cmd := range group.Commands {
+		if cmd.Name == cmdNameOrID || strconv.Itoa(cmd.ID) == cmdNameOrID {
+			if cmd.ReadOnly() {
+				return readOnlyError(cmd)
 			}
-			return fmt.Errorf("command %q not found in group %q", cmdNameOrID, groupName)
+			group.Commands = append(group.Commands[:j], group.Commands[j+1:]...)
+			return nil
 		}
 	}
-	return fmt.Errorf("group %q not found", groupName)
+	return fmt.Errorf("command %q not found in group %q", cmdNameOrID, groupName)
 }
 
 // RemoveCommandByID removes a command by its global ID (no group needed)
 func (c *Config) RemoveCommandByID(id int) error {
-	for i, g := range c.Groups {
-		for j, cmd := range g.Commands {
+	_, path := c.GetCommandByID(id)
+	if group := c.GetGroup(path); group != nil {
+		for j, cmd := range group.Commands {
 			if cmd.ID == id {
-				c.Groups[i].Commands = append(g.Commands[:j], g.Commands[j+1:]...)
+				if cmd.ReadOnly() {
+					return readOnlyError(cmd)
+				}
+				group.Commands = append(group.Commands[:j], group.Commands[j+1:]...)
 				return nil
 			}
 		}
@@ -417,25 +465,18 @@ func (c *Config) RemoveCommandByID(id int) error {
 	return fmt.Errorf("command with ID %d not found", id)
 }
 
-func (c *Config) GetGroup(name string) *Group {
-	for i := range c.Groups {
-		if c.Groups[i].Name == name {
-			return &c.Groups[i]
-		}
-	}
-	return nil
-}
-
-// GetCommandByID finds a command by its global ID
+// GetCommandByID finds a command by its global ID, returning it with its group path
 func (c *Config) GetCommandByID(id int) (*Command, string) {
-	for _, g := range c.Groups {
+	var found *Command
+	var foundPath string
+	walkGroups(c.Groups, "", func(path string, g *Group) {
 		for i := range g.Commands {
-			if g.Commands[i].ID == id {
-				return &g.Commands[i], g.Name
+			if found == nil && g.Commands[i].ID == id {
+				found, foundPath = &g.Commands[i], path
 			}
 		}
-	}
-	return nil, ""
+	})
+	return found, foundPath
 }
 
 // GetCommand finds a command by name or ID within a group
@@ -461,76 +502,102 @@ func (c *Config) UpdateCommand(groupName, cmdNameOrID, newName, newCommand, newD
 
 // UpdateCommandWithAction updates a command with optional action change
 func (c *Config) UpdateCommandWithAction(groupName, cmdNameOrID, newName, newCommand, newDescription string, newAction ActionType) error {
-	for i, g := range c.Groups {
-		if g.Name == groupName {
-			for j, cmd := range g.Commands {
-				if cmd.Name == cmdNameOrID || strconv.Itoa(cmd.ID) == cmdNameOrID {
-					// Check if new name conflicts with another command
-					if newName != cmd.Name {
-						for k, other := range g.Commands {
-							if k != j && other.Name == newName {
-								return fmt.Errorf("command %q already exists in group %q", newName, groupName)
-							}
-						}
-					}
-					c.Groups[i].Commands[j].Name = newName
-					c.Groups[i].Commands[j].Command = newCommand
-					c.Groups[i].Commands[j].Description = newDescription
-					if newAction != "" {
-						c.Groups[i].Commands[j].DefaultAction = newAction
+	group := c.GetGroup(groupName)
+	if group == nil {
+		return fmt.Errorf("group %q not found", groupName)
+	}
+	for j, cmd := range group.Commands {
+		if cmd.Name == cmdNameOrID || strconv.Itoa(cmd.ID) == cmdNameOrID {
+			if cmd.ReadOnly() {
+				return readOnlyError(cmd)
+			}
+			// Check if new name conflicts with another command
+			if newName != cmd.Name {
+				for k, other := range group.Commands {
+					if k != j && other.Name == newName {
+						return fmt.Errorf("command %q already exists in group %q", newName, groupName)
 					}
-					return nil
 				}
 			}
-			return fmt.Errorf("command %q not found in group %q", cmdNameOrID, groupName)
+			group.Commands[j].Name = newName
+			group.Commands[j].Command = newCommand
+			group.Commands[j].Description = newDescription
+			if newAction != "" {
+				group.Commands[j].DefaultAction = newAction
+			}
+			return nil
 		}
 	}
-	return fmt.Errorf("group %q not found", groupName)
+	return fmt.Errorf("command %q not found in group %q", cmdNameOrID, groupName)
 }
 
 // SetCommandAction sets the default action for a command
 func (c *Config) SetCommandAction(id int, action ActionType) error {
-	for i, g := range c.Groups {
-		for j := range g.Commands {
-			if c.Groups[i].Commands[j].ID == id {
-				c.Groups[i].Commands[j].DefaultAction = action
-				return nil
-			}
-		}
+	cmd, _ := c.GetCommandByID(id)
+	if cmd == nil {
+		return fmt.Errorf("command with ID %d not found", id)
 	}
-	return fmt.Errorf("command with ID %d not found", id)
+	if cmd.ReadOnly() {
+		return readOnlyError(*cmd)
+	}
+	cmd.DefaultAction = action
+	return nil
 }
 
 func (c *Config) AllCommands() []Command {
 	var all []Command
-	for _, g := range c.Groups {
+	walkGroups(c.Groups, "", func(_ string, g *Group) {
 		all = append(all, g.Commands...)
-	}
+	})
 	return all
 }
 
+// FlatCommand is a command together with its group path. The json/yaml tags
+// are the stable field names used by machine-readable CLI output.
 type FlatCommand struct {
-	ID            int
-	GroupName     string
-	Name          string
-	Command       string
-	Description   string
-	DefaultAction ActionType
+	ID            int               `json:"id" yaml:"id"`
+	GroupName     string            `json:"group" yaml:"group"`
+	Name          string            `json:"name" yaml:"name"`
+	Command       string            `json:"command" yaml:"command"`
+	Description   string            `json:"description" yaml:"description"`
+	DefaultAction ActionType        `json:"default_action" yaml:"default_action"`
+	Tags          []string          `json:"tags" yaml:"tags"`
+	Source        string            `json:"source,omitempty" yaml:"source,omitempty"`
+	Steps         []Step            `json:"steps,omitempty" yaml:"steps,omitempty"`
+	OnFailure     FailurePolicy     `json:"on_failure,omitempty" yaml:"on_failure,omitempty"`
+	Cwd           string            `json:"cwd,omitempty" yaml:"cwd,omitempty"`
+	Env           map[string]string `json:"env,omitempty" yaml:"env,omitempty"`
+	Confirm       bool              `json:"confirm,omitempty" yaml:"confirm,omitempty"`
+	// AllowDefaultRun keeps a default action of run for a dangerous command.
+	AllowDefaultRun bool `json:"allow_default_run,omitempty" yaml:"allow_default_run,omitempty"`
+}
+
+// Flatten returns the command as a FlatCommand belonging to the group at groupName.
+func (cmd Command) Flatten(groupName string) FlatCommand {
+	return FlatCommand{
+		ID:              cmd.ID,
+		GroupName:       groupName,
+		Name:            cmd.Name,
+		Command:         cmd.Command,
+		Description:     cmd.Description,
+		DefaultAction:   cmd.DefaultAction,
+		Tags:            append([]string{}, cmd.Tags...),
+		Source:          cmd.Source,
+		Steps:           cmd.Steps,
+		OnFailure:       cmd.OnFailure,
+		Cwd:             cmd.Cwd,
+		Env:             cmd.Env,
+		Confirm:         cmd.Confirm,
+		AllowDefaultRun: cmd.AllowDefaultRun,
+	}
 }
 
 func (c *Config) FlatCommands() []FlatCommand {
 	var all []FlatCommand
-	for _, g := range c.Groups {
+	walkGroups(c.Groups, "", func(path string, g *Group) {
 		for _, cmd := range g.Commands {
-			all = append(all, FlatCommand{
-				ID:            cmd.ID,
-				GroupName:     g.Name,
-				Name:          cmd.Name,
-				Command:       cmd.Command,
-				Description:   cmd.Description,
-				DefaultAction: cmd.DefaultAction,
-			})
+			all = append(all, cmd.Flatten(path))
 		}
-	}
+	})
 	return all
 }
@@ -489,6 +489,31 @@ groups:
 	}
 }
 
+func TestConfigValidation_InsertAction(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "config.yaml")
+
+	content := `
+groups:
+  - name: test
+    commands:
+      - name: cmd1
+        command: echo hi
+        default_action: insert
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+
+	cfg, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("unexpected error for insert action: %v", err)
+	}
+	if cfg.Groups[0].Commands[0].DefaultAction != ActionInsert {
+		t.Errorf("expected insert action, got %q", cfg.Groups[0].Commands[0].DefaultAction)
+	}
+}
+
 func TestConfigValidation_EmptyGroupName(t *testing.T) {
 	tmpDir := t.TempDir()
 	path := filepath.Join(tmpDir, "config.yaml")
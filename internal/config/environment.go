@@ -0,0 +1,100 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"regexp"
+)
+
+// Environment is where a command runs: its working directory, or bkmk's own
+// if empty, and the variables added to bkmk's environment. It converts to
+// runner.Options.
+type Environment struct {
+	Dir string
+	Env map[string]string
+}
+
+// envName matches a valid environment variable name.
+var envName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func validateEnv(env map[string]string) error {
+	for name := range env {
+		if !envName.MatchString(name) {
+			return fmt.Errorf("invalid env variable name %q", name)
+		}
+	}
+	return nil
+}
+
+// Environment returns where cmd runs: its own cwd and env over the defaults
+// of its group and the groups above it, nearest first. $VAR and a leading ~
+// are expanded in the directory and in variable values.
+func (c *Config) Environment(cmd FlatCommand) (Environment, error) {
+	var env Environment
+	cwd, vars := c.groupDefaults(cmd.GroupName)
+	if cmd.Cwd != "" {
+		cwd = cmd.Cwd
+	}
+	maps.Copy(vars, cmd.Env)
+
+	for name, value := range vars {
+		expanded, err := expand(value)
+		if err != nil {
+			return env, err
+		}
+		vars[name] = expanded
+	}
+	if len(vars) > 0 {
+		env.Env = vars
+	}
+
+	if cwd != "" {
+		dir, err := expand(cwd)
+		if err != nil {
+			return env, err
+		}
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return env, fmt.Errorf("working directory %s does not exist", dir)
+		}
+		env.Dir = dir
+	}
+	return env, nil
+}
+
+// groupDefaults returns the cwd and env that the group at path and the groups
+// above it give their commands, nearest first, without expanding them.
+func (c *Config) groupDefaults(path string) (string, map[string]string) {
+	var cwd string
+	vars := make(map[string]string)
+	names := SplitPath(path)
+	for i := range names {
+		g := c.GetGroup(JoinPath(names[:i+1]...))
+		if g == nil {
+			break
+		}
+		if g.Cwd != "" {
+			cwd = g.Cwd
+		}
+		maps.Copy(vars, g.Env)
+	}
+	return cwd, vars
+}
+
+// StepEnvironment returns where a step of workflow runs: for a step that
+// refers to another bookmark, that bookmark's environment, otherwise the
+// workflow's.
+func (c *Config) StepEnvironment(workflow FlatCommand, step Step) (Environment, error) {
+	if step.Ref != 0 {
+		if ref, group := c.GetCommandByID(step.Ref); ref != nil {
+			return c.Environment(ref.Flatten(group))
+		}
+	}
+	return c.Environment(workflow)
+}
+
+// expand replaces $VAR and ${VAR} with their values, then a leading ~ with
+// the home directory.
+func expand(s string) (string, error) {
+	return expandHome(os.ExpandEnv(s))
+}
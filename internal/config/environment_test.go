@@ -0,0 +1,86 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEnvironment(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("PROJECT", "api")
+	for _, dir := range []string{"src/api", "src/api/db"} {
+		if err := os.MkdirAll(filepath.Join(home, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg, err := Parse([]byte(`
+groups:
+  - name: work
+    cwd: ~/src/$PROJECT
+    env:
+      STAGE: dev
+      LOG: info
+    groups:
+      - name: db
+        env: {LOG: debug}
+        commands:
+          - name: migrate
+            command: make migrate
+            cwd: ${HOME}/src/api/db
+            env: {STAGE: test, DATA: ~/data}
+          - name: status
+            command: make status
+`), "config.yaml")
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	migrate, _ := cfg.GetCommand("work/db", "migrate")
+	env, err := cfg.Environment(migrate.Flatten("work/db"))
+	if err != nil {
+		t.Fatalf("Environment() error: %v", err)
+	}
+	want := Environment{
+		Dir: filepath.Join(home, "src/api/db"),
+		Env: map[string]string{"STAGE": "test", "LOG": "debug", "DATA": filepath.Join(home, "data")},
+	}
+	if !reflect.DeepEqual(env, want) {
+		t.Errorf("Environment(migrate) = %+v, want %+v", env, want)
+	}
+
+	status, _ := cfg.GetCommand("work/db", "status")
+	env, err = cfg.Environment(status.Flatten("work/db"))
+	if err != nil {
+		t.Fatalf("Environment() error: %v", err)
+	}
+	if env.Dir != filepath.Join(home, "src/api") || env.Env["STAGE"] != "dev" || env.Env["LOG"] != "debug" {
+		t.Errorf("expected the group defaults, got %+v", env)
+	}
+
+	missing := FlatCommand{Command: "ls", Cwd: "~/nowhere"}
+	if _, err := cfg.Environment(missing); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("expected an error for a missing directory, got %v", err)
+	}
+	if env, err := cfg.Environment(FlatCommand{Command: "ls"}); err != nil || !reflect.DeepEqual(env, Environment{}) {
+		t.Errorf("expected no environment for a plain command, got %+v, %v", env, err)
+	}
+}
+
+func TestEnvironmentValidation(t *testing.T) {
+	_, err := Parse([]byte(`
+groups:
+  - name: g
+    commands:
+      - name: c
+        command: ls
+        env: {"BAD-NAME": x}
+`), "config.yaml")
+	if err == nil || !strings.Contains(err.Error(), `invalid env variable name "BAD-NAME"`) {
+		t.Errorf("expected an invalid name error, got %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// PathSeparator separates group names in a group path such as "cloud/aws/ec2".
+const PathSeparator = "/"
+
+// SplitPath splits a group path into its group names, ignoring empty
+// segments so "cloud//aws/" and "cloud/aws" are the same path.
+func SplitPath(path string) []string {
+	var names []string
+	for _, name := range strings.Split(path, PathSeparator) {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// JoinPath joins group names or paths into a single path.
+func JoinPath(parts ...string) string {
+	var names []string
+	for _, part := range parts {
+		names = append(names, SplitPath(part)...)
+	}
+	return strings.Join(names, PathSeparator)
+}
+
+// ParentPath returns the path of the group containing path, or "" for a
+// top-level group.
+func ParentPath(path string) string {
+	names := SplitPath(path)
+	if len(names) <= 1 {
+		return ""
+	}
+	return strings.Join(names[:len(names)-1], PathSeparator)
+}
+
+// BaseName returns the last group name in path.
+func BaseName(path string) string {
+	names := SplitPath(path)
+	if len(names) == 0 {
+		return ""
+	}
+	return names[len(names)-1]
+}
+
+// IsWithin reports whether path is ancestor or one of its subgroups.
+func IsWithin(path, ancestor string) bool {
+	path, ancestor = JoinPath(path), JoinPath(ancestor)
+	return path == ancestor || strings.HasPrefix(path, ancestor+PathSeparator)
+}
+
+// walkGroups calls fn for every group and subgroup, parents before children,
+// with the group's full path. Groups are passed by pointer so fn can modify
+// their commands, but it must not add or remove groups.
+func walkGroups(groups []Group, parent string, fn func(path string, g *Group)) {
+	for i := range groups {
+		path := JoinPath(parent, groups[i].Name)
+		fn(path, &groups[i])
+		walkGroups(groups[i].Groups, path, fn)
+	}
+}
+
+// GroupPaths returns the path of every group and subgroup, parents before children.
+func (c *Config) GroupPaths() []string {
+	var paths []string
+	walkGroups(c.Groups, "", func(path string, _ *Group) {
+		paths = append(paths, path)
+	})
+	return paths
+}
+
+// GetGroup returns the group at path, or nil if there is none. A plain group
+// name is the path of a top-level group.
+func (c *Config) GetGroup(path string) *Group {
+	names := SplitPath(path)
+	if len(names) == 0 {
+		return nil
+	}
+	groups := c.Groups
+	var group *Group
+	for _, name := range names {
+		idx := slices.IndexFunc(groups, func(g Group) bool { return g.Name == name })
+		if idx == -1 {
+			return nil
+		}
+		group = &groups[idx]
+		groups = group.Groups
+	}
+	return group
+}
+
+// EnsureGroup returns the group at path, creating it and any missing parents.
+// It returns nil if path names no group.
+func (c *Config) EnsureGroup(path string) *Group {
+	siblings := &c.Groups
+	var group *Group
+	for _, name := range SplitPath(path) {
+		idx := slices.IndexFunc(*siblings, func(g Group) bool { return g.Name == name })
+		if idx == -1 {
+			*siblings = append(*siblings, Group{Name: name, Commands: []Command{}})
+			idx = len(*siblings) - 1
+		}
+		group = &(*siblings)[idx]
+		siblings = &group.Groups
+	}
+	return group
+}
+
+// siblingsOf returns the slice holding the group at path: the config's
+// top-level groups or its parent's subgroups.
+func (c *Config) siblingsOf(path string) (*[]Group, error) {
+	parent := ParentPath(path)
+	if parent == "" {
+		return &c.Groups, nil
+	}
+	g := c.GetGroup(parent)
+	if g == nil {
+		return nil, fmt.Errorf("group %q not found", parent)
+	}
+	return &g.Groups, nil
+}
+
+// cloneGroup deep-copies a group's commands and subgroups.
+func cloneGroup(g Group) Group {
+	clone := Group{Name: g.Name, Cwd: g.Cwd, Env: maps.Clone(g.Env), Commands: slices.Clone(g.Commands)}
+	if clone.Commands == nil {
+		clone.Commands = []Command{}
+	}
+	for _, sub := range g.Groups {
+		clone.Groups = append(clone.Groups, cloneGroup(sub))
+	}
+	return clone
+}
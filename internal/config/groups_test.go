@@ -0,0 +1,216 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func nestedConfig() *Config {
+	return &Config{
+		Groups: []Group{
+			{Name: "cloud", Commands: []Command{}, Groups: []Group{
+				{Name: "aws", Commands: []Command{
+					{ID: 1, Name: "whoami", Command: "aws sts get-caller-identity"},
+				}, Groups: []Group{
+					{Name: "ec2", Commands: []Command{
+						{ID: 2, Name: "ls", Command: "aws ec2 describe-instances"},
+					}},
+				}},
+			}},
+			{Name: "git", Commands: []Command{
+				{ID: 3, Name: "st", Command: "git status"},
+			}},
+		},
+		NextID: 4,
+	}
+}
+
+func TestPathHelpers(t *testing.T) {
+	if got := SplitPath("/cloud//aws/ "); !reflect.DeepEqual(got, []string{"cloud", "aws"}) {
+		t.Errorf("SplitPath() = %v", got)
+	}
+	if got := JoinPath("cloud/", "aws", "", "ec2"); got != "cloud/aws/ec2" {
+		t.Errorf("JoinPath() = %q", got)
+	}
+	if got := ParentPath("cloud/aws/ec2"); got != "cloud/aws" {
+		t.Errorf("ParentPath() = %q", got)
+	}
+	if got := ParentPath("cloud"); got != "" {
+		t.Errorf("ParentPath() of top-level group = %q", got)
+	}
+	if got := BaseName("cloud/aws/ec2"); got != "ec2" {
+		t.Errorf("BaseName() = %q", got)
+	}
+	if !IsWithin("cloud/aws/ec2", "cloud") || !IsWithin("cloud", "cloud") || IsWithin("cloudy", "cloud") {
+		t.Error("IsWithin() gave the wrong answer")
+	}
+}
+
+func TestNestedGroups_FlatCommandsAndLookup(t *testing.T) {
+	cfg := nestedConfig()
+
+	var groups []string
+	for _, cmd := range cfg.FlatCommands() {
+		groups = append(groups, cmd.GroupName)
+	}
+	if want := []string{"cloud/aws", "cloud/aws/ec2", "git"}; !reflect.DeepEqual(groups, want) {
+		t.Errorf("FlatCommands() groups = %v, want %v", groups, want)
+	}
+
+	if want := []string{"cloud", "cloud/aws", "cloud/aws/ec2", "git"}; !reflect.DeepEqual(cfg.GroupPaths(), want) {
+		t.Errorf("GroupPaths() = %v, want %v", cfg.GroupPaths(), want)
+	}
+
+	cmd, err := cfg.GetCommand("cloud/aws/ec2", "ls")
+	if err != nil || cmd.ID != 2 {
+		t.Fatalf("GetCommand by path failed: %v", err)
+	}
+	if _, path := cfg.GetCommandByID(2); path != "cloud/aws/ec2" {
+		t.Errorf("GetCommandByID path = %q", path)
+	}
+	if cfg.GetGroup("aws") != nil {
+		t.Error("subgroup should not be found at the top level")
+	}
+}
+
+func TestNestedGroups_Mutations(t *testing.T) {
+	cfg := nestedConfig()
+
+	// Adding to a missing path creates every level
+	if err := cfg.AddCommand("cloud/gcp/gke", "creds", "gcloud container clusters get-credentials", ""); err != nil {
+		t.Fatalf("AddCommand failed: %v", err)
+	}
+	if g := cfg.GetGroup("cloud/gcp/gke"); g == nil || len(g.Commands) != 1 {
+		t.Fatal("expected command in newly created nested group")
+	}
+
+	if err := cfg.AddGroup("cloud/aws"); err == nil {
+		t.Error("expected error adding an existing group")
+	}
+	if err := cfg.AddGroup("cloud/aws/s3"); err != nil {
+		t.Errorf("AddGroup failed: %v", err)
+	}
+
+	if err := cfg.RenameGroup("cloud/aws/ec2", "compute"); err != nil {
+		t.Fatalf("RenameGroup failed: %v", err)
+	}
+	if cfg.GetGroup("cloud/aws/compute") == nil {
+		t.Error("expected renamed group")
+	}
+	if err := cfg.RenameGroup("cloud/aws/compute", "s3"); err == nil {
+		t.Error("expected error renaming onto a sibling")
+	}
+	if err := cfg.RenameGroup("cloud/aws/compute", "a/b"); err == nil {
+		t.Error("expected error for name containing '/'")
+	}
+
+	if err := cfg.SetCommandTags(2, []string{"ec2"}); err != nil {
+		t.Errorf("SetCommandTags on nested command failed: %v", err)
+	}
+
+	if err := cfg.RemoveCommandByID(2); err != nil {
+		t.Fatalf("RemoveCommandByID failed: %v", err)
+	}
+	if len(cfg.GetGroup("cloud/aws/compute").Commands) != 0 {
+		t.Error("expected nested command removed")
+	}
+
+	if err := cfg.RemoveGroup("cloud/aws"); err != nil {
+		t.Fatalf("RemoveGroup failed: %v", err)
+	}
+	if cfg.GetGroup("cloud/aws/compute") != nil || cfg.GetGroup("cloud") == nil {
+		t.Error("RemoveGroup should remove the subtree only")
+	}
+}
+
+func TestNestedGroups_LoadAndSave(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "config.yaml")
+
+	content := `groups:
+  - name: cloud
+    commands: []
+    groups:
+      - name: aws
+        commands:
+          - name: whoami
+            command: aws sts get-caller-identity
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+
+	cfg, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom failed: %v", err)
+	}
+	cmd, _ := cfg.GetCommand("cloud/aws", "whoami")
+	if cmd == nil || cmd.ID == 0 {
+		t.Fatal("expected nested command with a migrated ID")
+	}
+
+	if err := cfg.SaveTo(path); err != nil {
+		t.Fatalf("SaveTo failed: %v", err)
+	}
+	reloaded, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if !reflect.DeepEqual(reloaded.FlatCommands(), cfg.FlatCommands()) {
+		t.Error("nested groups did not survive a save")
+	}
+}
+
+func TestNestedGroups_SlashInNameRejected(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "config.yaml")
+
+	content := `groups:
+  - name: cloud/aws
+    commands: []
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+
+	_, err := LoadFrom(path)
+	if err == nil || !strings.Contains(err.Error(), "cannot contain") {
+		t.Errorf("expected error for '/' in group name, got: %v", err)
+	}
+}
+
+func TestNestedGroups_ExportImport(t *testing.T) {
+	cfg := nestedConfig()
+
+	exported, err := cfg.Export([]string{"cloud/aws/ec2"})
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	flat := exported.FlatCommands()
+	if len(flat) != 1 || flat[0].GroupName != "cloud/aws/ec2" {
+		t.Fatalf("expected only the ec2 command at its path, got %+v", flat)
+	}
+
+	exported, err = cfg.Export([]string{"cloud", "cloud/aws"})
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if got := len(exported.FlatCommands()); got != 2 {
+		t.Errorf("expected subtree exported once, got %d commands", got)
+	}
+
+	dest := &Config{Groups: []Group{}, NextID: 1}
+	report := dest.Import(exported, ImportOptions{})
+	if len(report.Added) != 2 {
+		t.Fatalf("expected 2 added, got %+v", report)
+	}
+	if report.Added[1].Group != "cloud/aws/ec2" {
+		t.Errorf("expected import report to use paths, got %q", report.Added[1].Group)
+	}
+	if dest.GetGroup("cloud/aws/ec2") == nil {
+		t.Error("expected nested groups recreated on import")
+	}
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Guardrails configures which commands are dangerous: those matching one of
+// the built-in patterns, unless turned off, or one of the user's own.
+type Guardrails struct {
+	Patterns        []string `yaml:"patterns,omitempty"`
+	DisableBuiltins bool     `yaml:"disable_builtins,omitempty"`
+}
+
+// builtinDangerPatterns catch commands that are hard to undo or aimed at
+// production.
+var builtinDangerPatterns = compilePatterns(
+	`(?i)\brm\s+(-[a-z]*\s+)*-[a-z]*(rf|fr)`,
+	`(?i)\bdrop\s+(table|database|schema)\b`,
+	`(?i)\btruncate\s+table\b`,
+	`(?i)\bdelete\s+from\b`,
+	`\bkubectl\s+delete\b`,
+	`\bterraform\s+destroy\b`,
+	`\bmkfs\b`,
+	`\bdd\s+.*\bof=`,
+	`--force(\s|$)`, // not --force-with-lease
+	`(?i)\bprod(uction)?\b`,
+)
+
+func compilePatterns(patterns ...string) []*regexp.Regexp {
+	res := make([]*regexp.Regexp, len(patterns))
+	for i, p := range patterns {
+		res[i] = regexp.MustCompile(p)
+	}
+	return res
+}
+
+func validateGuardrails(g Guardrails) error {
+	for _, p := range g.Patterns {
+		if _, err := regexp.Compile(p); err != nil {
+			return fmt.Errorf("guardrails: invalid pattern %q: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// MatchDanger returns the part of command matched by a danger pattern, or ""
+// if none match.
+func (c *Config) MatchDanger(command string) string {
+	var patterns []*regexp.Regexp
+	if !c.Guardrails.DisableBuiltins {
+		patterns = append(patterns, builtinDangerPatterns...)
+	}
+	for _, p := range c.Guardrails.Patterns {
+		if re, err := regexp.Compile(p); err == nil { // invalid ones are rejected on load
+			patterns = append(patterns, re)
+		}
+	}
+	for _, re := range patterns {
+		if match := re.FindString(command); match != "" {
+			return strings.TrimSpace(match)
+		}
+	}
+	return ""
+}
+
+// Danger returns why running cmd needs a typed confirmation, or "" if it does
+// not. A command is dangerous when it sets confirm or matches a danger
+// pattern; a workflow when any of its steps is. Steps with a command are
+// checked as given, so the steps of a workflow can be checked after their
+// placeholders are filled in.
+func (c *Config) Danger(cmd FlatCommand) string {
+	if cmd.Confirm {
+		return "it is marked confirm"
+	}
+	if !cmd.IsWorkflow() {
+		if match := c.MatchDanger(cmd.Command); match != "" {
+			return fmt.Sprintf("it contains %q", match)
+		}
+		return ""
+	}
+	for i, step := range cmd.Steps {
+		command := step.Command
+		if step.Ref != 0 {
+			if ref, _ := c.GetCommandByID(step.Ref); ref != nil {
+				if ref.Confirm {
+					return fmt.Sprintf("step %d runs %q, which is marked confirm", i+1, ref.Name)
+				}
+				if command == "" {
+					command = ref.Command
+				}
+			}
+		}
+		if match := c.MatchDanger(command); match != "" {
+			return fmt.Sprintf("step %d contains %q", i+1, match)
+		}
+	}
+	return ""
+}
+
+// RefusesDefaultRun reports whether cmd's default action of run is ignored
+// because the command is dangerous, so it is only run from the action menu.
+func (c *Config) RefusesDefaultRun(cmd FlatCommand) bool {
+	return cmd.DefaultAction == ActionRun && !cmd.AllowDefaultRun && c.Danger(cmd) != ""
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMatchDanger(t *testing.T) {
+	cfg := &Config{}
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{"rm -rf build", "rm -rf"},
+		{"rm -v -Rf /tmp/x", "rm -v -Rf"},
+		{"rm build.log", ""},
+		{"psql -c 'DROP TABLE users'", "DROP TABLE"},
+		{"kubectl delete ns staging", "kubectl delete"},
+		{"git push --force origin main", "--force"},
+		{"git push origin main --force", "--force"},
+		{"git push --force-with-lease origin main", ""},
+		{"kubectl --context prod get pods", "prod"},
+		{"ssh deploy@production", "production"},
+		{"make product", ""},
+		{"ffmpeg -i in.mp4 produce.mp4", ""},
+		{"./reproduce.sh", ""},
+		{"ls -la", ""},
+	}
+	for _, tt := range tests {
+		if got := cfg.MatchDanger(tt.command); got != tt.want {
+			t.Errorf("MatchDanger(%q) = %q, want %q", tt.command, got, tt.want)
+		}
+	}
+
+	custom := &Config{Guardrails: Guardrails{Patterns: []string{`\bhelm\s+uninstall\b`}, DisableBuiltins: true}}
+	if got := custom.MatchDanger("helm uninstall api"); got != "helm uninstall" {
+		t.Errorf("expected the user pattern to match, got %q", got)
+	}
+	if got := custom.MatchDanger("rm -rf build"); got != "" {
+		t.Errorf("expected built-in patterns to be off, got %q", got)
+	}
+}
+
+func TestDanger(t *testing.T) {
+	cfg, err := Parse([]byte(`
+groups:
+  - name: ops
+    commands:
+      - id: 1
+        name: wipe
+        command: ./wipe.sh
+        confirm: true
+      - id: 2
+        name: clean
+        command: rm -rf dist
+        default_action: run
+      - id: 3
+        name: nuke
+        command: rm -rf cache
+        default_action: run
+        allow_default_run: true
+      - id: 4
+        name: reset
+        steps:
+          - command: echo start
+          - ref: 1
+`), "config.yaml")
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	flat := func(id int) FlatCommand {
+		cmd, path := cfg.GetCommandByID(id)
+		return cmd.Flatten(path)
+	}
+
+	if got := cfg.Danger(flat(1)); got != "it is marked confirm" {
+		t.Errorf("Danger(wipe) = %q", got)
+	}
+	if got := cfg.Danger(flat(2)); got != `it contains "rm -rf"` {
+		t.Errorf("Danger(clean) = %q", got)
+	}
+	if got := cfg.Danger(flat(4)); !strings.Contains(got, `step 2 runs "wipe"`) {
+		t.Errorf("Danger(reset) = %q, want it to name the confirmed step", got)
+	}
+	if !cfg.RefusesDefaultRun(flat(2)) {
+		t.Error("expected the default run of a dangerous command to be refused")
+	}
+	if cfg.RefusesDefaultRun(flat(3)) {
+		t.Error("expected allow_default_run to keep the default run")
+	}
+}
+
+func TestGuardrailsValidation(t *testing.T) {
+	_, err := Parse([]byte(`
+guardrails:
+  patterns: ["(unclosed"]
+groups: []
+`), "config.yaml")
+	if err == nil || !strings.Contains(err.Error(), `invalid pattern "(unclosed"`) {
+		t.Errorf("expected an invalid pattern error, got %v", err)
+	}
+}
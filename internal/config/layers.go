@@ -0,0 +1,297 @@
+package config
+
+import (
+	"fmt"
+	"hash/fnv"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// EnvConfig names the environment variable that overrides the config path.
+const EnvConfig = "BKMK_CONFIG"
+
+// pathOverride is set by SetPath, e.g. from the --config flag.
+var pathOverride string
+
+// SetPath makes Path return path, taking precedence over $BKMK_CONFIG.
+func SetPath(path string) {
+	pathOverride = path
+}
+
+// Path returns the writable config file in use: the path given to SetPath,
+// then $BKMK_CONFIG, then DefaultPath. Backups and usage stats live beside it.
+func Path() (string, error) {
+	if pathOverride != "" {
+		return expandHome(pathOverride)
+	}
+	if env := os.Getenv(EnvConfig); env != "" {
+		return expandHome(env)
+	}
+	return DefaultPath()
+}
+
+// expandHome replaces a leading "~" with the user's home directory.
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+}
+
+// ReadOnly reports whether the command comes from an included file and so
+// cannot be edited or removed.
+func (cmd Command) ReadOnly() bool {
+	return cmd.Source != ""
+}
+
+// readOnlyError explains that cmd belongs to an included file.
+func readOnlyError(cmd Command) error {
+	return fmt.Errorf("command %q is read-only (included from %s)", cmd.Name, cmd.Source)
+}
+
+// readOnlyIn returns the first read-only command in g or its subgroups.
+func readOnlyIn(g *Group) (Command, bool) {
+	var found Command
+	ok := false
+	walkGroups([]Group{*g}, "", func(_ string, sub *Group) {
+		for _, cmd := range sub.Commands {
+			if !ok && cmd.ReadOnly() {
+				found, ok = cmd, true
+			}
+		}
+	})
+	return found, ok
+}
+
+// includeFiles expands the include patterns into the files they name, in
+// order and without duplicates. Relative patterns are relative to dir and a
+// directory includes every .yaml and .yml file in it. A pattern without glob
+// characters must exist; a glob may match nothing.
+func (c *Config) includeFiles(dir string) ([]string, error) {
+	var files []string
+	add := func(file string) {
+		if !slices.Contains(files, file) {
+			files = append(files, file)
+		}
+	}
+
+	for _, pattern := range c.Include {
+		expanded, err := expandHome(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if !filepath.IsAbs(expanded) {
+			expanded = filepath.Join(dir, expanded)
+		}
+
+		matches := []string{expanded}
+		if strings.ContainsAny(expanded, "*?[") {
+			if matches, err = filepath.Glob(expanded); err != nil {
+				return nil, fmt.Errorf("invalid include pattern %q: %w", pattern, err)
+			}
+		}
+
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read include %q: %w", pattern, err)
+			}
+			if !info.IsDir() {
+				add(match)
+				continue
+			}
+			var inDir []string
+			for _, ext := range []string{"*.yaml", "*.yml"} {
+				found, _ := filepath.Glob(filepath.Join(match, ext))
+				inDir = append(inDir, found...)
+			}
+			sort.Strings(inDir)
+			for _, file := range inDir {
+				add(file)
+			}
+		}
+	}
+	return files, nil
+}
+
+// Included commands are numbered in blocks of includeIDStride IDs, one block
+// per included file, chosen from a hash of the file's path. That keeps an
+// included command's ID the same from one load to the next, whatever else is
+// included, so it can be run, tracked and referred to by ID like any other.
+// The blocks fill the range from includeIDStride up to includeIDEnd, which
+// commands of the config's own skip.
+const (
+	includeIDStride = 10000
+	includeIDEnd    = 10000 * includeIDStride
+)
+
+// includeBase returns the first ID of the block for the included file.
+func includeBase(file string) int {
+	h := fnv.New32a()
+	h.Write([]byte(file))
+	return (1 + int(h.Sum32()%(includeIDEnd/includeIDStride-1))) * includeIDStride
+}
+
+// loadIncludes merges the commands of every included file into c, marking
+// them read-only with the file they came from. Earlier layers win: an
+// included command is hidden if its group already has one of the same name.
+// Included IDs never move NextID, so they are not written back on save.
+// Includes are not followed recursively and settings such as editor are only
+// read from the config itself.
+func (c *Config) loadIncludes(configPath string) error {
+	files, err := c.includeFiles(filepath.Dir(configPath))
+	if err != nil {
+		return err
+	}
+
+	ids := make(map[int]bool)
+	for _, cmd := range c.AllCommands() {
+		ids[cmd.ID] = true
+	}
+	bases := make(map[int]bool)
+
+	for _, file := range files {
+		if file == configPath {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read included config: %w", err)
+		}
+		inc, err := Parse(data, file)
+		if err != nil {
+			return err
+		}
+		// Two paths that hash to the same block are rare; the later file
+		// takes the next free one
+		base := includeBase(file)
+		for bases[base] {
+			base += includeIDStride
+			if base >= includeIDEnd {
+				base = includeIDStride
+			}
+		}
+		bases[base] = true
+		c.mergeIncluded(inc, file, base, ids)
+	}
+	return nil
+}
+
+// mergeIncluded adds the commands of inc to c as read-only commands from
+// source, numbered from base. Workflow steps that refer to a command by its
+// ID in inc are renumbered to match.
+func (c *Config) mergeIncluded(inc *Config, source string, base int, ids map[int]bool) {
+	newIDs := make(map[int]int)
+	walkGroups(inc.Groups, "", func(path string, ig *Group) {
+		// Groups that only exist in includes are dropped again on save
+		names := SplitPath(path)
+		owned := true
+		for i := range names {
+			prefix := JoinPath(names[:i+1]...)
+			if c.GetGroup(prefix) == nil {
+				c.EnsureGroup(prefix).Source = source
+			}
+			owned = owned && c.GetGroup(prefix).Source == source
+		}
+		group := c.GetGroup(path)
+		if group.Source == source && group.Cwd == "" && group.Env == nil {
+			group.Cwd, group.Env = ig.Cwd, ig.Env
+		}
+		// Where the groups above a command are not all the include's own,
+		// their cwd and env are not the include's either, so the command
+		// carries the include's defaults itself
+		var cwd string
+		var env map[string]string
+		if !owned {
+			cwd, env = inc.groupDefaults(path)
+		}
+
+		for _, cmd := range ig.Commands {
+			if slices.ContainsFunc(group.Commands, func(existing Command) bool {
+				return existing.Name == cmd.Name
+			}) {
+				continue
+			}
+			if cmd.Cwd == "" {
+				cmd.Cwd = cwd
+			}
+			if len(env) > 0 {
+				cmd.Env = mergeEnv(env, cmd.Env)
+			}
+			own := cmd.ID
+			cmd.ID = base + own
+			if own >= includeIDStride {
+				cmd.ID = base + 1
+			}
+			// A command of the config's own may already hold the ID
+			for ids[cmd.ID] {
+				cmd.ID++
+			}
+			ids[cmd.ID], newIDs[own] = true, cmd.ID
+			cmd.Source = source
+			group.Commands = append(group.Commands, cmd)
+		}
+	})
+
+	walkGroups(c.Groups, "", func(_ string, g *Group) {
+		for i := range g.Commands {
+			cmd := &g.Commands[i]
+			if cmd.Source != source || len(cmd.Steps) == 0 {
+				continue
+			}
+			cmd.Steps = slices.Clone(cmd.Steps)
+			for j, step := range cmd.Steps {
+				if id, ok := newIDs[step.Ref]; ok && step.Ref != 0 {
+					cmd.Steps[j].Ref = id
+				}
+			}
+		}
+	})
+}
+
+// writable returns the part of the config owned by the config file itself,
+// without commands and groups merged in from included files.
+func (c *Config) writable() *Config {
+	out := *c
+	out.Groups = writableGroups(c.Groups)
+	return &out
+}
+
+func writableGroups(groups []Group) []Group {
+	out := []Group{}
+	for _, g := range groups {
+		kept := Group{Name: g.Name, Commands: []Command{}, Groups: writableGroups(g.Groups)}
+		if g.Source == "" {
+			// The cwd and env of a group from an include belong to that file
+			kept.Cwd, kept.Env = g.Cwd, g.Env
+		}
+		for _, cmd := range g.Commands {
+			if !cmd.ReadOnly() {
+				kept.Commands = append(kept.Commands, cmd)
+			}
+		}
+		if len(kept.Groups) == 0 {
+			kept.Groups = nil
+		}
+		if g.Source != "" && len(kept.Commands) == 0 && len(kept.Groups) == 0 {
+			continue
+		}
+		out = append(out, kept)
+	}
+	return out
+}
+
+// mergeEnv returns the variables of base with those of over on top.
+func mergeEnv(base, over map[string]string) map[string]string {
+	merged := maps.Clone(base)
+	maps.Copy(merged, over)
+	return merged
+}
@@ -0,0 +1,336 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func TestPath_Overrides(t *testing.T) {
+	t.Setenv(EnvConfig, "")
+	defaultPath, err := DefaultPath()
+	if err != nil {
+		t.Fatalf("DefaultPath failed: %v", err)
+	}
+	if path, _ := Path(); path != defaultPath {
+		t.Errorf("expected default path %q, got %q", defaultPath, path)
+	}
+
+	t.Setenv(EnvConfig, "/tmp/work.yaml")
+	if path, _ := Path(); path != "/tmp/work.yaml" {
+		t.Errorf("expected $%s to be used, got %q", EnvConfig, path)
+	}
+
+	SetPath("/tmp/flag.yaml")
+	defer SetPath("")
+	if path, _ := Path(); path != "/tmp/flag.yaml" {
+		t.Errorf("expected SetPath to take precedence, got %q", path)
+	}
+}
+
+func TestIncludes_MergedReadOnly(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "config.yaml")
+
+	writeTestFile(t, path, `include:
+  - team/*.yaml
+groups:
+  - name: docker
+    commands:
+      - id: 1
+        name: ps
+        command: docker ps -a
+`)
+	writeTestFile(t, filepath.Join(tmpDir, "team", "shared.yaml"), `groups:
+  - name: docker
+    commands:
+      - id: 1
+        name: prune
+        command: docker system prune
+      - id: 2
+        name: ps
+        command: docker ps
+  - name: k8s
+    commands:
+      - id: 3
+        name: pods
+        command: kubectl get pods
+`)
+
+	cfg, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom failed: %v", err)
+	}
+
+	// The personal ps shadows the included one
+	ps, err := cfg.GetCommand("docker", "ps")
+	if err != nil || ps.ReadOnly() || ps.Command != "docker ps -a" {
+		t.Fatalf("expected personal ps to win, got %+v (%v)", ps, err)
+	}
+
+	prune, err := cfg.GetCommand("docker", "prune")
+	if err != nil {
+		t.Fatalf("expected included prune: %v", err)
+	}
+	if !prune.ReadOnly() || prune.Source != filepath.Join(tmpDir, "team", "shared.yaml") {
+		t.Errorf("expected prune to be read-only from shared.yaml, got source %q", prune.Source)
+	}
+	base := includeBase(prune.Source)
+	if prune.ID != base+1 {
+		t.Errorf("included command should be numbered in its file's block, got ID %d", prune.ID)
+	}
+	if cmd, _ := cfg.GetCommandByID(base + 3); cmd == nil || cmd.Name != "pods" {
+		t.Error("expected pods under its file's ID")
+	}
+	if cfg.NextID != 2 {
+		t.Errorf("included IDs must not move NextID, got %d", cfg.NextID)
+	}
+
+	// Read-only commands cannot be changed
+	if err := cfg.RemoveCommand("docker", "prune"); err == nil || !strings.Contains(err.Error(), "read-only") {
+		t.Errorf("expected read-only error removing prune, got %v", err)
+	}
+	if err := cfg.UpdateCommand("docker", "prune", "p", "x", ""); err == nil {
+		t.Error("expected error updating a read-only command")
+	}
+	if err := cfg.SetCommandTags(prune.ID, []string{"ops"}); err == nil {
+		t.Error("expected error tagging a read-only command")
+	}
+	if err := cfg.RemoveGroup("k8s"); err == nil {
+		t.Error("expected error removing a group with read-only commands")
+	}
+	if err := cfg.RenameGroup("docker", "containers"); err == nil {
+		t.Error("expected error renaming a group with read-only commands")
+	}
+
+	// Writes go to the personal file only
+	if err := cfg.AddCommand("k8s", "nodes", "kubectl get nodes", ""); err != nil {
+		t.Fatalf("AddCommand failed: %v", err)
+	}
+	if err := cfg.SaveTo(path); err != nil {
+		t.Fatalf("SaveTo failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read saved config: %v", err)
+	}
+	saved := string(data)
+	if strings.Contains(saved, "prune") || strings.Contains(saved, "pods") {
+		t.Errorf("included commands must not be written to the personal config:\n%s", saved)
+	}
+	if !strings.Contains(saved, "nodes") || !strings.Contains(saved, "team/*.yaml") {
+		t.Errorf("expected personal command and include to be saved:\n%s", saved)
+	}
+
+	reloaded, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if len(reloaded.FlatCommands()) != len(cfg.FlatCommands()) {
+		t.Errorf("expected %d commands after reload, got %d", len(cfg.FlatCommands()), len(reloaded.FlatCommands()))
+	}
+}
+
+func TestIncludes_StableIDs(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "config.yaml")
+
+	writeTestFile(t, path, `include:
+  - team.yaml
+  - ops.yaml
+groups:
+  - name: local
+    commands:
+      - id: 1
+        name: a
+        command: echo a
+`)
+	writeTestFile(t, filepath.Join(tmpDir, "team.yaml"), `groups:
+  - name: team
+    commands:
+      - name: build
+        command: make
+      - name: deploy
+        steps:
+          - ref: 1
+          - command: ./deploy.sh
+`)
+	writeTestFile(t, filepath.Join(tmpDir, "ops.yaml"), `groups:
+  - name: ops
+    commands:
+      - id: 1
+        name: restart
+        command: systemctl restart app
+`)
+
+	var firstIDs []int
+	for round := range 3 {
+		if _, err := UpdateAt(path, func(cfg *Config) error {
+			return cfg.AddCommand("local", fmt.Sprintf("added-%d", round), "true", "")
+		}); err != nil {
+			t.Fatalf("UpdateAt failed: %v", err)
+		}
+		cfg, err := LoadFrom(path)
+		if err != nil {
+			t.Fatalf("LoadFrom failed: %v", err)
+		}
+		var ids []int
+		for _, name := range [][2]string{{"team", "build"}, {"team", "deploy"}, {"ops", "restart"}} {
+			cmd, err := cfg.GetCommand(name[0], name[1])
+			if err != nil {
+				t.Fatalf("GetCommand(%v) failed: %v", name, err)
+			}
+			ids = append(ids, cmd.ID)
+		}
+		if round == 0 {
+			firstIDs = ids
+		} else if !slices.Equal(ids, firstIDs) {
+			t.Errorf("included IDs changed across saves: %v then %v", firstIDs, ids)
+		}
+		if cfg.NextID != round+3 {
+			t.Errorf("round %d: NextID = %d, want %d", round, cfg.NextID, round+3)
+		}
+	}
+	team, ops := includeBase(filepath.Join(tmpDir, "team.yaml")), includeBase(filepath.Join(tmpDir, "ops.yaml"))
+	if want := []int{team + 1, team + 2, ops + 1}; !slices.Equal(firstIDs, want) {
+		t.Errorf("included IDs = %v, want %v", firstIDs, want)
+	}
+
+	// The workflow's ref follows the command it named in its own file
+	cfg, _ := LoadFrom(path)
+	deploy, group := cfg.GetCommandByID(team + 2)
+	steps, err := cfg.ResolveSteps(deploy.Flatten(group))
+	if err != nil || steps[0].Name != "build" {
+		t.Errorf("expected the deploy workflow to start with build, got %+v (%v)", steps, err)
+	}
+}
+
+func TestIncludes_IDsSurviveReordering(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "config.yaml")
+	writeTestFile(t, filepath.Join(tmpDir, "team.yaml"), "groups:\n  - name: team\n    commands:\n      - {id: 1, name: build, command: make}\n")
+	writeTestFile(t, filepath.Join(tmpDir, "ops.yaml"), "groups:\n  - name: ops\n    commands:\n      - {id: 1, name: restart, command: systemctl restart app}\n")
+
+	idOf := func(include, group, name string) int {
+		t.Helper()
+		writeTestFile(t, path, include+"groups: []\n")
+		cfg, err := LoadFrom(path)
+		if err != nil {
+			t.Fatalf("LoadFrom failed: %v", err)
+		}
+		cmd, err := cfg.GetCommand(group, name)
+		if err != nil {
+			t.Fatalf("GetCommand failed: %v", err)
+		}
+		return cmd.ID
+	}
+
+	restart := idOf("include: [team.yaml, ops.yaml]\n", "ops", "restart")
+	if got := idOf("include: [ops.yaml, team.yaml]\n", "ops", "restart"); got != restart {
+		t.Errorf("reordering includes changed the ID from %d to %d", restart, got)
+	}
+	if got := idOf("include: [ops.yaml]\n", "ops", "restart"); got != restart {
+		t.Errorf("removing an include changed the ID from %d to %d", restart, got)
+	}
+}
+
+func TestNewIDSkipsIncludeRange(t *testing.T) {
+	cfg := &Config{NextID: includeIDStride - 1}
+	for _, name := range []string{"a", "b"} {
+		if err := cfg.AddCommand("local", name, "true", ""); err != nil {
+			t.Fatalf("AddCommand failed: %v", err)
+		}
+	}
+	a, _ := cfg.GetCommand("local", "a")
+	b, _ := cfg.GetCommand("local", "b")
+	if a.ID != includeIDStride-1 || b.ID != includeIDEnd {
+		t.Errorf("expected IDs %d and %d, got %d and %d", includeIDStride-1, includeIDEnd, a.ID, b.ID)
+	}
+}
+
+func TestIncludes_GroupEnvironmentStaysInInclude(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "config.yaml")
+	writeTestFile(t, path, "include:\n  - team.yaml\ngroups: []\n")
+	writeTestFile(t, filepath.Join(tmpDir, "team.yaml"), `groups:
+  - name: team
+    cwd: /srv/team
+    env: {STAGE: prod}
+    commands:
+      - name: deploy
+        command: ./deploy.sh
+`)
+
+	// A command of your own in a group that only exists in the include
+	if _, err := UpdateAt(path, func(cfg *Config) error {
+		return cfg.AddCommand("team", "logs", "tail -f app.log", "")
+	}); err != nil {
+		t.Fatalf("UpdateAt failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read saved config: %v", err)
+	}
+	if saved := string(data); !strings.Contains(saved, "logs") || strings.Contains(saved, "/srv/team") || strings.Contains(saved, "STAGE") {
+		t.Errorf("expected only the command saved, not the include's cwd and env:\n%s", saved)
+	}
+
+	// The included command still runs with its file's defaults
+	cfg, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom failed: %v", err)
+	}
+	deploy, err := cfg.GetCommand("team", "deploy")
+	if err != nil || deploy.Cwd != "/srv/team" || deploy.Env["STAGE"] != "prod" {
+		t.Errorf("expected the included command to keep its file's cwd and env, got %+v (%v)", deploy, err)
+	}
+	if err := cfg.SaveTo(path); err != nil {
+		t.Fatalf("SaveTo failed: %v", err)
+	}
+	if data, _ := os.ReadFile(path); strings.Contains(string(data), "/srv/team") {
+		t.Errorf("included command's cwd must not be saved:\n%s", data)
+	}
+}
+
+func TestIncludes_DirectoryAndMissing(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "config.yaml")
+	teamDir := filepath.Join(tmpDir, "team")
+
+	writeTestFile(t, filepath.Join(teamDir, "b.yml"), "groups:\n  - name: b\n    commands:\n      - name: one\n        command: echo b\n")
+	writeTestFile(t, filepath.Join(teamDir, "a.yaml"), "groups:\n  - name: a\n    commands:\n      - name: one\n        command: echo a\n")
+	writeTestFile(t, filepath.Join(teamDir, "notes.txt"), "not config")
+	writeTestFile(t, path, "include: ["+teamDir+", missing/*.yaml]\ngroups: []\n")
+
+	cfg, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom failed: %v", err)
+	}
+	if paths := cfg.GroupPaths(); len(paths) != 2 || paths[0] != "a" || paths[1] != "b" {
+		t.Errorf("expected groups from both files in name order, got %v", paths)
+	}
+
+	writeTestFile(t, path, "include: [missing.yaml]\ngroups: []\n")
+	if _, err := LoadFrom(path); err == nil {
+		t.Error("expected error for a missing include without wildcards")
+	}
+
+	writeTestFile(t, filepath.Join(teamDir, "a.yaml"), "groups:\n  - name: a\n    bogus: true\n")
+	writeTestFile(t, path, "include: [team]\ngroups: []\n")
+	if _, err := LoadFrom(path); err == nil || !strings.Contains(err.Error(), "a.yaml") {
+		t.Errorf("expected invalid include to be reported by file, got %v", err)
+	}
+}
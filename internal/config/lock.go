@@ -0,0 +1,142 @@
+package config
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ErrModified is returned when saving a config whose file was changed by
+// something else since it was loaded. Reload it, or use Update, rather than
+// overwriting those changes.
+var ErrModified = errors.New("config file changed since it was loaded")
+
+// fileState identifies the contents of a config file at one point in time.
+type fileState struct {
+	path   string
+	exists bool
+	sum    [sha256.Size]byte
+}
+
+// stateOf returns the current state of the file at path.
+func stateOf(path string) (fileState, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return fileState{path: path}, nil
+	}
+	if err != nil {
+		return fileState{}, fmt.Errorf("failed to read config: %w", err)
+	}
+	return fileState{path: path, exists: true, sum: sha256.Sum256(data)}, nil
+}
+
+// LoadedFrom returns the file the config was loaded from, or "" if it was
+// not loaded from a file.
+func (c *Config) LoadedFrom() string {
+	return c.disk.path
+}
+
+// ChangedOnDisk reports whether the file the config was loaded from has been
+// changed, created or removed since it was loaded or last saved.
+func (c *Config) ChangedOnDisk() (bool, error) {
+	if c.disk.path == "" {
+		return false, nil
+	}
+	current, err := stateOf(c.disk.path)
+	if err != nil {
+		return false, err
+	}
+	return current != c.disk, nil
+}
+
+// Lock takes an exclusive advisory lock for the file at path, waiting for
+// any other bkmk process holding it. The lock is a separate file so the file
+// itself can be replaced by rename while it is held. The config and the
+// state files kept beside it each have their own.
+func Lock(path string) (unlock func(), err error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create directory for %s: %w", filepath.Base(path), err)
+	}
+	f, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock for %s: %w", filepath.Base(path), err)
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock %s: %w", filepath.Base(path), err)
+	}
+	return func() {
+		_ = unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+// WriteFileAtomic writes data to a temporary file beside path and renames it
+// into place, so readers see either the old or the new file and never a
+// partial write. If path is a symlink, as for a config kept with dotfiles,
+// the file it points to is replaced and the link kept. An existing file keeps
+// its mode; perm is the mode of a new one.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Update loads the config at Path, applies change and saves the result while
+// holding the config lock, so concurrent bkmk processes never lose each
+// other's changes. Nothing is saved if change returns an error.
+func Update(change func(*Config) error) (*Config, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	return UpdateAt(path, change)
+}
+
+// UpdateAt is Update for the config at path.
+func UpdateAt(path string, change func(*Config) error) (*Config, error) {
+	unlock, err := Lock(path)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	cfg, err := LoadFrom(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := change(cfg); err != nil {
+		return nil, err
+	}
+	if err := cfg.saveLocked(path); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
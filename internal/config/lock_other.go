@@ -0,0 +1,11 @@
+//go:build !unix
+
+package config
+
+import "os"
+
+// Advisory locking is only implemented on unix; elsewhere writes are still
+// atomic and stale saves are still detected.
+func lockFile(*os.File) error { return nil }
+
+func unlockFile(*os.File) error { return nil }
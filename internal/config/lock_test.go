@@ -0,0 +1,157 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestUpdateAt_ConcurrentWriters(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "config.yaml")
+
+	const writers, perWriter = 2, 10
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*perWriter)
+	for w := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perWriter {
+				_, err := UpdateAt(path, func(c *Config) error {
+					return c.AddCommand(fmt.Sprintf("writer%d", w), fmt.Sprintf("cmd%d", i), "echo", "")
+				})
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("UpdateAt failed: %v", err)
+		}
+	}
+
+	cfg, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom failed: %v", err)
+	}
+	cmds := cfg.FlatCommands()
+	if len(cmds) != writers*perWriter {
+		t.Fatalf("expected %d commands from both writers, got %d", writers*perWriter, len(cmds))
+	}
+	ids := make(map[int]bool)
+	for _, cmd := range cmds {
+		if ids[cmd.ID] {
+			t.Errorf("duplicate ID %d", cmd.ID)
+		}
+		ids[cmd.ID] = true
+	}
+
+	// Only the config, its lock and backups remain: no temp files
+	entries, err := os.ReadDir(tmpDir)
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	for _, e := range entries {
+		if name := e.Name(); name != "config.yaml" && name != "config.yaml.lock" && name != "backup" {
+			t.Errorf("unexpected file left behind: %s", name)
+		}
+	}
+}
+
+func TestSaveTo_DetectsChangesSinceLoad(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "config.yaml")
+
+	if err := (&Config{Groups: []Group{}}).SaveTo(path); err != nil {
+		t.Fatalf("SaveTo failed: %v", err)
+	}
+
+	first, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom failed: %v", err)
+	}
+	second, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom failed: %v", err)
+	}
+
+	if err := first.AddCommand("git", "st", "git status", ""); err != nil {
+		t.Fatalf("AddCommand failed: %v", err)
+	}
+	if err := first.SaveTo(path); err != nil {
+		t.Fatalf("first SaveTo failed: %v", err)
+	}
+	// Saving again from the same process is not a conflict
+	if err := first.SaveTo(path); err != nil {
+		t.Fatalf("repeat SaveTo failed: %v", err)
+	}
+
+	if err := second.AddCommand("git", "lg", "git log", ""); err != nil {
+		t.Fatalf("AddCommand failed: %v", err)
+	}
+	if err := second.SaveTo(path); !errors.Is(err, ErrModified) {
+		t.Fatalf("expected ErrModified for a stale config, got %v", err)
+	}
+
+	// Update replays the change on the latest version
+	if _, err := UpdateAt(path, func(c *Config) error {
+		return c.AddCommand("git", "lg", "git log", "")
+	}); err != nil {
+		t.Fatalf("UpdateAt failed: %v", err)
+	}
+	cfg, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom failed: %v", err)
+	}
+	if len(cfg.FlatCommands()) != 2 {
+		t.Errorf("expected both changes kept, got %+v", cfg.FlatCommands())
+	}
+}
+
+func TestSaveTo_KeepsSymlinkAndMode(t *testing.T) {
+	tmpDir := t.TempDir()
+	target := filepath.Join(tmpDir, "dotfiles", "bkmk.yaml")
+	path := filepath.Join(tmpDir, "config.yaml")
+	writeTestFile(t, target, "groups: []\n")
+	if err := os.Chmod(target, 0o600); err != nil {
+		t.Fatalf("Chmod failed: %v", err)
+	}
+	if err := os.Symlink(target, path); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	if _, err := UpdateAt(path, func(c *Config) error {
+		return c.AddCommand("git", "st", "git status", "")
+	}); err != nil {
+		t.Fatalf("UpdateAt failed: %v", err)
+	}
+
+	if info, err := os.Lstat(path); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("config should still be a symlink, got %v (%v)", info.Mode(), err)
+	}
+	data, err := os.ReadFile(target)
+	if err != nil || !strings.Contains(string(data), "git status") {
+		t.Errorf("expected the link's target to be updated, got %q (%v)", data, err)
+	}
+	if info, err := os.Stat(target); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("expected the target to keep mode 0600, got %v (%v)", info.Mode(), err)
+	}
+
+	backups, err := ListBackupsFor(path)
+	if err != nil || len(backups) == 0 {
+		t.Fatalf("expected a backup, got %v (%v)", backups, err)
+	}
+	if err := RestoreBackupTo(path, backups[0].Path); err != nil {
+		t.Fatalf("RestoreBackupTo failed: %v", err)
+	}
+	if info, err := os.Lstat(path); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("restore should keep the symlink, got %v (%v)", info.Mode(), err)
+	}
+}
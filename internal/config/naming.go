@@ -0,0 +1,114 @@
+package config
+
+import (
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// maxNameWords is how many words of a command SuggestName uses.
+const maxNameWords = 4
+
+// nameWord matches the words of a command that describe what it does:
+// subcommands and flags without values, as in "docker ps -a".
+var nameWord = regexp.MustCompile(`^-{0,2}[A-Za-z][A-Za-z0-9_-]*$`)
+
+// defaultGroup is suggested for a command with no program to name a group after.
+const defaultGroup = "misc"
+
+// envAssignment matches a leading "NAME=value" environment assignment.
+var envAssignment = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*=`)
+
+// wrappers are commands that run the command after them, which says more
+// about what a bookmark does than the wrapper itself.
+var wrappers = []string{"sudo", "env", "time", "nohup", "exec", "command", "builtin"}
+
+// commandWords returns the words of the first line of command, from its
+// program onwards.
+func commandWords(command string) []string {
+	line, _, _ := strings.Cut(command, "\n")
+	words := strings.Fields(line)
+	for len(words) > 0 && (envAssignment.MatchString(words[0]) || slices.Contains(wrappers, words[0])) {
+		words = words[1:]
+	}
+	return words
+}
+
+// Program returns the name of the program command runs, such as "docker" for
+// "sudo /usr/bin/docker ps", or "" if there is none.
+func Program(command string) string {
+	words := commandWords(command)
+	if len(words) == 0 {
+		return ""
+	}
+	return filepath.Base(words[0])
+}
+
+// SuggestName proposes a name for command in the group at path, built from
+// its program, subcommands and flags, as in "docker-ps-a" for "docker ps -a".
+// The name is numbered if the group already has a command by that name.
+func (c *Config) SuggestName(path, command string) string {
+	words := commandWords(command)
+	var parts []string
+	for i, word := range words {
+		if i == 0 {
+			word = filepath.Base(word)
+			word = strings.TrimSuffix(word, filepath.Ext(word)) // deploy.sh -> deploy
+		} else if !nameWord.MatchString(word) {
+			break
+		}
+		if part := strings.ToLower(strings.TrimLeft(word, "-")); part != "" {
+			parts = append(parts, part)
+		}
+		if len(parts) == maxNameWords {
+			break
+		}
+	}
+	name := strings.Join(parts, "-")
+	if name == "" {
+		name = "command"
+	}
+
+	if group := c.GetGroup(path); group != nil {
+		return uniqueCommandName(group, name)
+	}
+	return name
+}
+
+// SuggestGroup proposes a group for command: the group named after its
+// program, else the group holding the most commands that run the same
+// program. With no such group it returns the program's name without its
+// extension, for a new group, or defaultGroup if there is no program.
+func (c *Config) SuggestGroup(command string) string {
+	program := Program(command)
+	name := strings.TrimSuffix(program, filepath.Ext(program)) // deploy.sh -> deploy
+	if name == "" {
+		return defaultGroup
+	}
+
+	best, bestCount := "", 0
+	named := ""
+	walkGroups(c.Groups, "", func(path string, g *Group) {
+		if named == "" && strings.EqualFold(g.Name, name) {
+			named = path
+		}
+		count := 0
+		for _, cmd := range g.Commands {
+			if Program(cmd.Command) == program {
+				count++
+			}
+		}
+		if count > bestCount {
+			best, bestCount = path, count
+		}
+	})
+
+	switch {
+	case named != "":
+		return named
+	case best != "":
+		return best
+	}
+	return name
+}
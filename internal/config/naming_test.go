@@ -0,0 +1,53 @@
+package config
+
+import "testing"
+
+func TestSuggestName(t *testing.T) {
+	cfg := nestedConfig()
+	tests := []struct {
+		group, command, want string
+	}{
+		{"", "docker ps -a", "docker-ps-a"},
+		{"", "sudo /usr/bin/docker ps -a", "docker-ps-a"},
+		{"", "AWS_PROFILE=prod aws s3 ls s3://bucket", "aws-s3-ls"},
+		{"", "kubectl get pods -n kube-system -o wide", "kubectl-get-pods-n"},
+		{"", "git log -n {{n:10}}", "git-log-n"},
+		{"", "make build\n./bin/app", "make-build"},
+		{"", "./deploy.sh prod", "deploy-prod"},
+		{"", "   ", "command"},
+		{"git", "git status", "git-status"},
+	}
+	for _, tt := range tests {
+		if got := cfg.SuggestName(tt.group, tt.command); got != tt.want {
+			t.Errorf("SuggestName(%q, %q) = %q, want %q", tt.group, tt.command, got, tt.want)
+		}
+	}
+
+	cfg.Groups[1].Commands = append(cfg.Groups[1].Commands, Command{ID: 4, Name: "git-status"})
+	if got := cfg.SuggestName("git", "git status"); got != "git-status-2" {
+		t.Errorf("expected a name not yet taken in the group, got %q", got)
+	}
+}
+
+func TestSuggestGroup(t *testing.T) {
+	cfg := nestedConfig()
+	cfg.Groups = append(cfg.Groups, Group{Name: "tools", Commands: []Command{
+		{ID: 4, Name: "up", Command: "docker compose up -d"},
+	}})
+	tests := []struct {
+		command, want string
+	}{
+		{"git push --force-with-lease", "git"},
+		{"aws s3 ls", "cloud/aws"},
+		{"sudo docker ps -a", "tools"},
+		{"terraform plan", "terraform"},
+		{"./deploy.sh staging", "deploy"},
+		{"FOO=1", defaultGroup},
+		{"", defaultGroup},
+	}
+	for _, tt := range tests {
+		if got := cfg.SuggestGroup(tt.command); got != tt.want {
+			t.Errorf("SuggestGroup(%q) = %q, want %q", tt.command, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// TagCount is a tag and the number of commands carrying it.
+type TagCount struct {
+	Name  string `json:"name" yaml:"name"`
+	Count int    `json:"count" yaml:"count"`
+}
+
+// ParseTags splits a comma or space separated list into tags, dropping
+// duplicates, empty entries and a leading '#'.
+func ParseTags(s string) []string {
+	var tags []string
+	for _, field := range strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	}) {
+		tag := strings.TrimPrefix(field, "#")
+		if tag != "" && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// validateTags rejects empty, duplicate or whitespace-containing tags.
+func validateTags(tags []string) error {
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		if tag == "" {
+			return fmt.Errorf("tags cannot be empty")
+		}
+		if strings.ContainsAny(tag, " \t\n,#") {
+			return fmt.Errorf("tag %q cannot contain spaces, commas or '#'", tag)
+		}
+		if seen[tag] {
+			return fmt.Errorf("duplicate tag %q", tag)
+		}
+		seen[tag] = true
+	}
+	return nil
+}
+
+// HasTag reports whether the command carries tag.
+func (f FlatCommand) HasTag(tag string) bool {
+	return slices.Contains(f.Tags, tag)
+}
+
+// Tags returns every tag in use with its command count, sorted by name.
+func (c *Config) Tags() []TagCount {
+	counts := make(map[string]int)
+	for _, cmd := range c.FlatCommands() {
+		for _, tag := range cmd.Tags {
+			counts[tag]++
+		}
+	}
+
+	result := make([]TagCount, 0, len(counts))
+	for name, count := range counts {
+		result = append(result, TagCount{Name: name, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// CommandsWithTag returns all commands carrying tag.
+func (c *Config) CommandsWithTag(tag string) []FlatCommand {
+	var result []FlatCommand
+	for _, cmd := range c.FlatCommands() {
+		if cmd.HasTag(tag) {
+			result = append(result, cmd)
+		}
+	}
+	return result
+}
+
+// SetCommandTags replaces the tags of the command with the given ID
+func (c *Config) SetCommandTags(id int, tags []string) error {
+	if err := validateTags(tags); err != nil {
+		return err
+	}
+	cmd, _ := c.GetCommandByID(id)
+	if cmd == nil {
+		return fmt.Errorf("command with ID %d not found", id)
+	}
+	if cmd.ReadOnly() {
+		return readOnlyError(*cmd)
+	}
+	cmd.Tags = tags
+	return nil
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTags(t *testing.T) {
+	tests := map[string][]string{
+		"docker, debugging": {"docker", "debugging"},
+		"#k8s  #prod,k8s":   {"k8s", "prod"},
+		"":                  nil,
+		" , ,":              nil,
+		"single":            {"single"},
+	}
+	for in, want := range tests {
+		if got := ParseTags(in); !reflect.DeepEqual(got, want) {
+			t.Errorf("ParseTags(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestTagsAndCommandsWithTag(t *testing.T) {
+	cfg := &Config{
+		Groups: []Group{
+			{Name: "docker", Commands: []Command{
+				{ID: 1, Name: "logs", Command: "docker compose logs", Tags: []string{"docker", "debugging"}},
+				{ID: 2, Name: "ps", Command: "docker ps", Tags: []string{"docker"}},
+			}},
+			{Name: "k8s", Commands: []Command{
+				{ID: 3, Name: "events", Command: "kubectl get events", Tags: []string{"debugging"}},
+			}},
+		},
+	}
+
+	want := []TagCount{{Name: "debugging", Count: 2}, {Name: "docker", Count: 2}}
+	if got := cfg.Tags(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Tags() = %+v, want %+v", got, want)
+	}
+
+	cmds := cfg.CommandsWithTag("debugging")
+	if len(cmds) != 2 || cmds[0].ID != 1 || cmds[1].ID != 3 {
+		t.Errorf("unexpected commands with tag: %+v", cmds)
+	}
+	if cmds[1].GroupName != "k8s" {
+		t.Errorf("expected group name on tagged command, got %q", cmds[1].GroupName)
+	}
+
+	if err := cfg.SetCommandTags(2, []string{"containers"}); err != nil {
+		t.Fatalf("SetCommandTags failed: %v", err)
+	}
+	if len(cfg.CommandsWithTag("containers")) != 1 {
+		t.Error("expected tag to be set")
+	}
+	if err := cfg.SetCommandTags(2, []string{"bad tag"}); err == nil {
+		t.Error("expected error for tag with space")
+	}
+	if err := cfg.SetCommandTags(99, nil); err == nil {
+		t.Error("expected error for missing command")
+	}
+}
+
+func TestConfigValidation_InvalidTags(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "config.yaml")
+
+	content := `
+groups:
+  - name: test
+    commands:
+      - name: cmd1
+        command: echo hi
+        tags: [ok, ok]
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+
+	_, err := LoadFrom(path)
+	if err == nil {
+		t.Fatal("expected error for duplicate tags, got nil")
+	}
+	if !strings.Contains(err.Error(), "duplicate tag") {
+		t.Errorf("expected 'duplicate tag' in error, got: %v", err)
+	}
+}
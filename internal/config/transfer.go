@@ -0,0 +1,174 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+)
+
+// ImportMode controls how imported commands that clash with existing ones are handled.
+type ImportMode int
+
+const (
+	// ImportMerge keeps existing commands and renames clashing imports.
+	ImportMerge ImportMode = iota
+	// ImportReplace overwrites existing commands that share a name, keeping their IDs.
+	ImportReplace
+)
+
+// ImportOptions configures Import.
+type ImportOptions struct {
+	Mode ImportMode
+	// Into places every imported command in this group instead of its own.
+	Into string
+}
+
+// ImportItem describes what happened to one imported command.
+type ImportItem struct {
+	Group   string
+	Name    string
+	NewName string // set when the command was renamed
+	ID      int    // ID assigned in the destination config
+	// Included is set when the command was renamed because a read-only
+	// command from an included file has its name.
+	Included bool
+}
+
+// ImportReport summarises the outcome of Import.
+type ImportReport struct {
+	Added    []ImportItem
+	Renamed  []ImportItem
+	Replaced []ImportItem
+	Skipped  []ImportItem
+}
+
+// Export returns a copy of the config containing only the groups at the given
+// paths, with their subgroups, or all groups if none are given. Parents of an
+// exported subgroup are kept so it stays at the same path, but without their
+// own commands. Settings such as editor are not exported, and neither are
+// commands from included files, which belong to those files.
+func (c *Config) Export(groupNames []string) (*Config, error) {
+	for _, name := range groupNames {
+		if c.GetGroup(name) == nil {
+			return nil, fmt.Errorf("group %q not found", name)
+		}
+	}
+	c = c.writable()
+	for _, name := range groupNames {
+		if c.GetGroup(name) == nil {
+			return nil, fmt.Errorf("group %q only has included commands", name)
+		}
+	}
+
+	out := &Config{Groups: []Group{}}
+	if len(groupNames) == 0 {
+		for _, g := range c.Groups {
+			out.Groups = append(out.Groups, cloneGroup(g))
+		}
+		return out, nil
+	}
+
+	for _, path := range c.GroupPaths() {
+		if !slices.ContainsFunc(groupNames, func(name string) bool { return JoinPath(name) == path }) {
+			continue
+		}
+		// Already exported along with a selected parent
+		if slices.ContainsFunc(groupNames, func(name string) bool { return JoinPath(name) != path && IsWithin(path, name) }) {
+			continue
+		}
+		exported := cloneGroup(*c.GetGroup(path))
+		if parent := ParentPath(path); parent != "" {
+			p := out.EnsureGroup(parent)
+			p.Groups = append(p.Groups, exported)
+		} else {
+			out.Groups = append(out.Groups, exported)
+		}
+	}
+	return out, nil
+}
+
+// Import adds the groups and commands from src, including subgroups, at the
+// same paths. Imported commands always get fresh IDs from NextID so they never
+// collide with existing ones. Commands already present in the destination
+// group are skipped; name clashes are renamed or replaced according to
+// opts.Mode. Read-only commands from included files are never replaced: the
+// import is renamed instead.
+func (c *Config) Import(src *Config, opts ImportOptions) ImportReport {
+	var report ImportReport
+	// Workflow steps refer to commands by ID, so track where each one went
+	newIDs := make(map[int]int)
+	imported := make(map[int]bool)
+
+	walkGroups(src.Groups, "", func(path string, sg *Group) {
+		groupName := path
+		if opts.Into != "" {
+			groupName = opts.Into
+		}
+		group := c.EnsureGroup(groupName)
+
+		for _, cmd := range sg.Commands {
+			item := ImportItem{Group: groupName, Name: cmd.Name}
+			srcID := cmd.ID
+
+			// Already present, possibly under another name
+			if same := slices.IndexFunc(group.Commands, func(existing Command) bool {
+				return existing.Command == cmd.Command && slices.Equal(existing.Steps, cmd.Steps)
+			}); same != -1 {
+				item.ID = group.Commands[same].ID
+				newIDs[srcID] = item.ID
+				report.Skipped = append(report.Skipped, item)
+				continue
+			}
+
+			idx := slices.IndexFunc(group.Commands, func(existing Command) bool {
+				return existing.Name == cmd.Name
+			})
+			if idx == -1 {
+				cmd.ID = c.newID()
+				group.Commands = append(group.Commands, cmd)
+				item.ID = cmd.ID
+				newIDs[srcID], imported[cmd.ID] = cmd.ID, true
+				report.Added = append(report.Added, item)
+				continue
+			}
+
+			if opts.Mode == ImportReplace && !group.Commands[idx].ReadOnly() {
+				cmd.ID = group.Commands[idx].ID
+				group.Commands[idx] = cmd
+				item.ID = cmd.ID
+				newIDs[srcID], imported[cmd.ID] = cmd.ID, true
+				report.Replaced = append(report.Replaced, item)
+				continue
+			}
+
+			cmd.Name = uniqueCommandName(group, cmd.Name)
+			cmd.ID = c.newID()
+			group.Commands = append(group.Commands, cmd)
+			item.NewName = cmd.Name
+			item.Included = group.Commands[idx].ReadOnly()
+			item.ID = cmd.ID
+			newIDs[srcID], imported[cmd.ID] = cmd.ID, true
+			report.Renamed = append(report.Renamed, item)
+		}
+	})
+
+	c.remapRefs(imported, newIDs)
+	return report
+}
+
+// uniqueCommandName returns name, or name-2, name-3... if it is taken in group.
+func uniqueCommandName(group *Group, name string) string {
+	taken := func(candidate string) bool {
+		return slices.ContainsFunc(group.Commands, func(cmd Command) bool {
+			return cmd.Name == candidate
+		})
+	}
+	if !taken(name) {
+		return name
+	}
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s-%d", name, i)
+		if !taken(candidate) {
+			return candidate
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	cfg := &Config{
+		Editor: "code",
+		NextID: 4,
+		Groups: []Group{
+			{Name: "docker", Commands: []Command{{ID: 1, Name: "ps", Command: "docker ps"}}},
+			{Name: "git", Commands: []Command{{ID: 2, Name: "st", Command: "git status"}}},
+			{Name: "empty"},
+		},
+	}
+
+	out, err := cfg.Export([]string{"git", "empty"})
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if out.Editor != "" || out.NextID != 0 {
+		t.Errorf("export should not include settings, got editor=%q next_id=%d", out.Editor, out.NextID)
+	}
+	if len(out.Groups) != 2 || out.Groups[0].Name != "git" || out.Groups[1].Name != "empty" {
+		t.Fatalf("unexpected exported groups: %+v", out.Groups)
+	}
+
+	// Exported commands must not alias the source
+	out.Groups[0].Commands[0].Name = "changed"
+	if cfg.Groups[1].Commands[0].Name != "st" {
+		t.Error("modifying export should not modify source config")
+	}
+
+	if _, err := cfg.Export([]string{"missing"}); err == nil {
+		t.Error("expected error exporting missing group")
+	}
+}
+
+func TestImportMerge(t *testing.T) {
+	cfg := &Config{
+		NextID: 3,
+		Groups: []Group{
+			{Name: "docker", Commands: []Command{
+				{ID: 1, Name: "ps", Command: "docker ps"},
+				{ID: 2, Name: "logs", Command: "docker logs -f"},
+			}},
+		},
+	}
+	src := &Config{
+		Groups: []Group{
+			{Name: "docker", Commands: []Command{
+				{ID: 1, Name: "ps", Command: "docker ps"},              // identical: skipped
+				{ID: 2, Name: "logs", Command: "docker logs --tail 5"}, // clash: renamed
+				{ID: 3, Name: "images", Command: "docker images"},      // new: added
+			}},
+			{Name: "git", Commands: []Command{
+				{ID: 1, Name: "st", Command: "git status"},
+			}},
+		},
+	}
+
+	report := cfg.Import(src, ImportOptions{Mode: ImportMerge})
+
+	if len(report.Added) != 2 || len(report.Renamed) != 1 || len(report.Skipped) != 1 || len(report.Replaced) != 0 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if report.Renamed[0].NewName != "logs-2" {
+		t.Errorf("expected rename to logs-2, got %q", report.Renamed[0].NewName)
+	}
+
+	seen := make(map[int]bool)
+	for _, cmd := range cfg.AllCommands() {
+		if seen[cmd.ID] {
+			t.Errorf("duplicate ID %d after import", cmd.ID)
+		}
+		seen[cmd.ID] = true
+	}
+	if cfg.NextID != 6 {
+		t.Errorf("expected NextID 6, got %d", cfg.NextID)
+	}
+	if cfg.GetGroup("git") == nil {
+		t.Error("expected git group to be created")
+	}
+
+	// Importing again should skip everything
+	again := cfg.Import(src, ImportOptions{Mode: ImportMerge})
+	if len(again.Skipped) != 4 || len(again.Added)+len(again.Renamed) != 0 {
+		t.Errorf("expected re-import to skip all, got %+v", again)
+	}
+}
+
+func TestImportReplaceInto(t *testing.T) {
+	cfg := &Config{
+		NextID: 2,
+		Groups: []Group{
+			{Name: "team", Commands: []Command{{ID: 1, Name: "deploy", Command: "make deploy"}}},
+		},
+	}
+	src := &Config{
+		Groups: []Group{
+			{Name: "other", Commands: []Command{
+				{ID: 7, Name: "deploy", Command: "make deploy ENV=prod", Description: "Deploy to prod"},
+			}},
+		},
+	}
+
+	report := cfg.Import(src, ImportOptions{Mode: ImportReplace, Into: "team"})
+
+	if len(report.Replaced) != 1 {
+		t.Fatalf("expected 1 replaced, got %+v", report)
+	}
+	cmd, err := cfg.GetCommand("team", "deploy")
+	if err != nil {
+		t.Fatalf("GetCommand failed: %v", err)
+	}
+	if cmd.ID != 1 || cmd.Command != "make deploy ENV=prod" || cmd.Description != "Deploy to prod" {
+		t.Errorf("unexpected replaced command: %+v", cmd)
+	}
+	if cfg.GetGroup("other") != nil {
+		t.Error("--into should not create the source group")
+	}
+}
+
+func TestExportLeavesOutIncluded(t *testing.T) {
+	cfg := &Config{
+		Groups: []Group{
+			{Name: "git", Commands: []Command{
+				{ID: 1, Name: "st", Command: "git status"},
+				{ID: 10001, Name: "prune", Command: "git remote prune origin", Source: "shared.yaml"},
+			}},
+			{Name: "team", Source: "shared.yaml", Commands: []Command{
+				{ID: 10002, Name: "deploy", Command: "make deploy", Source: "shared.yaml"},
+			}},
+		},
+	}
+
+	out, err := cfg.Export(nil)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if len(out.Groups) != 1 || len(out.Groups[0].Commands) != 1 || out.Groups[0].Commands[0].Name != "st" {
+		t.Fatalf("expected only the personal command, got %+v", out.Groups)
+	}
+
+	if _, err := cfg.Export([]string{"team"}); err == nil {
+		t.Error("expected error exporting a group that only has included commands")
+	}
+}
+
+func TestImportReplaceKeepsIncluded(t *testing.T) {
+	cfg := &Config{
+		NextID: 2,
+		Groups: []Group{
+			{Name: "team", Commands: []Command{
+				{ID: 10001, Name: "deploy", Command: "make deploy", Source: "shared.yaml"},
+			}},
+		},
+	}
+	src := &Config{
+		Groups: []Group{
+			{Name: "team", Commands: []Command{{ID: 7, Name: "deploy", Command: "make deploy ENV=prod"}}},
+		},
+	}
+
+	report := cfg.Import(src, ImportOptions{Mode: ImportReplace})
+
+	if len(report.Replaced) != 0 || len(report.Renamed) != 1 || !report.Renamed[0].Included {
+		t.Fatalf("expected the import to be renamed around the included command, got %+v", report)
+	}
+	included, err := cfg.GetCommand("team", "deploy")
+	if err != nil {
+		t.Fatalf("GetCommand failed: %v", err)
+	}
+	if included.ID != 10001 || included.Source != "shared.yaml" || included.Command != "make deploy" {
+		t.Errorf("included command should be untouched, got %+v", included)
+	}
+	renamed, err := cfg.GetCommand("team", "deploy-2")
+	if err != nil {
+		t.Fatalf("GetCommand failed: %v", err)
+	}
+	if renamed.ID != 2 || renamed.ReadOnly() || renamed.Command != "make deploy ENV=prod" {
+		t.Errorf("unexpected renamed command: %+v", renamed)
+	}
+}
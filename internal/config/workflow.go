@@ -0,0 +1,163 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FailurePolicy is what a workflow does when a step fails.
+type FailurePolicy string
+
+const (
+	FailStop     FailurePolicy = "stop"     // skip the remaining steps (the default)
+	FailContinue FailurePolicy = "continue" // run the remaining steps anyway
+)
+
+// Step is one step of a workflow: either an inline command or a reference to
+// another bookmark by ID.
+type Step struct {
+	Name    string `json:"name,omitempty" yaml:"name,omitempty"`
+	Command string `json:"command,omitempty" yaml:"command,omitempty"`
+	Ref     int    `json:"ref,omitempty" yaml:"ref,omitempty"`
+	// Confirm asks before running the step.
+	Confirm bool `json:"confirm,omitempty" yaml:"confirm,omitempty"`
+	// OnFailure overrides the workflow's policy for this step.
+	OnFailure FailurePolicy `json:"on_failure,omitempty" yaml:"on_failure,omitempty"`
+}
+
+// Label names the step for progress output: its name, or else the first line
+// of its command.
+func (s Step) Label() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	line, _, _ := strings.Cut(s.Command, "\n")
+	return line
+}
+
+// IsWorkflow reports whether the command is a workflow of steps rather than
+// a single command.
+func (cmd Command) IsWorkflow() bool {
+	return len(cmd.Steps) > 0
+}
+
+// IsWorkflow reports whether the command is a workflow of steps rather than
+// a single command.
+func (f FlatCommand) IsWorkflow() bool {
+	return len(f.Steps) > 0
+}
+
+// Summary describes the command for listings: the command itself, or for a
+// workflow the labels of its steps, as in "workflow: build → push → roll".
+func (cmd Command) Summary() string {
+	return summarize(cmd.Command, cmd.Steps)
+}
+
+// Summary describes the command for listings, like Command.Summary.
+func (f FlatCommand) Summary() string {
+	return summarize(f.Command, f.Steps)
+}
+
+func summarize(command string, steps []Step) string {
+	if len(steps) == 0 {
+		return command
+	}
+	labels := make([]string, len(steps))
+	for i, step := range steps {
+		labels[i] = step.Label()
+		if labels[i] == "" {
+			labels[i] = fmt.Sprintf("[%d]", step.Ref)
+		}
+	}
+	return "workflow: " + strings.Join(labels, " → ")
+}
+
+// validateWorkflow checks a workflow has no command of its own, and that each
+// step has exactly one of an inline command or a reference.
+func validateWorkflow(cmd Command) error {
+	if !cmd.IsWorkflow() {
+		if cmd.OnFailure != "" {
+			return fmt.Errorf("on_failure is only valid for workflows with steps")
+		}
+		return nil
+	}
+	if cmd.Command != "" {
+		return fmt.Errorf("cannot have both a command and steps")
+	}
+	if err := validatePolicy(cmd.OnFailure); err != nil {
+		return err
+	}
+	for i, step := range cmd.Steps {
+		switch {
+		case step.Command == "" && step.Ref == 0:
+			return fmt.Errorf("step %d needs a command or a ref", i+1)
+		case step.Command != "" && step.Ref != 0:
+			return fmt.Errorf("step %d cannot have both a command and a ref", i+1)
+		case step.Ref != 0 && step.Ref == cmd.ID:
+			return fmt.Errorf("step %d refers to the workflow itself", i+1)
+		}
+		if err := validatePolicy(step.OnFailure); err != nil {
+			return fmt.Errorf("step %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func validatePolicy(p FailurePolicy) error {
+	switch p {
+	case "", FailStop, FailContinue:
+		return nil
+	}
+	return fmt.Errorf("invalid on_failure %q (valid: stop, continue)", p)
+}
+
+// ResolveSteps returns the steps of workflow ready to run: references are
+// replaced by the command they refer to, named after it unless the step has
+// a name of its own, and every step carries its effective failure policy.
+// Steps may not refer to other workflows.
+func (c *Config) ResolveSteps(workflow FlatCommand) ([]Step, error) {
+	policy := workflow.OnFailure
+	if policy == "" {
+		policy = FailStop
+	}
+	steps := make([]Step, len(workflow.Steps))
+	for i, step := range workflow.Steps {
+		if step.Ref != 0 {
+			ref, _ := c.GetCommandByID(step.Ref)
+			switch {
+			case ref == nil:
+				return nil, fmt.Errorf("step %d refers to command %d, which does not exist", i+1, step.Ref)
+			case ref.IsWorkflow():
+				return nil, fmt.Errorf("step %d refers to workflow %q; workflows cannot be nested", i+1, ref.Name)
+			}
+			step.Command = ref.Command
+			if step.Name == "" {
+				step.Name = ref.Name
+			}
+		}
+		if step.OnFailure == "" {
+			step.OnFailure = policy
+		}
+		steps[i] = step
+	}
+	return steps, nil
+}
+
+// remapRefs points the steps of every workflow among ids at the new IDs of
+// the commands they referred to, after an import renumbered them.
+func (c *Config) remapRefs(ids map[int]bool, newIDs map[int]int) {
+	walkGroups(c.Groups, "", func(_ string, g *Group) {
+		for i := range g.Commands {
+			cmd := &g.Commands[i]
+			if !ids[cmd.ID] || !cmd.IsWorkflow() {
+				continue
+			}
+			cmd.Steps = append([]Step(nil), cmd.Steps...)
+			for j, step := range cmd.Steps {
+				if id, ok := newIDs[step.Ref]; ok {
+					cmd.Steps[j].Ref = id
+				}
+			}
+		}
+	})
+}
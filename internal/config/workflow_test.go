@@ -0,0 +1,143 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const workflowConfig = `
+groups:
+  - name: deploy
+    commands:
+      - id: 1
+        name: build
+        command: docker build -t app:{{tag}} .
+      - id: 2
+        name: release
+        on_failure: continue
+        steps:
+          - ref: 1
+          - name: push
+            command: docker push app:{{tag}}
+            on_failure: stop
+          - name: roll
+            command: kubectl rollout restart deploy/app
+            confirm: true
+`
+
+func TestWorkflowParseAndResolve(t *testing.T) {
+	cfg, err := Parse([]byte(workflowConfig), "config.yaml")
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	cmd, group := cfg.GetCommandByID(2)
+	if cmd == nil || !cmd.IsWorkflow() {
+		t.Fatalf("expected command 2 to be a workflow, got %+v", cmd)
+	}
+	flat := cmd.Flatten(group)
+	if got := flat.Summary(); got != "workflow: [1] → push → roll" {
+		t.Errorf("Summary() = %q", got)
+	}
+
+	steps, err := cfg.ResolveSteps(flat)
+	if err != nil {
+		t.Fatalf("ResolveSteps() error: %v", err)
+	}
+	want := []Step{
+		{Name: "build", Command: "docker build -t app:{{tag}} .", Ref: 1, OnFailure: FailContinue},
+		{Name: "push", Command: "docker push app:{{tag}}", OnFailure: FailStop},
+		{Name: "roll", Command: "kubectl rollout restart deploy/app", Confirm: true, OnFailure: FailContinue},
+	}
+	if !reflect.DeepEqual(steps, want) {
+		t.Errorf("ResolveSteps() =\n%+v\nwant\n%+v", steps, want)
+	}
+}
+
+func TestWorkflowValidation(t *testing.T) {
+	tests := []struct {
+		name, commands, wantErr string
+	}{
+		{"command and steps", `
+      - name: w
+        command: echo
+        steps: [{command: echo}]`, "both a command and steps"},
+		{"empty step", `
+      - name: w
+        steps: [{name: nothing}]`, "step 1 needs a command or a ref"},
+		{"command and ref", `
+      - name: w
+        steps: [{command: echo, ref: 3}]`, "step 1 cannot have both"},
+		{"bad policy", `
+      - name: w
+        on_failure: retry
+        steps: [{command: echo}]`, `invalid on_failure "retry"`},
+		{"policy without steps", `
+      - name: w
+        command: echo
+        on_failure: continue`, "only valid for workflows"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte("groups:\n  - name: g\n    commands:"+tt.commands+"\n"), "config.yaml")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestWorkflowWithoutIDs(t *testing.T) {
+	cfg, err := Parse([]byte(`
+groups:
+  - name: g
+    commands:
+      - name: w
+        steps: [{command: echo one}, {command: echo two}]
+`), "config.yaml")
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if cmd, err := cfg.GetCommand("g", "w"); err != nil || len(cmd.Steps) != 2 {
+		t.Errorf("expected workflow w with two steps, got %+v", cmd)
+	}
+}
+
+func TestResolveStepsRejectsBadRefs(t *testing.T) {
+	cfg, err := Parse([]byte(workflowConfig), "config.yaml")
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	missing := FlatCommand{Name: "w", Steps: []Step{{Ref: 99}}}
+	if _, err := cfg.ResolveSteps(missing); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("expected an error for a missing ref, got %v", err)
+	}
+	nested := FlatCommand{Name: "w", Steps: []Step{{Ref: 2}}}
+	if _, err := cfg.ResolveSteps(nested); err == nil || !strings.Contains(err.Error(), "cannot be nested") {
+		t.Errorf("expected an error for a nested workflow, got %v", err)
+	}
+}
+
+func TestImportRemapsWorkflowRefs(t *testing.T) {
+	src, err := Parse([]byte(workflowConfig), "config.yaml")
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	dst := &Config{Groups: []Group{{Name: "other", Commands: []Command{{ID: 1, Name: "x", Command: "true"}}}}, NextID: 2}
+
+	dst.Import(src, ImportOptions{})
+
+	release, err := dst.GetCommand("deploy", "release")
+	if err != nil {
+		t.Fatalf("workflow not imported: %v", err)
+	}
+	build, _ := dst.GetCommand("deploy", "build")
+	if release.Steps[0].Ref != build.ID || build.ID == 1 {
+		t.Errorf("expected the step to refer to the imported build (%d), got ref %d", build.ID, release.Steps[0].Ref)
+	}
+	if src.Groups[0].Commands[1].Steps[0].Ref != 1 {
+		t.Error("import changed the source workflow's steps")
+	}
+}
@@ -0,0 +1,65 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// atuinReader reads the SQLite database of atuin, which replaces the shell's
+// own history and records the directory, exit status, duration and host of
+// every command.
+type atuinReader struct{}
+
+func (atuinReader) Name() string { return "atuin" }
+
+func (atuinReader) Paths(home string) []string {
+	if path := os.Getenv("ATUIN_DB_PATH"); path != "" {
+		return []string{path}
+	}
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	return []string{filepath.Join(dataHome, "atuin", "history.db")}
+}
+
+func (atuinReader) Detailed(string) bool { return true }
+
+func (atuinReader) Read(path string) ([]Entry, error) {
+	db, err := openSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	// Times are in nanoseconds; atuin records -1 where the exit status or
+	// duration is unknown.
+	rows, err := db.Query(`SELECT command, timestamp, duration, exit, cwd, hostname
+		FROM history WHERE deleted_at IS NULL ORDER BY timestamp`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		var cmd, cwd, host string
+		var started, duration int64
+		var exit int
+		if err := rows.Scan(&cmd, &started, &duration, &exit, &cwd, &host); err != nil {
+			return nil, err
+		}
+		host, _, _ = strings.Cut(host, ":") // stored as host:user
+		entries = append(entries, Entry{
+			Command:   cmd,
+			Timestamp: time.Unix(0, started),
+			Dir:       cwd,
+			Exit:      max(exit, 0),
+			Duration:  time.Duration(max(duration, 0)),
+			Host:      host,
+		})
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,213 @@
+package history
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// plainWord matches the program name and subcommands at the start of a
+// command, such as "docker compose up", which clustering never varies.
+var plainWord = regexp.MustCompile(`^[A-Za-z][A-Za-z_-]*$`)
+
+// flagName matches the name of a flag usable as a placeholder name.
+var flagName = regexp.MustCompile(`^--?([A-Za-z_][A-Za-z0-9_-]*)$`)
+
+// splitArgs splits cmd into words on unquoted whitespace, keeping quotes and
+// escapes in the words as written.
+func splitArgs(cmd string) []string {
+	var words []string
+	var word strings.Builder
+	var inQuote rune
+	escaped := false
+
+	for _, r := range cmd {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\' && inQuote != '\'':
+			escaped = true
+		case inQuote != 0:
+			if r == inQuote {
+				inQuote = 0
+			}
+		case r == '"' || r == '\'':
+			inQuote = r
+		case r == ' ' || r == '\t':
+			if word.Len() > 0 {
+				words = append(words, word.String())
+				word.Reset()
+			}
+			continue
+		}
+		word.WriteRune(r)
+	}
+	if word.Len() > 0 {
+		words = append(words, word.String())
+	}
+	return words
+}
+
+// slot marks the words of a command that may hold a literal value, and so may
+// differ between commands in the same cluster.
+type slot struct {
+	index  int
+	prefix string // fixed part of the word, e.g. "--name=" in "--name=web"
+}
+
+// shapeOf returns the clustering key of a command split into words, with the
+// words that may hold values replaced by a marker, and the slots they fill.
+// Flags, the program and any subcommands that follow it stay literal.
+func shapeOf(words []string) (string, []slot) {
+	shape := make([]string, len(words))
+	var slots []slot
+	leading := true
+
+	for i, word := range words {
+		switch {
+		case i == 0 || leading && plainWord.MatchString(word):
+			shape[i] = word
+			continue
+		case strings.HasPrefix(word, "-"):
+			leading = false
+			name, _, hasValue := strings.Cut(word, "=")
+			if !hasValue {
+				shape[i] = word
+				continue
+			}
+			slots = append(slots, slot{index: i, prefix: name + "="})
+		default:
+			leading = false
+			slots = append(slots, slot{index: i})
+		}
+		shape[i] = slots[len(slots)-1].prefix + "\x00"
+	}
+	return strings.Join(shape, "\x1f"), slots
+}
+
+// cluster merges commands with the same shape, such as "git log -n 5" and
+// "git log -n 10", into one suggestion. The most used variant stands for the
+// cluster, with a template whose placeholders cover the words that differ.
+func cluster(counts map[string]int) []FrequentCommand {
+	type group struct {
+		words [][]string
+		cmds  []string
+		slots []slot
+		total int
+	}
+	groups := make(map[string]*group)
+	var order []string
+
+	for cmd, count := range counts {
+		words := splitArgs(cmd)
+		key, slots := shapeOf(words)
+		if strings.Contains(cmd, "\n") {
+			key = cmd // multi-line commands are too varied to cluster
+		}
+		g, ok := groups[key]
+		if !ok {
+			g = &group{slots: slots}
+			groups[key] = g
+			order = append(order, key)
+		}
+		g.words = append(g.words, words)
+		g.cmds = append(g.cmds, cmd)
+		g.total += count
+	}
+
+	result := make([]FrequentCommand, 0, len(groups))
+	for _, key := range order {
+		g := groups[key]
+		// Most used variant first
+		idx := make([]int, len(g.cmds))
+		for i := range idx {
+			idx[i] = i
+		}
+		sort.Slice(idx, func(a, b int) bool {
+			ca, cb := counts[g.cmds[idx[a]]], counts[g.cmds[idx[b]]]
+			if ca != cb {
+				return ca > cb
+			}
+			return g.cmds[idx[a]] < g.cmds[idx[b]]
+		})
+
+		best := idx[0]
+		fc := FrequentCommand{Command: g.cmds[best], Count: g.total}
+		if len(g.cmds) > 1 {
+			fc.Variants = len(g.cmds)
+			fc.Template = template(g.words, best, g.slots)
+		}
+		result = append(result, fc)
+	}
+	return result
+}
+
+// template builds a command from the words of the variants in a cluster,
+// turning each slot whose value differs between them into a placeholder that
+// defaults to the value in variant best.
+func template(variants [][]string, best int, slots []slot) string {
+	words := append([]string(nil), variants[best]...)
+	var varying []slot
+	for _, s := range slots {
+		for _, v := range variants {
+			if v[s.index] != words[s.index] {
+				varying = append(varying, s)
+				break
+			}
+		}
+	}
+
+	names := placeholderNames(words, varying)
+	for i, s := range varying {
+		value := unquote(strings.TrimPrefix(words[s.index], s.prefix))
+		placeholder := "{{" + names[i] + "}}"
+		if value != "" && !strings.ContainsAny(value, "{}") {
+			placeholder = "{{" + names[i] + ":" + value + "}}"
+		}
+		words[s.index] = s.prefix + placeholder
+	}
+	return strings.Join(words, " ")
+}
+
+// placeholderNames names each slot after the flag it belongs to, as in
+// "-n {{n}}" or "--name={{name}}", or else "arg", numbered if there are several.
+func placeholderNames(words []string, slots []slot) []string {
+	names := make([]string, len(slots))
+	var unnamed []int
+	for i, s := range slots {
+		flag := strings.TrimSuffix(s.prefix, "=")
+		if flag == "" && s.index > 0 {
+			flag = words[s.index-1]
+		}
+		if m := flagName.FindStringSubmatch(flag); m != nil {
+			names[i] = m[1]
+		} else {
+			unnamed = append(unnamed, i)
+		}
+	}
+	for n, i := range unnamed {
+		names[i] = "arg"
+		if len(unnamed) > 1 {
+			names[i] = fmt.Sprintf("arg%d", n+1)
+		}
+	}
+
+	// A flag given twice would otherwise name two placeholders the same
+	seen := make(map[string]int)
+	for i, name := range names {
+		seen[name]++
+		if seen[name] > 1 {
+			names[i] = fmt.Sprintf("%s%d", name, seen[name])
+		}
+	}
+	return names
+}
+
+// unquote removes the quotes around a word quoted as a whole.
+func unquote(word string) string {
+	if len(word) >= 2 && (word[0] == '\'' || word[0] == '"') && word[len(word)-1] == word[0] {
+		return word[1 : len(word)-1]
+	}
+	return word
+}
@@ -0,0 +1,70 @@
+package history
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"git log -n 5", []string{"git", "log", "-n", "5"}},
+		{`git commit -m "fix the  bug"`, []string{"git", "commit", "-m", `"fix the  bug"`}},
+		{`echo 'it''s' a\ b`, []string{"echo", `'it''s'`, `a\ b`}},
+		{"  spaced   out  ", []string{"spaced", "out"}},
+	}
+	for _, tt := range tests {
+		if got := splitArgs(tt.input); !slices.Equal(got, tt.expected) {
+			t.Errorf("splitArgs(%q) = %q, want %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestCluster(t *testing.T) {
+	counts := map[string]int{
+		"git log --oneline -n 5":                  2,
+		"git log --oneline -n 10":                 3,
+		"git log --oneline -n 20":                 1,
+		"git checkout main":                       2,
+		"git checkout develop":                    2,
+		"kubectl logs -n prod api-7f9":            1,
+		"kubectl logs -n staging api-2b1":         1,
+		"docker run --name=web -p 8080:80 nginx":  1,
+		"docker run --name=db -p 5432:5432 nginx": 1,
+		`git commit -m "first"`:                   1,
+		`git commit -m "second try"`:              1,
+	}
+	got := make(map[string]FrequentCommand)
+	for _, fc := range cluster(counts) {
+		got[fc.Command] = fc
+	}
+
+	tests := []struct {
+		command  string
+		count    int
+		template string
+	}{
+		{"git log --oneline -n 10", 6, "git log --oneline -n {{n:10}}"},
+		// Subcommands and their plain-word arguments are never merged
+		{"git checkout main", 2, ""},
+		{"git checkout develop", 2, ""},
+		{"kubectl logs -n prod api-7f9", 2, "kubectl logs -n {{n:prod}} {{arg:api-7f9}}"},
+		{"docker run --name=db -p 5432:5432 nginx", 2, "docker run --name={{name:db}} -p {{p:5432:5432}} nginx"},
+		{`git commit -m "first"`, 2, "git commit -m {{m:first}}"},
+	}
+	for _, tt := range tests {
+		fc, ok := got[tt.command]
+		if !ok {
+			t.Errorf("no suggestion for %q in %+v", tt.command, got)
+			continue
+		}
+		if fc.Count != tt.count || fc.Template != tt.template {
+			t.Errorf("%q: got count %d template %q, want %d %q", tt.command, fc.Count, fc.Template, tt.count, tt.template)
+		}
+	}
+	if len(got) != len(tests) {
+		t.Errorf("expected %d suggestions, got %d: %+v", len(tests), len(got), got)
+	}
+}
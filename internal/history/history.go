@@ -1,9 +1,8 @@
 package history
 
 import (
-	"bufio"
+	"fmt"
 	"os"
-	"path/filepath"
 	"slices"
 	"sort"
 	"strconv"
@@ -11,18 +10,56 @@ import (
 	"time"
 )
 
-// FrequentCommand represents a command with its frequency count.
+// FrequentCommand represents a command with its frequency count. Commands
+// that differ only in literal values are counted together: Command is the
+// most used of them and Template replaces the values that vary with
+// {{placeholders}}.
 type FrequentCommand struct {
-	Command string
-	Count   int
+	Command  string `json:"command" yaml:"command"`
+	Count    int    `json:"count" yaml:"count"`
+	Template string `json:"template,omitempty" yaml:"template,omitempty"`
+	Variants int    `json:"variants,omitempty" yaml:"variants,omitempty"` // distinct commands counted, if more than one
 }
 
+// Entry is one command from the shell history.
 type Entry struct {
 	Command   string
 	Index     int
 	Timestamp time.Time
+
+	// Only detailed sources such as atuin record the fields below
+	Dir      string // working directory the command ran in
+	Exit     int    // exit status, 0 if unknown
+	Duration time.Duration
+	Host     string
+}
+
+// Failed reports whether the command is known to have exited unsuccessfully.
+func (e Entry) Failed() bool {
+	return e.Exit > 0
+}
+
+// Filter narrows the history to commands that succeeded or ran in a given
+// directory. It needs a detailed source, as plain history files record neither.
+type Filter struct {
+	Successful bool   // only commands that exited with status 0
+	Dir        string // only commands run in this directory, if set
+}
+
+// IsZero reports whether f keeps every entry.
+func (f Filter) IsZero() bool {
+	return !f.Successful && f.Dir == ""
 }
 
+// Match reports whether e passes the filter.
+func (f Filter) Match(e Entry) bool {
+	if f.Successful && e.Failed() {
+		return false
+	}
+	return f.Dir == "" || e.Dir == f.Dir
+}
+
+// GetHistoryPath returns the history file that would be read, as found by Locate.
 func GetHistoryPath() (string, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
@@ -31,77 +68,69 @@ func GetHistoryPath() (string, error) {
 	return GetHistoryPathWithHome(home)
 }
 
+// GetHistoryPathWithHome is GetHistoryPath for the given home directory.
 func GetHistoryPathWithHome(home string) (string, error) {
-	// Check HISTFILE environment variable first (user's explicit preference)
-	if histFile := os.Getenv("HISTFILE"); histFile != "" {
-		if _, err := os.Stat(histFile); err == nil {
-			return histFile, nil
-		}
-	}
+	_, path, err := Locate(home)
+	return path, err
+}
 
-	// Check for zsh history
-	zshHistory := filepath.Join(home, ".zsh_history")
-	if _, err := os.Stat(zshHistory); err == nil {
-		return zshHistory, nil
-	}
+// ReadHistory returns up to limit unique commands from the shell history,
+// newest first.
+func ReadHistory(limit int) ([]Entry, error) {
+	return ReadHistoryWith(Filter{}, limit)
+}
 
-	// Check for bash history
-	bashHistory := filepath.Join(home, ".bash_history")
-	if _, err := os.Stat(bashHistory); err == nil {
-		return bashHistory, nil
+// ReadHistoryWith is ReadHistory for only the commands matching filter. It
+// fails if the history in use does not record what filter needs.
+func ReadHistoryWith(filter Filter, limit int) ([]Entry, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
 	}
-
-	// Check for generic .history (common with custom HISTFILE configurations)
-	dotHistory := filepath.Join(home, ".history")
-	if _, err := os.Stat(dotHistory); err == nil {
-		return dotHistory, nil
+	reader, path, err := Locate(home)
+	if err != nil {
+		return nil, err
 	}
-
-	return "", os.ErrNotExist
-}
-
-func ReadHistory(limit int) ([]Entry, error) {
-	path, err := GetHistoryPath()
+	if !filter.IsZero() && !isDetailed(reader, path) {
+		return nil, fmt.Errorf("%s history does not record directories or exit statuses (try --shell atuin)", reader.Name())
+	}
+	entries, err := reader.Read(path)
 	if err != nil {
 		return nil, err
 	}
-
-	return ReadHistoryFrom(path, limit)
+	return recent(slices.DeleteFunc(entries, func(e Entry) bool { return !filter.Match(e) }), limit), nil
 }
 
+// ReadHistoryFrom is ReadHistory for a specific file, whose format is detected.
 func ReadHistoryFrom(path string, limit int) ([]Entry, error) {
-	file, err := os.Open(path)
+	entries, err := Detect(path).Read(path)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
-
-	var allLines []string
-	scanner := bufio.NewScanner(file)
-
-	// Increase buffer size for long commands
-	buf := make([]byte, 0, 64*1024)
-	scanner.Buffer(buf, 1024*1024)
+	return recent(entries, limit), nil
+}
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		if line == "" {
-			continue
-		}
-		allLines = append(allLines, line)
+// readLocated reads the history file found by Locate.
+func readLocated() ([]Entry, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
 	}
-
-	if err := scanner.Err(); err != nil {
+	reader, path, err := Locate(home)
+	if err != nil {
 		return nil, err
 	}
+	return reader.Read(path)
+}
 
-	// Process lines (handle zsh extended history format)
-	var entries []Entry
+// recent returns up to limit unique commands from entries, which are oldest
+// first, newest first.
+func recent(entries []Entry, limit int) []Entry {
+	var result []Entry
 	seen := make(map[string]bool)
 
-	// Read from end (most recent first)
-	for i := len(allLines) - 1; i >= 0; i-- {
-		cmd, ts := parseHistoryLine(allLines[i])
+	for i := len(entries) - 1; i >= 0; i-- {
+		cmd := cleanCommand(entries[i].Command)
 		if cmd == "" {
 			continue
 		}
@@ -112,24 +141,29 @@ func ReadHistoryFrom(path string, limit int) ([]Entry, error) {
 		}
 		seen[cmd] = true
 
-		entries = append(entries, Entry{
-			Command:   cmd,
-			Index:     len(entries),
-			Timestamp: ts,
-		})
+		entry := entries[i]
+		entry.Command = cmd
+		entry.Index = len(result)
+		result = append(result, entry)
 
-		if limit > 0 && len(entries) >= limit {
+		if limit > 0 && len(result) >= limit {
 			break
 		}
 	}
 
-	return entries, nil
+	return result
 }
 
 func parseHistoryLine(line string) (string, time.Time) {
+	cmd, ts := splitExtendedLine(line)
+	return cleanCommand(cmd), ts
+}
+
+// splitExtendedLine separates the timestamp from a zsh extended history line
+// (": timestamp:duration;command"). Other lines are returned unchanged.
+func splitExtendedLine(line string) (string, time.Time) {
 	var ts time.Time
 
-	// Handle zsh extended history format: : timestamp:0;command
 	if strings.HasPrefix(line, ": ") {
 		if idx := strings.Index(line, ";"); idx != -1 {
 			// Extract timestamp between ": " and ":"
@@ -144,58 +178,55 @@ func parseHistoryLine(line string) (string, time.Time) {
 		}
 	}
 
-	// Trim whitespace
-	line = strings.TrimSpace(line)
+	return line, ts
+}
+
+// cleanCommand trims cmd and returns "" for commands not worth offering.
+func cleanCommand(cmd string) string {
+	cmd = strings.TrimSpace(cmd)
 
 	// Skip empty lines and very short commands
-	if len(line) < 2 {
-		return "", ts
+	if len(cmd) < 2 {
+		return ""
 	}
 
 	// Skip common non-useful commands
 	skip := []string{"ls", "cd", "pwd", "clear", "exit", "history"}
-	if slices.Contains(skip, line) {
-		return "", ts
+	if slices.Contains(skip, cmd) {
+		return ""
 	}
 
-	return line, ts
+	return cmd
 }
 
 // GetFrequentCommands analyses shell history and returns the most frequently
 // used commands that have at least minArgs arguments, from the last daysBack days.
 // Returns up to limit results sorted by frequency (descending).
 func GetFrequentCommands(daysBack, minArgs, limit int) ([]FrequentCommand, error) {
-	path, err := GetHistoryPath()
+	entries, err := readLocated()
 	if err != nil {
 		return nil, err
 	}
-	return GetFrequentCommandsFrom(path, daysBack, minArgs, limit)
+	return frequent(entries, daysBack, minArgs, limit), nil
 }
 
 // GetFrequentCommandsFrom analyses shell history from a specific file.
 func GetFrequentCommandsFrom(path string, daysBack, minArgs, limit int) ([]FrequentCommand, error) {
-	file, err := os.Open(path)
+	entries, err := Detect(path).Read(path)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
+	return frequent(entries, daysBack, minArgs, limit), nil
+}
 
+func frequent(entries []Entry, daysBack, minArgs, limit int) []FrequentCommand {
 	cutoff := time.Now().AddDate(0, 0, -daysBack)
 	counts := make(map[string]int)
 	hasTimestamps := false
 
-	scanner := bufio.NewScanner(file)
-	buf := make([]byte, 0, 64*1024)
-	scanner.Buffer(buf, 1024*1024)
-
-	for scanner.Scan() {
-		line := scanner.Text()
-		if line == "" {
-			continue
-		}
-
-		cmd, ts := parseHistoryLine(line)
-		if cmd == "" {
+	for _, entry := range entries {
+		cmd, ts := cleanCommand(entry.Command), entry.Timestamp
+		if cmd == "" || entry.Failed() {
 			continue
 		}
 
@@ -234,15 +265,8 @@ func GetFrequentCommandsFrom(path string, daysBack, minArgs, limit int) ([]Frequ
 		counts[cmd]++
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, err
-	}
-
-	// Convert to slice and sort by frequency
-	result := make([]FrequentCommand, 0, len(counts))
-	for cmd, count := range counts {
-		result = append(result, FrequentCommand{Command: cmd, Count: count})
-	}
+	// Merge near-identical commands and sort by frequency
+	result := cluster(counts)
 
 	sort.Slice(result, func(i, j int) bool {
 		if result[i].Count != result[j].Count {
@@ -256,7 +280,7 @@ func GetFrequentCommandsFrom(path string, daysBack, minArgs, limit int) ([]Frequ
 		result = result[:limit]
 	}
 
-	return result, nil
+	return result
 }
 
 // countArgs returns the number of space-separated arguments in a command.
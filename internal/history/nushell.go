@@ -0,0 +1,88 @@
+package history
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// nushellReader reads nushell history in either of its formats: plaintext
+// history.txt, one command per line, or the history.sqlite3 database used
+// when history.file_format is "sqlite".
+type nushellReader struct{}
+
+func (nushellReader) Name() string { return "nushell" }
+
+func (nushellReader) Paths(home string) []string {
+	dirs := []string{filepath.Join(home, ".config", "nushell")}
+	if configHome := os.Getenv("XDG_CONFIG_HOME"); configHome != "" {
+		dirs = append([]string{filepath.Join(configHome, "nushell")}, dirs...)
+	}
+	dirs = append(dirs, filepath.Join(home, "Library", "Application Support", "nushell"))
+
+	var paths []string
+	for _, dir := range dirs {
+		paths = append(paths, filepath.Join(dir, "history.sqlite3"), filepath.Join(dir, "history.txt"))
+	}
+	return paths
+}
+
+// nushellNewline is how the plaintext format stores newlines within a command.
+const nushellNewline = `<\n>`
+
+// Detailed reports whether path is the SQLite format, which records where
+// and how each command ran.
+func (nushellReader) Detailed(path string) bool {
+	return strings.HasSuffix(path, ".sqlite3")
+}
+
+func (nushellReader) Read(path string) ([]Entry, error) {
+	if strings.HasSuffix(path, ".sqlite3") {
+		return readNushellSQLite(path)
+	}
+
+	var entries []Entry
+	err := readLines(path, func(line string) {
+		entries = append(entries, Entry{Command: strings.ReplaceAll(line, nushellNewline, "\n")})
+	})
+	return entries, err
+}
+
+func readNushellSQLite(path string) ([]Entry, error) {
+	db, err := openSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`SELECT command_line, start_timestamp, cwd, exit_status, duration_ms, hostname
+		FROM history ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		var cmd string
+		var started, exit, duration sql.NullInt64 // times in milliseconds
+		var cwd, host sql.NullString
+		if err := rows.Scan(&cmd, &started, &cwd, &exit, &duration, &host); err != nil {
+			return nil, err
+		}
+		entry := Entry{
+			Command:  cmd,
+			Dir:      cwd.String,
+			Exit:     int(exit.Int64),
+			Duration: time.Duration(duration.Int64) * time.Millisecond,
+			Host:     host.String,
+		}
+		if started.Valid {
+			entry.Timestamp = time.UnixMilli(started.Int64)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
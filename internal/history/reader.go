@@ -0,0 +1,229 @@
+package history
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Reader parses one shell's history file.
+type Reader interface {
+	// Name is the value accepted by --shell.
+	Name() string
+	// Paths lists where the shell keeps its history under home, most likely first.
+	Paths(home string) []string
+	// Read returns every command in the file at path, oldest first.
+	Read(path string) ([]Entry, error)
+}
+
+// DetailedReader is a Reader whose entries can record the directory, exit
+// status, duration and host of each command.
+type DetailedReader interface {
+	Reader
+	// Detailed reports whether the file at path records those details.
+	Detailed(path string) bool
+}
+
+func isDetailed(r Reader, path string) bool {
+	d, ok := r.(DetailedReader)
+	return ok && d.Detailed(path)
+}
+
+var readers = []Reader{
+	zshReader{},
+	bashReader{},
+	fishReader{},
+	nushellReader{},
+	powershellReader{},
+	atuinReader{},
+}
+
+// shellAliases maps shell binary names to reader names.
+var shellAliases = map[string]string{
+	"nu":   "nushell",
+	"pwsh": "powershell",
+}
+
+// Get returns the reader registered under name or one of its aliases.
+func Get(name string) (Reader, error) {
+	if alias, ok := shellAliases[name]; ok {
+		name = alias
+	}
+	for _, r := range readers {
+		if r.Name() == name {
+			return r, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown shell %q (supported: %s)", name, strings.Join(Names(), ", "))
+}
+
+// Names returns the names of all registered readers.
+func Names() []string {
+	names := make([]string, len(readers))
+	for i, r := range readers {
+		names[i] = r.Name()
+	}
+	return names
+}
+
+// shellOverride is set by SetShell, e.g. from the --shell flag.
+var shellOverride Reader
+
+// SetShell makes Locate read the named shell's history instead of detecting it.
+func SetShell(name string) error {
+	r, err := Get(name)
+	if err != nil {
+		return err
+	}
+	shellOverride = r
+	return nil
+}
+
+// Locate finds the history file to read and the reader for its format. In
+// order it tries the shell given to SetShell, $HISTFILE, atuin's database
+// when atuin is active, the shell in $SHELL, then every known shell's default
+// locations.
+func Locate(home string) (Reader, string, error) {
+	if shellOverride != nil {
+		if path := histFile(); path != "" && isLineBased(shellOverride) {
+			return shellOverride, path, nil
+		}
+		if path := firstExisting(shellOverride.Paths(home)); path != "" {
+			return shellOverride, path, nil
+		}
+		return nil, "", fmt.Errorf("no %s history found: %w", shellOverride.Name(), os.ErrNotExist)
+	}
+
+	// HISTFILE is the user's explicit preference
+	if path := histFile(); path != "" {
+		return Detect(path), path, nil
+	}
+
+	// atuin records more than any shell's own history, so it wins where it is
+	// hooked into the current shell. A database left on disk is not enough.
+	if atuinActive() {
+		if path := firstExisting(atuinReader{}.Paths(home)); path != "" {
+			return atuinReader{}, path, nil
+		}
+	}
+
+	if shell := os.Getenv("SHELL"); shell != "" {
+		if r, err := Get(filepath.Base(shell)); err == nil {
+			if path := firstExisting(r.Paths(home)); path != "" {
+				return r, path, nil
+			}
+		}
+	}
+
+	for _, r := range readers {
+		if path := firstExisting(r.Paths(home)); path != "" {
+			return r, path, nil
+		}
+	}
+
+	// Generic .history (common with custom HISTFILE configurations)
+	if path := firstExisting([]string{filepath.Join(home, ".history")}); path != "" {
+		return Detect(path), path, nil
+	}
+
+	return nil, "", os.ErrNotExist
+}
+
+// atuinActive reports whether atuin records the current shell's history,
+// which it marks by setting $ATUIN_SESSION, or its database was named.
+func atuinActive() bool {
+	return os.Getenv("ATUIN_SESSION") != "" || os.Getenv("ATUIN_DB_PATH") != ""
+}
+
+// histFile returns $HISTFILE if it names an existing file.
+func histFile() string {
+	return firstExisting([]string{os.Getenv("HISTFILE")})
+}
+
+// isLineBased reports whether r reads the kind of file HISTFILE points at.
+func isLineBased(r Reader) bool {
+	return r.Name() == "zsh" || r.Name() == "bash"
+}
+
+func firstExisting(paths []string) string {
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// bashTimestamp matches the "#epoch" lines bash writes when HISTTIMEFORMAT is set.
+var bashTimestamp = regexp.MustCompile(`^#\d{9,}$`)
+
+// Detect picks the reader for the history file at path from its name, or
+// failing that its first lines. Plain one-command-per-line files read as zsh,
+// which accepts both its extended format and plain lines.
+func Detect(path string) Reader {
+	base := filepath.Base(path)
+	switch {
+	case base == "fish_history":
+		return fishReader{}
+	case base == "history.db":
+		return atuinReader{}
+	case strings.HasSuffix(base, ".sqlite3"), filepath.Base(filepath.Dir(path)) == "nushell":
+		return nushellReader{}
+	case base == "ConsoleHost_history.txt":
+		return powershellReader{}
+	case base == ".bash_history":
+		return bashReader{}
+	case base == ".zsh_history":
+		return zshReader{}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return zshReader{}
+	}
+	defer file.Close()
+
+	scanner := newScanner(io.LimitReader(file, 64*1024))
+	for lines := 0; scanner.Scan() && lines < 20; lines++ {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "- cmd: "):
+			return fishReader{}
+		case bashTimestamp.MatchString(line):
+			return bashReader{}
+		case strings.HasPrefix(line, ": "):
+			return zshReader{}
+		}
+	}
+	return zshReader{}
+}
+
+// newScanner returns a line scanner with room for long commands.
+func newScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	buf := make([]byte, 0, 64*1024)
+	scanner.Buffer(buf, 1024*1024)
+	return scanner
+}
+
+// readLines calls fn with each line of the file at path.
+func readLines(path string, fn func(line string)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := newScanner(file)
+	for scanner.Scan() {
+		fn(scanner.Text())
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,452 @@
+package history
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReaders(t *testing.T) {
+	tests := []struct {
+		name      string
+		reader    Reader
+		path      string
+		want      []string
+		firstTime int64 // epoch of the first entry, 0 if untimed
+	}{
+		{
+			name:      "fish",
+			reader:    fishReader{},
+			path:      "testdata/fish_history",
+			want:      []string{"git status", "docker compose up -d --build", "echo one\ntwo", `printf 'a\b'`},
+			firstTime: 1699000000,
+		},
+		{
+			name:      "bash with HISTTIMEFORMAT",
+			reader:    bashReader{},
+			path:      "testdata/bash_history",
+			want:      []string{"git status", "kubectl get pods -n kube-system", "make test"},
+			firstTime: 1699000000,
+		},
+		{
+			name:   "nushell plaintext",
+			reader: nushellReader{},
+			path:   "testdata/nushell/history.txt",
+			want:   []string{"git log --oneline", "for x in [1 2] {\n  print $x\n}"},
+		},
+		{
+			name:   "powershell",
+			reader: powershellReader{},
+			path:   "testdata/ConsoleHost_history.txt",
+			want:   []string{"Get-ChildItem -Recurse", "Get-Process |\n  Sort-Object CPU"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := tt.reader.Read(tt.path)
+			if err != nil {
+				t.Fatalf("Read failed: %v", err)
+			}
+			if len(entries) != len(tt.want) {
+				t.Fatalf("got %d entries, want %d: %+v", len(entries), len(tt.want), entries)
+			}
+			for i, want := range tt.want {
+				if entries[i].Command != want {
+					t.Errorf("entry %d = %q, want %q", i, entries[i].Command, want)
+				}
+			}
+			if got := entries[0].Timestamp; tt.firstTime == 0 && !got.IsZero() || tt.firstTime != 0 && got.Unix() != tt.firstTime {
+				t.Errorf("first timestamp = %v, want epoch %d", got, tt.firstTime)
+			}
+		})
+	}
+}
+
+func TestBashReaderOnlyTimesFollowingCommand(t *testing.T) {
+	entries, err := bashReader{}.Read("testdata/bash_history")
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if last := entries[len(entries)-1]; !last.Timestamp.IsZero() {
+		t.Errorf("%q has no #epoch line but got timestamp %v", last.Command, last.Timestamp)
+	}
+}
+
+func TestNushellSQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.sqlite3")
+	writeSQLite(t, path, `CREATE TABLE history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		command_line TEXT NOT NULL,
+		start_timestamp INTEGER,
+		session_id INTEGER,
+		hostname TEXT,
+		cwd TEXT,
+		duration_ms INTEGER,
+		exit_status INTEGER,
+		more_info TEXT
+	);
+	INSERT INTO history (command_line, start_timestamp, cwd, exit_status) VALUES
+		('git fetch --all --prune', 1699000000000, '/src/bkmk', 0),
+		('cargo build --release', NULL, NULL, NULL);`)
+
+	entries, err := ReadHistoryFrom(path, 0)
+	if err != nil {
+		t.Fatalf("ReadHistoryFrom failed: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Command != "cargo build --release" {
+		t.Fatalf("expected newest first, got %+v", entries)
+	}
+	if !entries[0].Timestamp.IsZero() || !entries[1].Timestamp.Equal(time.UnixMilli(1699000000000)) {
+		t.Errorf("unexpected timestamps: %v, %v", entries[0].Timestamp, entries[1].Timestamp)
+	}
+	if entries[1].Dir != "/src/bkmk" {
+		t.Errorf("expected the directory to be read, got %q", entries[1].Dir)
+	}
+}
+
+// writeSQLite creates a SQLite database at path by running script.
+func writeSQLite(t *testing.T, path, script string) {
+	t.Helper()
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(script); err != nil {
+		t.Fatalf("create fixture: %v", err)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"testdata/fish_history", "fish"},
+		{"testdata/nushell/history.txt", "nushell"},
+		{"testdata/ConsoleHost_history.txt", "powershell"},
+		{write(".custom_bash", "#1699000000\ngit status\n"), "bash"},
+		{write(".custom_fish", "- cmd: git status\n  when: 1699000000\n"), "fish"},
+		{write(".custom_zsh", ": 1699000000:0;git status\n"), "zsh"},
+		{write(".plain", "git status\n"), "zsh"},
+	}
+	for _, tt := range tests {
+		if got := Detect(tt.path).Name(); got != tt.want {
+			t.Errorf("Detect(%s) = %s, want %s", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestLocate(t *testing.T) {
+	t.Setenv("HISTFILE", "")
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("ATUIN_DB_PATH", "")
+	t.Setenv("ATUIN_SESSION", "")
+	home := t.TempDir()
+
+	fishHistory := filepath.Join(home, ".local", "share", "fish", "fish_history")
+	if err := os.MkdirAll(filepath.Dir(fishHistory), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(fishHistory, []byte("- cmd: git status\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	bashHistory := filepath.Join(home, ".bash_history")
+	if err := os.WriteFile(bashHistory, []byte("git status\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("prefers $SHELL", func(t *testing.T) {
+		t.Setenv("SHELL", "/usr/bin/fish")
+		r, path, err := Locate(home)
+		if err != nil || r.Name() != "fish" || path != fishHistory {
+			t.Errorf("Locate = %v, %s, %v; want fish at %s", r, path, err, fishHistory)
+		}
+	})
+
+	t.Run("falls back to probing", func(t *testing.T) {
+		t.Setenv("SHELL", "/bin/sh")
+		r, path, err := Locate(home)
+		if err != nil || r.Name() != "bash" || path != bashHistory {
+			t.Errorf("Locate = %v, %s, %v; want bash at %s", r, path, err, bashHistory)
+		}
+	})
+
+	atuinDB := filepath.Join(home, ".local", "share", "atuin", "history.db")
+	if err := os.MkdirAll(filepath.Dir(atuinDB), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(atuinDB, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("atuin only when active", func(t *testing.T) {
+		t.Setenv("SHELL", "/usr/bin/fish")
+		if r, path, err := Locate(home); err != nil || r.Name() != "fish" || path != fishHistory {
+			t.Errorf("Locate = %v, %s, %v; want fish while atuin is not in use", r, path, err)
+		}
+		t.Setenv("ATUIN_SESSION", "0192b5a0f7e47c3d")
+		if r, path, err := Locate(home); err != nil || r.Name() != "atuin" || path != atuinDB {
+			t.Errorf("Locate = %v, %s, %v; want atuin at %s", r, path, err, atuinDB)
+		}
+	})
+
+	t.Run("--shell overrides detection", func(t *testing.T) {
+		t.Setenv("SHELL", "/bin/bash")
+		defer func() { shellOverride = nil }()
+		if err := SetShell("fish"); err != nil {
+			t.Fatal(err)
+		}
+		if _, path, err := Locate(home); err != nil || path != fishHistory {
+			t.Errorf("Locate = %s, %v; want %s", path, err, fishHistory)
+		}
+		if err := SetShell("nu"); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := Locate(home); err == nil {
+			t.Error("expected an error when the chosen shell has no history")
+		}
+	})
+
+	if err := SetShell("tcsh"); err == nil {
+		t.Error("expected an error for an unknown shell")
+	}
+}
+
+func TestZshMultilineEntries(t *testing.T) {
+	entries, err := zshReader{}.Read("testdata/zsh_history_multiline")
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	want := []string{
+		"docker run --rm \\\n  -v \"$PWD:/src\" \\\n  golang:1.25 go test ./...",
+		"for f in *.go; do\n  gofmt -l \"$f\"\n\ndone",
+		"git status",
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d: %+v", len(entries), len(want), entries)
+	}
+	for i, w := range want {
+		if entries[i].Command != w {
+			t.Errorf("entry %d = %q, want %q", i, entries[i].Command, w)
+		}
+		if entries[i].Timestamp.IsZero() {
+			t.Errorf("entry %d lost its timestamp", i)
+		}
+	}
+
+	// Reassembled commands are no longer discarded as fragments
+	frequent, err := GetFrequentCommandsFrom("testdata/zsh_history_multiline", 36500, 2, 0)
+	if err != nil {
+		t.Fatalf("GetFrequentCommandsFrom failed: %v", err)
+	}
+	if len(frequent) != 2 || frequent[0].Command != want[0] {
+		t.Errorf("expected both multi-line commands, got %+v", frequent)
+	}
+}
+
+func TestBashHeredocEntries(t *testing.T) {
+	entries, err := bashReader{}.Read("testdata/bash_heredoc")
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	want := []string{
+		"cat <<EOF > notes.txt\nfirst line\n\nsecond line\nEOF",
+		"git status",
+		"kubectl apply -f - <<-'YAML'\n\tkind: Namespace\n\tYAML",
+		`cat <<< "here-string"`,
+		// Not heredocs: arithmetic and quoted <<
+		"echo $((1<<n))",
+		`echo "a <<b" '<<c'`,
+		"git status",
+		"kubectl get pods",
+		// A heredoc never terminated gives its lines back as commands
+		"cat <<END",
+		"make build",
+		"git push",
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d: %+v", len(entries), len(want), entries)
+	}
+	for i, w := range want {
+		if entries[i].Command != w {
+			t.Errorf("entry %d = %q, want %q", i, entries[i].Command, w)
+		}
+	}
+}
+
+func TestHeredocDelimiters(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"cat <<EOF", []string{"EOF"}},
+		{"cat <<-'EOF' && cat << \"END\"", []string{"EOF", "END"}},
+		{"cat <<< word", nil},
+		{"echo $(( (1 << n) + 1 )) <<EOF", []string{"EOF"}},
+		{"(( x <<= 2 ))", nil},
+		{`echo "<<EOF" \<<NOT`, nil},
+	}
+	for _, tt := range tests {
+		if got := heredocDelimiters(tt.line); !slices.Equal(got, tt.want) {
+			t.Errorf("heredocDelimiters(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestBashHeredocGivesUpAfterMaxLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".bash_history")
+	lines := []string{"cat <<EOF"}
+	for i := range maxHeredocLines + 10 {
+		lines = append(lines, fmt.Sprintf("echo %d", i))
+	}
+	lines = append(lines, "EOF")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatalf("failed to write history: %v", err)
+	}
+
+	entries, err := bashReader{}.Read(path)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if len(entries) != len(lines) || entries[1].Command != "echo 0" {
+		t.Errorf("expected every line read as its own entry, got %d entries", len(entries))
+	}
+}
+
+func TestZshMetafiedHistory(t *testing.T) {
+	entries, err := ReadHistoryFrom("testdata/zsh_history_metafied", 0)
+	if err != nil {
+		t.Fatalf("ReadHistoryFrom failed: %v", err)
+	}
+	want := []string{
+		"git commit -m '🚀 release'",
+		"echo 'naïve café' > ăa.txt",
+		"cd ~/Документы && ls -la",
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d: %+v", len(entries), len(want), entries)
+	}
+	for i, w := range want {
+		if entries[i].Command != w {
+			t.Errorf("entry %d = %q, want %q", i, entries[i].Command, w)
+		}
+	}
+}
+
+func TestUnmetafy(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"git status", "git status"},
+		{"cat \xc4\x83\xa3a.txt", "cat ăa.txt"},
+		// Plain UTF-8 containing 0x83 is not zsh metafied
+		{"cat ăa.txt", "cat ăa.txt"},
+		// A trailing meta byte has nothing to decode
+		{"echo \x83", "echo \x83"},
+	}
+	for _, tt := range tests {
+		if got := unmetafy(tt.input); got != tt.expected {
+			t.Errorf("unmetafy(%q) = %q, want %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+// atuinFixture builds an atuin database from testdata/atuin/history.sql.
+func atuinFixture(t *testing.T) string {
+	t.Helper()
+	script, err := os.ReadFile("testdata/atuin/history.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "atuin", "history.db")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeSQLite(t, path, string(script))
+	return path
+}
+
+func TestAtuinReader(t *testing.T) {
+	path := atuinFixture(t)
+	if got := Detect(path).Name(); got != "atuin" {
+		t.Errorf("Detect = %s, want atuin", got)
+	}
+
+	entries, err := atuinReader{}.Read(path)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if len(entries) != 5 {
+		t.Fatalf("expected 5 entries without the deleted one, got %d: %+v", len(entries), entries)
+	}
+
+	first := entries[0]
+	if first.Dir != "/src/app" || first.Host != "laptop" || first.Duration != 2500*time.Millisecond || first.Failed() {
+		t.Errorf("unexpected details: %+v", first)
+	}
+	if !first.Timestamp.Equal(time.Unix(1699000000, 0)) {
+		t.Errorf("unexpected timestamp: %v", first.Timestamp)
+	}
+	if !entries[1].Failed() || entries[1].Exit != 1 {
+		t.Errorf("expected the typo to have failed: %+v", entries[1])
+	}
+	if unknown := entries[4]; unknown.Failed() || unknown.Duration != 0 {
+		t.Errorf("expected unknown status and duration to read as zero: %+v", unknown)
+	}
+}
+
+func TestReadHistoryWithFilter(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("HISTFILE", "")
+	t.Setenv("ATUIN_DB_PATH", atuinFixture(t))
+
+	entries, err := ReadHistoryWith(Filter{Successful: true, Dir: "/src/app"}, 0)
+	if err != nil {
+		t.Fatalf("ReadHistoryWith failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Command != "docker compose up -d --build" {
+		t.Errorf("expected only the successful command in /src/app, got %+v", entries)
+	}
+
+	// Plain history files cannot be filtered
+	t.Setenv("ATUIN_DB_PATH", "")
+	t.Setenv("HISTFILE", "testdata/bash_history")
+	if _, err := ReadHistoryWith(Filter{Successful: true}, 0); err == nil {
+		t.Error("expected an error filtering a history without exit statuses")
+	}
+}
+
+func TestFrequentCommandsIgnoreFailures(t *testing.T) {
+	path := atuinFixture(t)
+	commands, err := GetFrequentCommandsFrom(path, 36500, 2, 0)
+	if err != nil {
+		t.Fatalf("GetFrequentCommandsFrom failed: %v", err)
+	}
+	for _, cmd := range commands {
+		if cmd.Command == "docker compose up -d --bulid" {
+			t.Errorf("failed command should not be suggested: %+v", commands)
+		}
+	}
+	if len(commands) == 0 || commands[0].Command != "docker compose up -d --build" || commands[0].Count != 2 {
+		t.Errorf("expected the successful compose command twice, got %+v", commands)
+	}
+}
@@ -0,0 +1,280 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// zshReader reads zsh history, in either plain or extended
+// (": timestamp:duration;command") format.
+type zshReader struct{}
+
+func (zshReader) Name() string { return "zsh" }
+
+func (zshReader) Paths(home string) []string {
+	return []string{filepath.Join(home, ".zsh_history")}
+}
+
+// Read joins multi-line commands, which zsh saves with a backslash before
+// each embedded newline.
+func (zshReader) Read(path string) ([]Entry, error) {
+	var entries []Entry
+	var pending []string
+	add := func(lines []string) {
+		cmd, ts := splitExtendedLine(strings.Join(lines, "\n"))
+		entries = append(entries, Entry{Command: cmd, Timestamp: ts})
+	}
+	err := readLines(path, func(line string) {
+		if pending == nil && line == "" {
+			return
+		}
+		line = unmetafy(line)
+		if continued, ok := strings.CutSuffix(line, `\`); ok {
+			pending = append(pending, continued)
+			return
+		}
+		add(append(pending, line))
+		pending = nil
+	})
+	if pending != nil {
+		add(pending)
+	}
+	return entries, err
+}
+
+// zshMeta is the byte zsh writes before a "metafied" byte in its history
+// file. The byte that follows has been XORed with 0x20.
+const zshMeta = 0x83
+
+// unmetafy decodes the metafied bytes in a line of zsh history, which zsh
+// uses for non-ASCII bytes such as those of multibyte UTF-8 characters. A line
+// that is valid UTF-8 as it stands but not once decoded is left alone, as it
+// most likely came from a plain history file rather than zsh.
+func unmetafy(line string) string {
+	if strings.IndexByte(line, zshMeta) == -1 {
+		return line
+	}
+	decoded := make([]byte, 0, len(line))
+	for i := 0; i < len(line); i++ {
+		if line[i] == zshMeta && i+1 < len(line) {
+			i++
+			decoded = append(decoded, line[i]^0x20)
+			continue
+		}
+		decoded = append(decoded, line[i])
+	}
+	if utf8.ValidString(line) && !utf8.Valid(decoded) {
+		return line
+	}
+	return string(decoded)
+}
+
+// bashReader reads bash history, taking timestamps from the "#epoch" line
+// bash writes before each command when HISTTIMEFORMAT is set.
+type bashReader struct{}
+
+func (bashReader) Name() string { return "bash" }
+
+func (bashReader) Paths(home string) []string {
+	return []string{filepath.Join(home, ".bash_history")}
+}
+
+// maxHeredocLines is how far a heredoc may run before it is taken to be a
+// misread and its lines are read as commands after all.
+const maxHeredocLines = 500
+
+// Read keeps the body of a heredoc with the command that started it, as bash
+// saves heredocs over several lines. A heredoc whose terminator never comes
+// within maxHeredocLines, or before the end of the file, is given up on and
+// its lines read as commands of their own, so a misread never swallows the
+// rest of the history.
+func (bashReader) Read(path string) ([]Entry, error) {
+	var entries []Entry
+	var ts time.Time
+	var delims []string // heredoc terminators still to come for the last entry
+	var body []string   // lines of the open heredoc so far
+
+	var handle func(line string)
+	giveUp := func() {
+		lines := body
+		delims, body = nil, nil
+		for _, line := range lines {
+			handle(line)
+		}
+	}
+	handle = func(line string) {
+		if bashTimestamp.MatchString(line) {
+			if epoch, err := strconv.ParseInt(line[1:], 10, 64); err == nil {
+				ts = time.Unix(epoch, 0)
+			}
+			// A new entry, so any open heredoc was cut short
+			if len(body) > 0 {
+				entries[len(entries)-1].Command += "\n" + strings.Join(body, "\n")
+			}
+			delims, body = nil, nil
+			return
+		}
+		if len(delims) > 0 {
+			body = append(body, line)
+			if strings.TrimLeft(line, "\t") == delims[0] {
+				delims = delims[1:]
+			}
+			if len(delims) == 0 {
+				entries[len(entries)-1].Command += "\n" + strings.Join(body, "\n")
+				body = nil
+			} else if len(body) >= maxHeredocLines {
+				giveUp()
+			}
+			return
+		}
+		if line == "" {
+			return
+		}
+		entries = append(entries, Entry{Command: line, Timestamp: ts})
+		ts = time.Time{}
+		delims = heredocDelimiters(line)
+	}
+
+	err := readLines(path, handle)
+	for len(delims) > 0 {
+		giveUp()
+	}
+	return entries, err
+}
+
+// heredocWord matches the delimiter following a heredoc operator, which may
+// be quoted to stop expansion in the body.
+var heredocWord = regexp.MustCompile(`^-?\s*['"]?([A-Za-z_][A-Za-z0-9_]*)['"]?`)
+
+// heredocDelimiters returns the terminators of the heredocs started on line,
+// in order. A << inside quotes or arithmetic such as $((1<<n)) is not a
+// heredoc, and neither is the <<< of a here-string.
+func heredocDelimiters(line string) []string {
+	var delims []string
+	var quote byte
+	arith, parens := 0, 0 // depth of (( )) and of parentheses within it
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\\':
+			i++
+		case c == '\'' || c == '"':
+			quote = c
+		case strings.HasPrefix(line[i:], "(("):
+			arith++
+			i++
+		case arith > 0 && c == '(':
+			parens++
+		case arith > 0 && c == ')' && parens > 0:
+			parens--
+		case arith > 0 && strings.HasPrefix(line[i:], "))"):
+			arith--
+			i++
+		case arith > 0:
+		case strings.HasPrefix(line[i:], "<<<"):
+			i += 2
+		case strings.HasPrefix(line[i:], "<<"):
+			i++
+			if match := heredocWord.FindStringSubmatch(line[i+1:]); match != nil {
+				delims = append(delims, match[1])
+				i += len(match[0])
+			}
+		}
+	}
+	return delims
+}
+
+// fishReader reads fish's fish_history, a YAML-like list of
+// "- cmd: ..." items each followed by "  when: epoch" and optional paths.
+type fishReader struct{}
+
+func (fishReader) Name() string { return "fish" }
+
+func (fishReader) Paths(home string) []string {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	return []string{filepath.Join(dataHome, "fish", "fish_history")}
+}
+
+func (fishReader) Read(path string) ([]Entry, error) {
+	var entries []Entry
+	err := readLines(path, func(line string) {
+		if cmd, ok := strings.CutPrefix(line, "- cmd: "); ok {
+			entries = append(entries, Entry{Command: unescapeFish(cmd)})
+			return
+		}
+		when, ok := strings.CutPrefix(line, "  when: ")
+		if !ok || len(entries) == 0 {
+			return
+		}
+		if epoch, err := strconv.ParseInt(strings.TrimSpace(when), 10, 64); err == nil {
+			entries[len(entries)-1].Timestamp = time.Unix(epoch, 0)
+		}
+	})
+	return entries, err
+}
+
+// unescapeFish reverses fish's escaping of backslashes and newlines in cmd.
+func unescapeFish(cmd string) string {
+	if !strings.Contains(cmd, `\`) {
+		return cmd
+	}
+	var b strings.Builder
+	for i := 0; i < len(cmd); i++ {
+		if cmd[i] == '\\' && i+1 < len(cmd) {
+			switch cmd[i+1] {
+			case '\\':
+				b.WriteByte('\\')
+				i++
+				continue
+			case 'n':
+				b.WriteByte('\n')
+				i++
+				continue
+			}
+		}
+		b.WriteByte(cmd[i])
+	}
+	return b.String()
+}
+
+// powershellReader reads PSReadLine's ConsoleHost_history.txt, where each
+// line of a multi-line command but the last ends with a backtick.
+type powershellReader struct{}
+
+func (powershellReader) Name() string { return "powershell" }
+
+func (powershellReader) Paths(home string) []string {
+	return []string{filepath.Join(home, ".local", "share", "powershell", "PSReadLine", "ConsoleHost_history.txt")}
+}
+
+func (powershellReader) Read(path string) ([]Entry, error) {
+	var entries []Entry
+	var pending []string
+	err := readLines(path, func(line string) {
+		if continued, ok := strings.CutSuffix(line, "`"); ok {
+			pending = append(pending, continued)
+			return
+		}
+		cmd := strings.Join(append(pending, line), "\n")
+		entries = append(entries, Entry{Command: cmd})
+		pending = nil
+	})
+	if len(pending) > 0 {
+		entries = append(entries, Entry{Command: strings.Join(pending, "\n")})
+	}
+	return entries, err
+}
@@ -0,0 +1,19 @@
+package history
+
+import (
+	"database/sql"
+	"net/url"
+	"os"
+
+	_ "modernc.org/sqlite" // registers the "sqlite" driver
+)
+
+// openSQLite opens a history database read-only, so a shell writing to it at
+// the same time is never disturbed.
+func openSQLite(path string) (*sql.DB, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	dsn := url.URL{Scheme: "file", Path: path, RawQuery: "mode=ro&_pragma=busy_timeout(1000)"}
+	return sql.Open("sqlite", dsn.String())
+}
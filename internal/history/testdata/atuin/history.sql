@@ -0,0 +1,21 @@
+-- The schema of atuin's history.db. Times are in nanoseconds.
+CREATE TABLE history (
+	id TEXT PRIMARY KEY,
+	timestamp INTEGER NOT NULL,
+	duration INTEGER NOT NULL,
+	exit INTEGER NOT NULL,
+	command TEXT NOT NULL,
+	cwd TEXT NOT NULL,
+	session TEXT NOT NULL,
+	hostname TEXT NOT NULL,
+	deleted_at INTEGER,
+	UNIQUE(timestamp, cwd, command)
+);
+
+INSERT INTO history VALUES
+	('01', 1699000000000000000, 2500000000, 0, 'docker compose up -d --build', '/src/app', 's1', 'laptop:sam', NULL),
+	('02', 1699000100000000000, 150000000, 1, 'docker compose up -d --bulid', '/src/app', 's1', 'laptop:sam', NULL),
+	('03', 1699000200000000000, 900000000, 0, 'docker compose up -d --build', '/src/app', 's1', 'laptop:sam', NULL),
+	('04', 1699000300000000000, 3000000000, 0, 'kubectl get pods -n kube-system', '/home/sam', 's2', 'server:sam', NULL),
+	('05', 1699000400000000000, -1, -1, 'terraform plan -out tf.plan', '/src/infra', 's2', 'server:sam', NULL),
+	('06', 1699000500000000000, 100000000, 0, 'git push --force origin main', '/src/app', 's1', 'laptop:sam', 1699000600000000000);
@@ -0,0 +1,195 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/sammcj/bkmk/internal/config"
+)
+
+// Importer converts another tool's snippet format into bkmk groups.
+type Importer interface {
+	// Name is the value accepted by `bkmk import --from`.
+	Name() string
+	// Extensions lists the file extensions read when importing a directory.
+	Extensions() []string
+	// Parse reads snippets from r. source names the input for error messages
+	// and for formats that derive a group from the file name.
+	Parse(r io.Reader, source string) ([]config.Group, error)
+}
+
+var importers = []Importer{
+	petImporter{},
+	naviImporter{},
+	markdownImporter{},
+}
+
+// Get returns the importer registered under name.
+func Get(name string) (Importer, error) {
+	for _, imp := range importers {
+		if imp.Name() == name {
+			return imp, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown import format %q (supported: %s)", name, strings.Join(Names(), ", "))
+}
+
+// Names returns the names of all registered importers.
+func Names() []string {
+	names := make([]string, len(importers))
+	for i, imp := range importers {
+		names[i] = imp.Name()
+	}
+	return names
+}
+
+// Import reads path with imp and returns the result as a config ready for
+// Config.Import. If path is a directory, every file with one of the
+// importer's extensions is read, in name order.
+func Import(imp Importer, path string) (*config.Config, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		files = nil
+		err := filepath.WalkDir(path, func(p string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && slices.Contains(imp.Extensions(), strings.ToLower(filepath.Ext(p))) {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(files)
+	}
+
+	cfg := &config.Config{Groups: []config.Group{}}
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		groups, err := imp.Parse(f, file)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		for _, g := range groups {
+			mergeGroup(cfg, g)
+		}
+	}
+
+	return cfg, nil
+}
+
+// mergeGroup appends g's commands to the group of the same name in cfg,
+// creating it if needed and renaming commands whose names clash. A name
+// containing "/" is treated as a group path, so "cloud/aws" nests aws in cloud.
+func mergeGroup(cfg *config.Config, g config.Group) {
+	target := cfg.EnsureGroup(g.Name)
+	if target == nil {
+		return
+	}
+	for _, cmd := range g.Commands {
+		cmd.Name = uniqueName(target.Commands, cmd.Name)
+		target.Commands = append(target.Commands, cmd)
+	}
+}
+
+func uniqueName(cmds []config.Command, name string) string {
+	taken := func(candidate string) bool {
+		return slices.ContainsFunc(cmds, func(cmd config.Command) bool {
+			return cmd.Name == candidate
+		})
+	}
+	if !taken(name) {
+		return name
+	}
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s-%d", name, i)
+		if !taken(candidate) {
+			return candidate
+		}
+	}
+}
+
+var (
+	slugInvalidRe  = regexp.MustCompile(`[^a-z0-9]+`)
+	paramInvalidRe = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+)
+
+// slug turns free text into a short command name: lowercase words joined by
+// hyphens, at most maxSlugWords words.
+func slug(text string) string {
+	const maxSlugWords = 5
+	words := strings.Split(strings.Trim(slugInvalidRe.ReplaceAllString(strings.ToLower(text), "-"), "-"), "-")
+	if len(words) > maxSlugWords {
+		words = words[:maxSlugWords]
+	}
+	return strings.Join(words, "-")
+}
+
+// placeholder returns a bkmk {{name}} or {{name:default}} placeholder, with
+// name reduced to characters bkmk accepts.
+func placeholder(name, def string, hasDefault bool) string {
+	name = strings.Trim(paramInvalidRe.ReplaceAllString(name, "_"), "_")
+	if name == "" {
+		name = "value"
+	}
+	if name[0] >= '0' && name[0] <= '9' {
+		name = "arg" + name
+	}
+	if hasDefault {
+		return "{{" + name + ":" + def + "}}"
+	}
+	return "{{" + name + "}}"
+}
+
+// nameFor picks a command name from the description, falling back to the command.
+func nameFor(description, command string) string {
+	if name := slug(description); name != "" {
+		return name
+	}
+	if name := slug(command); name != "" {
+		return name
+	}
+	return "snippet"
+}
+
+// tagsFrom normalises another tool's tags into bkmk tags, replacing
+// whitespace and commas with hyphens and dropping empty or repeated tags.
+func tagsFrom(raw []string) []string {
+	var tags []string
+	for _, tag := range raw {
+		tag = strings.Join(strings.FieldsFunc(strings.TrimPrefix(strings.TrimSpace(tag), "#"), func(r rune) bool {
+			return r == ',' || r == '#' || unicode.IsSpace(r)
+		}), "-")
+		if tag != "" && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// groupName normalises a tag or title into a group path.
+func groupName(name, fallback string) string {
+	name = config.JoinPath(name)
+	if name == "" {
+		return fallback
+	}
+	return name
+}
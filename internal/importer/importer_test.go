@@ -0,0 +1,154 @@
+package importer
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sammcj/bkmk/internal/config"
+	"github.com/sammcj/bkmk/internal/params"
+)
+
+func TestImportFixtures(t *testing.T) {
+	tests := []struct {
+		format string
+		path   string
+		want   map[string][]config.Command
+	}{
+		{
+			format: "pet",
+			path:   "testdata/snippet.toml",
+			want: map[string][]config.Command{
+				"network": {{Name: "ping-google-dns", Command: "ping 8.8.8.8", Description: "Ping Google DNS", Tags: []string{"network", "google"}}},
+				"k8s":     {{Name: "show-pod-logs", Command: "kubectl logs -n {{namespace:default}} {{pod}}", Description: "Show pod logs", Tags: []string{"k8s"}}},
+				"pet":     {{Name: "checkout-a-branch", Command: "git checkout {{branch:main}}", Description: "Checkout a branch"}},
+			},
+		},
+		{
+			format: "navi",
+			path:   "testdata/navi",
+			want: map[string][]config.Command{
+				"git": {
+					{Name: "change-branch", Command: "git checkout {{branch}}", Description: "Change branch", Tags: []string{"git", "code"}},
+					{Name: "amend-last-commit-without-editing", Command: "git commit --amend --no-edit", Description: "Amend last commit without editing message", Tags: []string{"git", "code"}},
+				},
+				"docker": {{Name: "run-a-container-interactively", Command: "docker run -it --rm \\\n  {{image}} sh", Description: "Run a container interactively", Tags: []string{"docker"}}},
+			},
+		},
+		{
+			format: "markdown",
+			path:   "testdata/tar.md",
+			want: map[string][]config.Command{
+				"tar": {
+					{Name: "create-an-archive-and-write", Command: "tar cf {{path_to_target_tar}} {{path_to_file1_path_to_file2}}", Description: "create an archive and write it to a file"},
+					{Name: "extract-a-compressed-archive-file", Command: "tar xvf {{path_to_source_tar_gz_bz2_xz}}", Description: "Extract a (compressed) archive file into the current directory verbosely"},
+					{Name: "list-the-contents-of-a", Command: "tar tvf {{path_to_source_tar}}", Description: "List the contents of a tar file"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			imp, err := Get(tt.format)
+			if err != nil {
+				t.Fatalf("Get(%q) failed: %v", tt.format, err)
+			}
+
+			cfg, err := Import(imp, tt.path)
+			if err != nil {
+				t.Fatalf("Import failed: %v", err)
+			}
+
+			got := make(map[string][]config.Command)
+			for _, g := range cfg.Groups {
+				got[g.Name] = g.Commands
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("imported groups mismatch\n got: %+v\nwant: %+v", got, tt.want)
+			}
+
+			// Every converted command should have placeholders bkmk can parse
+			for _, g := range cfg.Groups {
+				for _, cmd := range g.Commands {
+					if strings.Contains(cmd.Command, "{{") && len(params.Parse(cmd.Command)) == 0 {
+						t.Errorf("command %q has unparseable placeholders", cmd.Command)
+					}
+				}
+			}
+
+			assertRoundTrip(t, cfg)
+		})
+	}
+}
+
+// assertRoundTrip imports cfg into an empty config, exports it and parses the
+// export again, expecting the same commands back.
+func assertRoundTrip(t *testing.T, imported *config.Config) {
+	t.Helper()
+
+	dest := &config.Config{Groups: []config.Group{}, NextID: 1}
+	report := dest.Import(imported, config.ImportOptions{})
+	if len(report.Renamed)+len(report.Skipped) != 0 {
+		t.Errorf("unexpected clashes importing into empty config: %+v", report)
+	}
+
+	exported, err := dest.Export(nil)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	data, err := exported.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	reparsed, err := config.Parse(data, "export.yaml")
+	if err != nil {
+		t.Fatalf("Parse of export failed: %v\n%s", err, data)
+	}
+
+	strip := func(cmds []config.FlatCommand) []config.FlatCommand {
+		for i := range cmds {
+			cmds[i].ID = 0
+		}
+		return cmds
+	}
+	if got, want := strip(reparsed.FlatCommands()), strip(imported.FlatCommands()); !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip mismatch\n got: %+v\nwant: %+v", got, want)
+	}
+}
+
+func TestGetUnknown(t *testing.T) {
+	if _, err := Get("unknown"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestImportMissingPath(t *testing.T) {
+	imp, _ := Get("pet")
+	if _, err := Import(imp, filepath.Join(t.TempDir(), "missing.toml")); err == nil {
+		t.Error("expected error for missing path")
+	}
+}
+
+func TestTagsFrom(t *testing.T) {
+	got := tagsFrom([]string{" k8s ", "my tag", "#prod", "k8s", ""})
+	want := []string{"k8s", "my-tag", "prod"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tagsFrom() = %v, want %v", got, want)
+	}
+}
+
+func TestSlug(t *testing.T) {
+	tests := map[string]string{
+		"Ping Google DNS":             "ping-google-dns",
+		"  Show   pod logs!! ":        "show-pod-logs",
+		"one two three four five six": "one-two-three-four-five",
+		"":                            "",
+	}
+	for in, want := range tests {
+		if got := slug(in); got != want {
+			t.Errorf("slug(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
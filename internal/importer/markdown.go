@@ -0,0 +1,92 @@
+package importer
+
+import (
+	"bufio"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/sammcj/bkmk/internal/config"
+)
+
+var (
+	// tldrVarRe matches tldr placeholders such as {{path/to/file}}.
+	tldrVarRe = regexp.MustCompile(`\{\{(.*?)\}\}`)
+	// mnemonicRe matches tldr's [c]reate style mnemonic brackets.
+	mnemonicRe = regexp.MustCompile(`\[(\w)\]`)
+)
+
+// markdownImporter reads tldr-style pages: a "# title" heading names the
+// group, and each "- description:" item is followed by the command, either
+// in backticks on its own line or in a fenced code block.
+type markdownImporter struct{}
+
+func (markdownImporter) Name() string { return "markdown" }
+
+func (markdownImporter) Extensions() []string { return []string{".md", ".markdown"} }
+
+func (markdownImporter) Parse(r io.Reader, source string) ([]config.Group, error) {
+	cfg := &config.Config{}
+	group := groupName(strings.TrimSuffix(filepath.Base(source), filepath.Ext(source)), "markdown")
+
+	var description string
+	var fence []string
+	inFence := false
+
+	add := func(command string) {
+		command = strings.TrimSpace(command)
+		if command == "" {
+			return
+		}
+		mergeGroup(cfg, config.Group{Name: group, Commands: []config.Command{{
+			Name:        nameFor(description, command),
+			Command:     convertTldrVars(command),
+			Description: description,
+		}}})
+		description = ""
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		trimmed := strings.TrimSpace(line)
+
+		if inFence {
+			if strings.HasPrefix(trimmed, "```") {
+				inFence = false
+				add(strings.Join(fence, "\n"))
+				fence = nil
+				continue
+			}
+			fence = append(fence, line)
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(trimmed, "```"):
+			inFence = true
+		case strings.HasPrefix(trimmed, "# "):
+			group = groupName(strings.TrimSpace(trimmed[2:]), group)
+			description = ""
+		case strings.HasPrefix(trimmed, "- "):
+			text := mnemonicRe.ReplaceAllString(strings.TrimSpace(trimmed[2:]), "$1")
+			description = strings.TrimSuffix(text, ":")
+		case len(trimmed) > 1 && strings.HasPrefix(trimmed, "`") && strings.HasSuffix(trimmed, "`"):
+			add(strings.Trim(trimmed, "`"))
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return cfg.Groups, nil
+}
+
+// convertTldrVars rewrites tldr placeholders as bkmk placeholders, turning
+// names like path/to/file into path_to_file.
+func convertTldrVars(command string) string {
+	return tldrVarRe.ReplaceAllStringFunc(command, func(match string) string {
+		return placeholder(tldrVarRe.FindStringSubmatch(match)[1], "", false)
+	})
+}
@@ -0,0 +1,81 @@
+package importer
+
+import (
+	"bufio"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/sammcj/bkmk/internal/config"
+)
+
+// naviVarRe matches navi variables such as <branch>.
+var naviVarRe = regexp.MustCompile(`<([A-Za-z_][\w-]*)>`)
+
+// naviImporter reads navi .cheat files. The first tag on a "%" line names the
+// group and all of them become tags; files without one use the file name. "$" variable generators and
+// "@" extends lines have no bkmk equivalent and are skipped.
+type naviImporter struct{}
+
+func (naviImporter) Name() string { return "navi" }
+
+func (naviImporter) Extensions() []string { return []string{".cheat"} }
+
+func (naviImporter) Parse(r io.Reader, source string) ([]config.Group, error) {
+	cfg := &config.Config{}
+	group := groupName(strings.TrimSuffix(filepath.Base(source), filepath.Ext(source)), "navi")
+
+	var description string
+	var command []string
+	var tags []string
+
+	flush := func() {
+		if len(command) > 0 {
+			cmd := strings.Join(command, "\n")
+			mergeGroup(cfg, config.Group{Name: group, Commands: []config.Command{{
+				Name:        nameFor(description, cmd),
+				Command:     naviVarRe.ReplaceAllString(cmd, "{{$1}}"),
+				Description: description,
+				Tags:        tags,
+			}}})
+		}
+		description = ""
+		command = nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		trimmed := strings.TrimSpace(line)
+
+		// Continuation of a multi-line command
+		if len(command) > 0 && strings.HasSuffix(command[len(command)-1], "\\") {
+			command = append(command, line)
+			continue
+		}
+
+		switch {
+		case trimmed == "":
+			flush()
+		case strings.HasPrefix(trimmed, "%"):
+			flush()
+			raw := strings.Split(strings.TrimSpace(trimmed[1:]), ",")
+			group = groupName(raw[0], group)
+			tags = tagsFrom(raw)
+		case strings.HasPrefix(trimmed, "#"):
+			flush()
+			description = strings.TrimSpace(trimmed[1:])
+		case strings.HasPrefix(trimmed, ";"), strings.HasPrefix(trimmed, "$"), strings.HasPrefix(trimmed, "@"):
+			// Comments, variable generators and extends
+		default:
+			command = append(command, line)
+		}
+	}
+	flush()
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return cfg.Groups, nil
+}
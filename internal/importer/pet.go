@@ -0,0 +1,75 @@
+package importer
+
+import (
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/sammcj/bkmk/internal/config"
+)
+
+// petVarRe matches pet variables: <name>, <name=default> and
+// <name=|_choice1_||_choice2_|>.
+var petVarRe = regexp.MustCompile(`<([A-Za-z_][\w-]*)(?:=([^<>]*))?>`)
+
+// petChoiceRe extracts the first choice from pet's |_a_||_b_| default syntax.
+var petChoiceRe = regexp.MustCompile(`^\|_(.*?)_\|`)
+
+type petFile struct {
+	Snippets []petSnippet `toml:"snippets"`
+}
+
+type petSnippet struct {
+	Description string   `toml:"description"`
+	Command     string   `toml:"command"`
+	Tag         []string `toml:"tag"`
+	Output      string   `toml:"output"`
+}
+
+// petImporter reads pet's snippet.toml. Each snippet goes in the group named
+// by its first tag, or "pet" if it has none, and keeps all its tags.
+type petImporter struct{}
+
+func (petImporter) Name() string { return "pet" }
+
+func (petImporter) Extensions() []string { return []string{".toml"} }
+
+func (petImporter) Parse(r io.Reader, _ string) ([]config.Group, error) {
+	var file petFile
+	if _, err := toml.NewDecoder(r).Decode(&file); err != nil {
+		return nil, err
+	}
+
+	cfg := &config.Config{}
+	for _, s := range file.Snippets {
+		command := strings.TrimSpace(s.Command)
+		if command == "" {
+			continue
+		}
+		group := "pet"
+		if len(s.Tag) > 0 {
+			group = groupName(s.Tag[0], group)
+		}
+		mergeGroup(cfg, config.Group{Name: group, Commands: []config.Command{{
+			Name:        nameFor(s.Description, command),
+			Command:     convertPetVars(command),
+			Description: strings.TrimSpace(s.Description),
+			Tags:        tagsFrom(s.Tag),
+		}}})
+	}
+	return cfg.Groups, nil
+}
+
+// convertPetVars rewrites pet variables as bkmk placeholders.
+func convertPetVars(command string) string {
+	return petVarRe.ReplaceAllStringFunc(command, func(match string) string {
+		m := petVarRe.FindStringSubmatch(match)
+		name, def := m[1], m[2]
+		hasDefault := strings.Contains(match, "=")
+		if c := petChoiceRe.FindStringSubmatch(def); c != nil {
+			def = c[1]
+		}
+		return placeholder(name, def, hasDefault)
+	})
+}
@@ -0,0 +1,194 @@
+package params
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// placeholderRe matches {{name}} and {{name:default}} placeholders.
+var placeholderRe = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_-]*)\s*(?::([^}]*))?\}\}`)
+
+// safeRe matches values that need no quoting in a POSIX shell.
+var safeRe = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// Param is a placeholder found in a command string.
+type Param struct {
+	Name       string
+	Default    string
+	HasDefault bool
+}
+
+// Parse returns the unique placeholders in command, in order of first appearance.
+// If a placeholder appears more than once, the first default given wins.
+// Surrounding whitespace in defaults is ignored.
+func Parse(command string) []Param {
+	var result []Param
+	seen := make(map[string]int)
+
+	for _, m := range placeholderRe.FindAllStringSubmatchIndex(command, -1) {
+		name := command[m[2]:m[3]]
+		hasDefault := m[4] != -1
+		var def string
+		if hasDefault {
+			def = strings.TrimSpace(command[m[4]:m[5]])
+		}
+
+		if idx, ok := seen[name]; ok {
+			if !result[idx].HasDefault && hasDefault {
+				result[idx].Default = def
+				result[idx].HasDefault = true
+			}
+			continue
+		}
+		seen[name] = len(result)
+		result = append(result, Param{Name: name, Default: def, HasDefault: hasDefault})
+	}
+
+	return result
+}
+
+// Has reports whether command contains any placeholders.
+func Has(command string) bool {
+	return placeholderRe.MatchString(command)
+}
+
+// Matches reports whether rendering template with some values for its
+// placeholders could give command. A template without placeholders matches
+// only itself.
+func Matches(template, command string) bool {
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	last := 0
+	for _, m := range placeholderRe.FindAllStringIndex(template, -1) {
+		pattern.WriteString(regexp.QuoteMeta(template[last:m[0]]))
+		pattern.WriteString("(.+)")
+		last = m[1]
+	}
+	pattern.WriteString(regexp.QuoteMeta(template[last:]))
+	pattern.WriteString("$")
+	return regexp.MustCompile(pattern.String()).MatchString(command)
+}
+
+// Render substitutes placeholders in command with shell-quoted values.
+// A value given for a placeholder is used even if empty; without one the
+// placeholder's default is used, and a placeholder with neither is an error.
+//
+// Values are quoted for where the placeholder sits: as a word of their own
+// outside quotes, escaped to stay within the quotes of a placeholder written
+// inside "..." or '...', so "{{msg}}" stays one double-quoted string.
+func Render(command string, values map[string]string) (string, error) {
+	defaults := make(map[string]Param)
+	for _, p := range Parse(command) {
+		defaults[p.Name] = p
+	}
+
+	var b strings.Builder
+	var missing []string
+	var quote byte // the quote the text so far leaves open, if any
+	last := 0
+	for _, m := range placeholderRe.FindAllStringSubmatchIndex(command, -1) {
+		literal := command[last:m[0]]
+		quote = scanQuotes(literal, quote)
+		b.WriteString(literal)
+		last = m[1]
+
+		name := command[m[2]:m[3]]
+		value, ok := values[name]
+		if !ok {
+			p := defaults[name]
+			if !p.HasDefault {
+				missing = append(missing, name)
+				b.WriteString(command[m[0]:m[1]])
+				continue
+			}
+			value = p.Default
+		}
+		b.WriteString(quoteIn(quote, value))
+	}
+	b.WriteString(command[last:])
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf("missing value for %s", strings.Join(dedupe(missing), ", "))
+	}
+	return b.String(), nil
+}
+
+// scanQuotes returns the quote left open at the end of text, given the one
+// open at its start: a single or double quote, or 0 for none.
+func scanQuotes(text string, quote byte) byte {
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			}
+		case c == '\\':
+			i++ // escapes the next character, in double quotes or none
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		}
+	}
+	return quote
+}
+
+// quoteIn quotes s for use inside the given open quote, or as a word of its
+// own outside quotes.
+func quoteIn(quote byte, s string) string {
+	switch quote {
+	case '\'':
+		return strings.ReplaceAll(s, "'", `'\''`)
+	case '"':
+		var b strings.Builder
+		for _, r := range s {
+			if strings.ContainsRune("\\\"$`", r) {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(r)
+		}
+		return b.String()
+	}
+	return Quote(s)
+}
+
+// Quote returns s quoted for safe use as a single POSIX shell word.
+// Values made only of safe characters are returned unchanged.
+func Quote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	if safeRe.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// ParseAssignments parses name=value pairs as given to --set.
+func ParseAssignments(pairs []string) (map[string]string, error) {
+	values := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid assignment %q (expected name=value)", pair)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+func dedupe(names []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, n := range names {
+		if !seen[n] {
+			seen[n] = true
+			out = append(out, n)
+		}
+	}
+	return out
+}
@@ -0,0 +1,138 @@
+package params
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	got := Parse("kubectl logs -n {{ns}} {{pod:web-0}} --tail {{ lines : 100 }} {{ns:default}}")
+
+	want := []Param{
+		{Name: "ns", Default: "default", HasDefault: true},
+		{Name: "pod", Default: "web-0", HasDefault: true},
+		{Name: "lines", Default: "100", HasDefault: true},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("Parse() returned %d params, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("param %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestParseNoPlaceholders(t *testing.T) {
+	if got := Parse("docker ps -a"); len(got) != 0 {
+		t.Errorf("expected no params, got %+v", got)
+	}
+	if Has("echo {{}}") {
+		t.Error("empty braces should not be a placeholder")
+	}
+	if !Has("echo {{name}}") {
+		t.Error("expected placeholder to be detected")
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		command string
+		values  map[string]string
+		want    string
+	}{
+		{"kubectl logs -n {{ns}} {{pod}}", map[string]string{"ns": "prod", "pod": "api-1"}, "kubectl logs -n prod api-1"},
+		{"echo {{msg}}", map[string]string{"msg": "hello world"}, "echo 'hello world'"},
+		{"echo {{msg}}", map[string]string{"msg": "it's; rm -rf /"}, `echo 'it'\''s; rm -rf /'`},
+		{"echo {{msg:hi there}}", nil, "echo 'hi there'"},
+		{"echo {{msg:}}", nil, "echo ''"},
+		{"echo {{a}} {{a}}", map[string]string{"a": "x"}, "echo x x"},
+		{"echo {{a:def}}", nil, "echo def"},
+		// An explicit empty value is passed as an empty argument
+		{"echo {{a:def}}", map[string]string{"a": ""}, "echo ''"},
+		// Inside quotes the value is escaped to stay within them
+		{`git commit -m "{{msg}}"`, map[string]string{"msg": "a b"}, `git commit -m "a b"`},
+		{`echo "{{msg}}"`, map[string]string{"msg": `say "hi" $HOME \ ` + "`id`"}, `echo "say \"hi\" \$HOME \\ \` + "`id\\`" + `"`},
+		{`echo "x: {{msg}}"`, map[string]string{"msg": ""}, `echo "x: "`},
+		{"echo '{{msg}}'", map[string]string{"msg": "it's $x"}, `echo 'it'\''s $x'`},
+		{`echo "it's" {{msg}}`, map[string]string{"msg": "a b"}, `echo "it's" 'a b'`},
+		{`echo \"{{msg}}`, map[string]string{"msg": "a b"}, `echo \"'a b'`},
+		{`echo "{{a}}" {{b}}`, map[string]string{"a": "1 2", "b": "3 4"}, `echo "1 2" '3 4'`},
+	}
+
+	for _, tt := range tests {
+		got, err := Render(tt.command, tt.values)
+		if err != nil {
+			t.Errorf("Render(%q) error: %v", tt.command, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Render(%q) = %q, want %q", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestRenderMissing(t *testing.T) {
+	_, err := Render("kubectl logs -n {{ns}} {{pod}} {{ns}}", map[string]string{})
+	if err == nil {
+		t.Fatal("expected error for missing values")
+	}
+	if !strings.Contains(err.Error(), "ns, pod") {
+		t.Errorf("expected missing names in error, got: %v", err)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	tests := map[string]string{
+		"":           "''",
+		"prod":       "prod",
+		"a/b.c-d":    "a/b.c-d",
+		"two words":  "'two words'",
+		"$HOME":      "'$HOME'",
+		"don't":      `'don'\''t'`,
+		"`whoami`":   "'`whoami`'",
+		"key=value,": "key=value,",
+	}
+	for in, want := range tests {
+		if got := Quote(in); got != want {
+			t.Errorf("Quote(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		template, command string
+		want              bool
+	}{
+		{"git log -n {{n:5}}", "git log -n 10", true},
+		{"git log -n {{n}}", "git log -n", false},
+		{"kubectl logs -n {{ns}} {{pod}}", "kubectl logs -n prod api-1", true},
+		{"git status", "git status", true},
+		{"git status", "git status -s", false},
+		{"echo {{msg}}.", "echo hi!", false},
+	}
+	for _, tt := range tests {
+		if got := Matches(tt.template, tt.command); got != tt.want {
+			t.Errorf("Matches(%q, %q) = %v, want %v", tt.template, tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestParseAssignments(t *testing.T) {
+	values, err := ParseAssignments([]string{"ns=prod", "query=a=b", "empty="})
+	if err != nil {
+		t.Fatalf("ParseAssignments failed: %v", err)
+	}
+	if values["ns"] != "prod" || values["query"] != "a=b" || values["empty"] != "" {
+		t.Errorf("unexpected values: %+v", values)
+	}
+
+	if _, err := ParseAssignments([]string{"noequals"}); err == nil {
+		t.Error("expected error for assignment without '='")
+	}
+	if _, err := ParseAssignments([]string{"=value"}); err == nil {
+		t.Error("expected error for assignment without name")
+	}
+}
@@ -0,0 +1,288 @@
+// Package runlog keeps a log of bookmark runs: what ran where and when, how
+// long it took, how it exited and, optionally, what it printed. Like usage
+// stats it lives in its own file next to the config, one JSON entry per line,
+// so recording a run only appends to it.
+package runlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sammcj/bkmk/internal/config"
+)
+
+// FileName is the run log, stored next to the config.
+const FileName = "runs.jsonl"
+
+// DefaultMaxOutput is how many bytes of output are kept per run unless the
+// config says otherwise.
+const DefaultMaxOutput = 16 * 1024
+
+// maxEntries is how many runs the log keeps; older ones are dropped once the
+// log grows past maxSize.
+const (
+	maxEntries = 1000
+	maxSize    = 4 << 20
+)
+
+// Entry is one run of a bookmark, or of one step of a workflow bookmark.
+type Entry struct {
+	ID        int           `json:"id"`
+	Name      string        `json:"name"`
+	Step      string        `json:"step,omitempty"` // the step, for a run of a workflow step
+	Command   string        `json:"command"`        // as run, with placeholders filled in
+	Dir       string        `json:"dir,omitempty"`
+	Started   time.Time     `json:"started"`
+	Duration  time.Duration `json:"duration"`
+	Exit      int           `json:"exit"`
+	Output    string        `json:"output,omitempty"`
+	Truncated bool          `json:"truncated,omitempty"` // Output is only the end of it
+}
+
+// Failed reports whether the run exited non-zero.
+func (e Entry) Failed() bool {
+	return e.Exit != 0
+}
+
+// Label names the run: the bookmark, and the step for a workflow step.
+func (e Entry) Label() string {
+	if e.Step != "" {
+		return e.Name + " › " + e.Step
+	}
+	return e.Name
+}
+
+// WorkDir returns the directory a command runs in: dir, or bkmk's own if
+// dir is empty.
+func WorkDir(dir string) string {
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	return dir
+}
+
+// Log holds the runs recorded so far, oldest first.
+type Log struct {
+	path    string
+	entries []Entry
+	skipped int
+	// MaxOutput is how many bytes of output Append keeps, from the end.
+	MaxOutput int
+}
+
+// DefaultPath returns the log path in the directory of the config in use.
+func DefaultPath() (string, error) {
+	cfgPath, err := config.Path()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(cfgPath), FileName), nil
+}
+
+// Load reads the log from the default location.
+func Load() (*Log, error) {
+	path, err := DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	return LoadFrom(path)
+}
+
+// LoadFrom reads the log at path. A missing file is an empty log. Lines that
+// cannot be parsed, such as one cut short by a crash mid-write, are skipped
+// and counted by Skipped.
+func LoadFrom(path string) (*Log, error) {
+	l := &Log{path: path, MaxOutput: DefaultMaxOutput}
+	entries, skipped, err := readEntries(path)
+	if err != nil {
+		return nil, err
+	}
+	l.entries, l.skipped = entries, skipped
+	return l, nil
+}
+
+// readEntries reads the runs in the log at path, and how many lines were
+// skipped as unreadable.
+func readEntries(path string) ([]Entry, int, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	skipped := 0
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 16<<20)
+	for scanner.Scan() {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			skipped++
+			continue
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to read run log %s: %w", path, err)
+	}
+	return entries, skipped, nil
+}
+
+// Skipped returns how many lines of the log could not be read when it was
+// loaded.
+func (l *Log) Skipped() int {
+	return l.skipped
+}
+
+// Path returns the file the log is kept in.
+func (l *Log) Path() string {
+	return l.path
+}
+
+// Entries returns every run in the log, oldest first.
+func (l *Log) Entries() []Entry {
+	return l.entries
+}
+
+// Last returns the most recent run of the bookmark with the given ID.
+func (l *Log) Last(id int) (Entry, bool) {
+	for i := len(l.entries) - 1; i >= 0; i-- {
+		if l.entries[i].ID == id {
+			return l.entries[i], true
+		}
+	}
+	return Entry{}, false
+}
+
+// Append records a run, keeping only the end of its output, and drops the
+// oldest runs once the log has grown too large. It holds the log's lock, so
+// runs recorded by several bkmk processes at once are all kept.
+func (l *Log) Append(e Entry) error {
+	e.Output, e.Truncated = Tail(e.Output, l.MaxOutput)
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	unlock, err := config.Lock(l.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0o600)
+	if err != nil {
+		return err
+	}
+	if endsMidLine(f) {
+		// Start afresh after a line cut short, rather than run on from it
+		data = append([]byte{'\n'}, data...)
+	}
+	_, err = f.Write(append(data, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	l.entries = append(l.entries, e)
+
+	if info, err := os.Stat(l.path); err == nil && info.Size() > maxSize {
+		return l.compact()
+	}
+	return nil
+}
+
+// endsMidLine reports whether f is not empty and does not end with a newline.
+func endsMidLine(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil || info.Size() == 0 {
+		return false
+	}
+	last := make([]byte, 1)
+	_, err = f.ReadAt(last, info.Size()-1)
+	return err == nil && last[0] != '\n'
+}
+
+// compact rewrites the log with only its most recent runs, if it has more
+// than maxEntries. The file is read again first, since other bkmk processes
+// may have added runs since this one loaded it; the caller holds the lock, so
+// none are added meanwhile.
+func (l *Log) compact() error {
+	entries, _, err := readEntries(l.path)
+	if err != nil {
+		return err
+	}
+	if len(entries) <= maxEntries {
+		l.entries = entries
+		return nil
+	}
+	entries = entries[len(entries)-maxEntries:]
+	var b strings.Builder
+	for _, e := range entries {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		b.Write(data)
+		b.WriteByte('\n')
+	}
+	if err := config.WriteFileAtomic(l.path, []byte(b.String()), 0o600); err != nil {
+		return err
+	}
+	l.entries = entries
+	return nil
+}
+
+// Tail returns at most limit bytes from the end of output, starting on a
+// whole line where it can, and whether anything was cut.
+func Tail(output string, limit int) (string, bool) {
+	if len(output) <= limit {
+		return output, false
+	}
+	if limit <= 0 {
+		return "", output != ""
+	}
+	tail := output[len(output)-limit:]
+	if i := strings.IndexByte(tail, '\n'); i >= 0 && i < len(tail)-1 {
+		tail = tail[i+1:]
+	}
+	return tail, true
+}
+
+// TailWriter collects the end of a command's output as it is written, keeping
+// at most twice the limit in memory.
+type TailWriter struct {
+	limit int
+	buf   []byte
+}
+
+// NewTailWriter returns a TailWriter for output that Tail will cut to limit.
+func NewTailWriter(limit int) *TailWriter {
+	return &TailWriter{limit: limit}
+}
+
+func (t *TailWriter) Write(p []byte) (int, error) {
+	t.buf = append(t.buf, p...)
+	if over := len(t.buf) - 2*t.limit; over > 0 {
+		t.buf = t.buf[over:]
+	}
+	return len(p), nil
+}
+
+// String returns the output written so far.
+func (t *TailWriter) String() string {
+	return string(t.buf)
+}
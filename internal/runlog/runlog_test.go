@@ -0,0 +1,183 @@
+package runlog
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppendAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", FileName)
+	l, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom() on a missing file: %v", err)
+	}
+	if len(l.Entries()) != 0 {
+		t.Fatalf("expected an empty log, got %d entries", len(l.Entries()))
+	}
+
+	started := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	runs := []Entry{
+		{ID: 1, Name: "build", Command: "make", Dir: "/src", Started: started, Duration: time.Second},
+		{ID: 2, Name: "test", Command: "make test", Started: started.Add(time.Minute), Exit: 2, Output: "FAIL\n"},
+		{ID: 1, Name: "build", Command: "make", Started: started.Add(2 * time.Minute), Exit: 1},
+	}
+	for _, run := range runs {
+		if err := l.Append(run); err != nil {
+			t.Fatalf("Append() error: %v", err)
+		}
+	}
+
+	loaded, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom() error: %v", err)
+	}
+	entries := loaded.Entries()
+	if len(entries) != 3 || entries[1].Output != "FAIL\n" || entries[0].Dir != "/src" || !entries[0].Started.Equal(started) {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+	last, ok := loaded.Last(1)
+	if !ok || last.Exit != 1 || !last.Failed() {
+		t.Errorf("Last(1) = %+v, %v; want the failed second run", last, ok)
+	}
+	if _, ok := loaded.Last(9); ok {
+		t.Error("expected no run for an unknown ID")
+	}
+}
+
+func TestLoadSkipsTornLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	good := `{"id":1,"name":"build","command":"make","started":"2026-03-01T12:00:00Z","duration":0,"exit":0}`
+	if err := os.WriteFile(path, []byte(good+"\nnot json\n"+`{"id":2,"name":"te`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom() error: %v", err)
+	}
+	if len(l.Entries()) != 1 || l.Skipped() != 2 {
+		t.Fatalf("expected 1 entry and 2 skipped lines, got %+v and %d", l.Entries(), l.Skipped())
+	}
+
+	// A run recorded after the torn line is not lost with it
+	if err := l.Append(Entry{ID: 3, Name: "deploy", Command: "make deploy"}); err != nil {
+		t.Fatalf("Append() error: %v", err)
+	}
+	loaded, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom() error: %v", err)
+	}
+	if last, ok := loaded.Last(3); !ok || last.Name != "deploy" || loaded.Skipped() != 2 {
+		t.Errorf("expected the new run after the torn line, got %+v (%d skipped)", loaded.Entries(), loaded.Skipped())
+	}
+}
+
+func TestAppendKeepsEndOfOutput(t *testing.T) {
+	l, _ := LoadFrom(filepath.Join(t.TempDir(), FileName))
+	l.MaxOutput = 10
+	if err := l.Append(Entry{ID: 1, Output: "first line\nsecond\nend\n"}); err != nil {
+		t.Fatal(err)
+	}
+	got := l.Entries()[0]
+	if got.Output != "end\n" || !got.Truncated {
+		t.Errorf("output = %q, truncated %v; want the last whole line", got.Output, got.Truncated)
+	}
+}
+
+func TestTail(t *testing.T) {
+	tests := []struct {
+		output    string
+		limit     int
+		want      string
+		truncated bool
+	}{
+		{"short\n", 100, "short\n", false},
+		{"one\ntwo\nthree\n", 9, "three\n", true},
+		{"abcdefghij", 4, "ghij", true}, // no line break to start on
+		{"anything", 0, "", true},
+		{"", 0, "", false},
+	}
+	for _, tt := range tests {
+		got, truncated := Tail(tt.output, tt.limit)
+		if got != tt.want || truncated != tt.truncated {
+			t.Errorf("Tail(%q, %d) = %q, %v; want %q, %v", tt.output, tt.limit, got, truncated, tt.want, tt.truncated)
+		}
+	}
+}
+
+func TestTailWriter(t *testing.T) {
+	w := NewTailWriter(8)
+	for i := range 10 {
+		fmt.Fprintf(w, "line %d\n", i)
+	}
+	if got := w.String(); len(got) > 16 || !strings.HasSuffix(got, "line 9\n") {
+		t.Errorf("expected at most twice the limit ending with the last line, got %q", got)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, FileName)
+	l, _ := LoadFrom(path)
+	other, _ := LoadFrom(path) // another bkmk process, such as the CLI beside the TUI
+	l.MaxOutput = maxSize
+	big := strings.Repeat("x", maxSize/maxEntries+1)
+	for i := range maxEntries + 1 {
+		if err := l.Append(Entry{ID: i, Output: big}); err != nil {
+			t.Fatal(err)
+		}
+		if i == maxEntries/2 {
+			if err := other.Append(Entry{ID: -1, Name: "from elsewhere"}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if n := len(l.Entries()); n != maxEntries {
+		t.Fatalf("expected %d entries after compacting, got %d", maxEntries, n)
+	}
+	loaded, err := LoadFrom(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries := loaded.Entries(); len(entries) != maxEntries || entries[0].ID != 2 {
+		t.Errorf("expected the two oldest runs to be dropped, got %d entries from ID %d", len(entries), entries[0].ID)
+	}
+	if _, ok := loaded.Last(-1); !ok {
+		t.Error("compacting must keep runs another process recorded since loading")
+	}
+
+	files, _ := os.ReadDir(dir)
+	for _, f := range files {
+		if name := f.Name(); name != FileName && name != FileName+".lock" {
+			t.Errorf("unexpected file left behind: %s", name)
+		}
+	}
+}
+
+func TestCompactCountsRunsOnDisk(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	l, _ := LoadFrom(path)
+	other, _ := LoadFrom(path) // loaded while the log was still empty
+	l.MaxOutput = maxSize
+	big := strings.Repeat("x", maxSize/maxEntries+1)
+	for i := range maxEntries {
+		if err := l.Append(Entry{ID: i, Output: big}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := other.Append(Entry{ID: -1, Name: "from elsewhere"}); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadFrom(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries := loaded.Entries(); len(entries) != maxEntries || entries[0].ID != 1 {
+		t.Errorf("expected the oldest run to be dropped, got %d entries from ID %d", len(entries), entries[0].ID)
+	}
+}
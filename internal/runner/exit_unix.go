@@ -0,0 +1,18 @@
+//go:build unix
+
+package runner
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// signalExitCode returns 128 plus the signal that killed the command, as a
+// shell reports it, or false if it was not killed by a signal.
+func signalExitCode(exitErr *exec.ExitError) (int, bool) {
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return 0, false
+	}
+	return 128 + int(status.Signal()), true
+}
@@ -0,0 +1,116 @@
+package runner
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// maxBatch is how many lines of output ReadLines returns at most.
+const maxBatch = 256
+
+// drainDelay is how long output is still read once the command has exited.
+// Anything it left running in the background may hold the output open for
+// much longer, so after that the output is taken to have ended.
+const drainDelay = 200 * time.Millisecond
+
+// Process is a command started in the background, with its standard output
+// and error merged and read line by line so they can be shown as it runs.
+type Process struct {
+	Started time.Time
+	cmd     *exec.Cmd
+	lines   chan string
+	read    chan struct{} // closed when the output has ended
+	exited  chan struct{} // closed when the command has exited
+	err     error         // from waiting for the command, once exited
+}
+
+// Start runs command in the user's shell like RunCommandWith, but without
+// waiting for it and with no input. The command runs in a process group of
+// its own where supported, so Interrupt reaches everything it starts.
+func Start(command string, opts Options) (*Process, error) {
+	cmd := ShellCommandWith(command, opts)
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stdout = w
+	cmd.Stderr = w
+	newProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		r.Close()
+		w.Close()
+		return nil, err
+	}
+	w.Close() // the child holds its own copy
+
+	p := &Process{
+		Started: time.Now(),
+		cmd:     cmd,
+		lines:   make(chan string, maxBatch),
+		read:    make(chan struct{}),
+		exited:  make(chan struct{}),
+	}
+	go p.readOutput(r)
+	go p.wait(r)
+	return p, nil
+}
+
+// wait waits for the command to exit, then stops reading its output if that
+// has not ended within drainDelay.
+func (p *Process) wait(r *os.File) {
+	p.err = p.cmd.Wait()
+	close(p.exited)
+	select {
+	case <-p.read:
+	case <-time.After(drainDelay):
+		r.Close()
+	}
+}
+
+func (p *Process) readOutput(r *os.File) {
+	defer close(p.lines)
+	defer close(p.read)
+	defer r.Close()
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			p.lines <- strings.TrimRight(line, "\r\n")
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// ReadLines waits for output and returns the lines read so far, or false
+// once the output has ended: at most drainDelay after the command exits.
+func (p *Process) ReadLines() ([]string, bool) {
+	line, ok := <-p.lines
+	if !ok {
+		return nil, false
+	}
+	lines := []string{line}
+	for len(lines) < maxBatch {
+		select {
+		case line, ok := <-p.lines:
+			if !ok {
+				return lines, true
+			}
+			lines = append(lines, line)
+		default:
+			return lines, true
+		}
+	}
+	return lines, true
+}
+
+// Wait waits for the command to exit, returning its error as RunCommand does.
+// It does not wait for anything the command left running in the background.
+func (p *Process) Wait() error {
+	<-p.exited
+	return p.err
+}
@@ -0,0 +1,18 @@
+//go:build !unix
+
+package runner
+
+import "os/exec"
+
+func newProcessGroup(*exec.Cmd) {}
+
+// Interrupt stops the command. Without process groups and signals it can
+// only be killed.
+func (p *Process) Interrupt() error {
+	return p.cmd.Process.Kill()
+}
+
+// Kill stops the command.
+func (p *Process) Kill() error {
+	return p.cmd.Process.Kill()
+}
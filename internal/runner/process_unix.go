@@ -0,0 +1,23 @@
+//go:build unix
+
+package runner
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+func newProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// Interrupt sends SIGINT to the command and everything it started, as
+// Ctrl+C would in a terminal.
+func (p *Process) Interrupt() error {
+	return syscall.Kill(-p.cmd.Process.Pid, syscall.SIGINT)
+}
+
+// Kill stops the command and everything it started.
+func (p *Process) Kill() error {
+	return syscall.Kill(-p.cmd.Process.Pid, syscall.SIGKILL)
+}
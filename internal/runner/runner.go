@@ -1,10 +1,13 @@
 package runner
 
 import (
+	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"os/exec"
 	"runtime"
+	"slices"
 	"strings"
 )
 
@@ -32,21 +35,71 @@ func CopyToClipboard(text string) error {
 	return cmd.Run()
 }
 
-// RunCommand executes the given command in the user's shell
-func RunCommand(command string) error {
+// Options say where a command runs.
+type Options struct {
+	Dir string            // working directory, or bkmk's own if empty
+	Env map[string]string // added to bkmk's environment
+}
+
+// ShellCommand returns the command ready to run in the user's shell, with no
+// standard streams attached.
+func ShellCommand(command string) *exec.Cmd {
+	return ShellCommandWith(command, Options{})
+}
+
+// ShellCommandWith is ShellCommand run in the directory and with the
+// environment given by opts.
+func ShellCommandWith(command string, opts Options) *exec.Cmd {
 	shell := os.Getenv("SHELL")
 	if shell == "" {
 		shell = "/bin/sh"
 	}
-
 	cmd := exec.Command(shell, "-c", command)
+	cmd.Dir = opts.Dir
+	if len(opts.Env) > 0 {
+		cmd.Env = os.Environ()
+		names := slices.Sorted(maps.Keys(opts.Env))
+		for _, name := range names {
+			cmd.Env = append(cmd.Env, name+"="+opts.Env[name])
+		}
+	}
+	return cmd
+}
+
+// RunCommand executes the given command in the user's shell
+func RunCommand(command string) error {
+	return RunCommandWith(command, Options{})
+}
+
+// RunCommandWith executes the command in the user's shell, in the directory
+// and with the environment given by opts.
+func RunCommandWith(command string, opts Options) error {
+	cmd := ShellCommandWith(command, opts)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
-
 	return cmd.Run()
 }
 
+// ExitCode returns the exit status to propagate for an error returned by
+// RunCommand: 0 for nil, the child's status if it exited non-zero, 128 plus
+// the signal if one killed it, otherwise 1.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if exitErr.ExitCode() > 0 {
+			return exitErr.ExitCode()
+		}
+		if code, ok := signalExitCode(exitErr); ok {
+			return code
+		}
+	}
+	return 1
+}
+
 // OpenInEditor opens the given path in the specified editor.
 // If configuredEditor is empty, falls back to $EDITOR env var, then to "vi".
 // Returns an exec.Cmd ready to be executed (caller handles stdin/stdout).
@@ -1,9 +1,13 @@
 package runner
 
 import (
+	"errors"
 	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestRunCommand(t *testing.T) {
@@ -49,6 +53,23 @@ func TestRunCommandFailure(t *testing.T) {
 	}
 }
 
+func TestExitCode(t *testing.T) {
+	if code := ExitCode(nil); code != 0 {
+		t.Errorf("ExitCode(nil) = %d, want 0", code)
+	}
+	if code := ExitCode(RunCommand("exit 3")); code != 3 {
+		t.Errorf("ExitCode(exit 3) = %d, want 3", code)
+	}
+	if code := ExitCode(errors.New("boom")); code != 1 {
+		t.Errorf("ExitCode(generic error) = %d, want 1", code)
+	}
+	if runtime.GOOS != "windows" {
+		if code := ExitCode(RunCommand("kill -TERM $$")); code != 143 {
+			t.Errorf("ExitCode(killed by SIGTERM) = %d, want 143", code)
+		}
+	}
+}
+
 func TestRunCommandUsesShell(t *testing.T) {
 	// Test that the command uses shell features (pipes, etc.)
 	tmpFile, err := os.CreateTemp("", "bkmk-test-*")
@@ -155,3 +176,77 @@ func TestOpenInEditorWithSpaces(t *testing.T) {
 		t.Errorf("Expected quoted path in command, got %q", cmdStr)
 	}
 }
+
+func TestRunCommandWith(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+
+	err := RunCommandWith(`printf '%s %s' "$PWD" "$GREETING" > out`, Options{Dir: dir, Env: map[string]string{"GREETING": "hello"}})
+	if err != nil {
+		t.Fatalf("RunCommandWith failed: %v", err)
+	}
+	content, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	realDir, _ := filepath.EvalSymlinks(dir)
+	if got := string(content); got != dir+" hello" && got != realDir+" hello" {
+		t.Errorf("expected the command to run in %s with GREETING set, got %q", dir, got)
+	}
+}
+
+func TestStart(t *testing.T) {
+	p, err := Start("echo one; echo two >&2; exit 3", Options{})
+	if err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	var output []string
+	for lines, ok := p.ReadLines(); ok; lines, ok = p.ReadLines() {
+		output = append(output, lines...)
+	}
+	if strings.Join(output, ",") != "one,two" {
+		t.Errorf("output = %q, want one and two", output)
+	}
+	if code := ExitCode(p.Wait()); code != 3 {
+		t.Errorf("exit code = %d, want 3", code)
+	}
+}
+
+func TestStartInterrupt(t *testing.T) {
+	p, err := Start("echo ready; sleep 10", Options{})
+	if err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	if lines, ok := p.ReadLines(); !ok || lines[0] != "ready" {
+		t.Fatalf("expected the first line before interrupting, got %q", lines)
+	}
+	if err := p.Interrupt(); err != nil {
+		t.Fatalf("Interrupt() error: %v", err)
+	}
+	for _, ok := p.ReadLines(); ok; _, ok = p.ReadLines() {
+	}
+	if err := p.Wait(); err == nil {
+		t.Error("expected an error from an interrupted command")
+	}
+}
+
+func TestStartEndsWithoutBackgroundChildren(t *testing.T) {
+	start := time.Now()
+	p, err := Start("sleep 5 & echo started", Options{})
+	if err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	var output []string
+	for lines, ok := p.ReadLines(); ok; lines, ok = p.ReadLines() {
+		output = append(output, lines...)
+	}
+	if err := p.Wait(); err != nil {
+		t.Errorf("Wait() error: %v", err)
+	}
+	if strings.Join(output, ",") != "started" {
+		t.Errorf("output = %q, want started", output)
+	}
+	if took := time.Since(start); took > 2*time.Second {
+		t.Errorf("output should end once the shell exits, took %v", took)
+	}
+}
@@ -0,0 +1,115 @@
+package shell
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// zshScript defines a ZLE widget that inserts the selected bookmark at the
+// cursor. {{key}} is replaced by the key that opens the picker.
+const zshScript = `# bkmk shell integration for zsh
+# Add to ~/.zshrc: eval "$(bkmk init zsh)"
+bkmk-widget() {
+  local selected
+  selected="$(bkmk select < /dev/tty)"
+  local ret=$?
+  if [[ -n "$selected" ]]; then
+    LBUFFER="${LBUFFER}${selected}"
+  fi
+  zle reset-prompt
+  return $ret
+}
+zle -N bkmk-widget
+bindkey -M emacs '{{key}}' bkmk-widget
+bindkey -M viins '{{key}}' bkmk-widget
+`
+
+// bashScript binds a readline function that splices the selection into READLINE_LINE.
+const bashScript = `# bkmk shell integration for bash
+# Add to ~/.bashrc: eval "$(bkmk init bash)"
+__bkmk_widget() {
+  local selected
+  selected="$(bkmk select < /dev/tty)" || return
+  READLINE_LINE="${READLINE_LINE:0:$READLINE_POINT}${selected}${READLINE_LINE:$READLINE_POINT}"
+  READLINE_POINT=$(( READLINE_POINT + ${#selected} ))
+}
+bind -m emacs-standard -x '"{{key}}": __bkmk_widget'
+bind -m vi-insert -x '"{{key}}": __bkmk_widget'
+`
+
+// fishScript binds a function that inserts the selection with commandline -i.
+const fishScript = `# bkmk shell integration for fish
+# Add to ~/.config/fish/config.fish: bkmk init fish | source
+function bkmk_widget
+  set -l selected (bkmk select < /dev/tty | string collect)
+  if test -n "$selected"
+    commandline -i -- $selected
+  end
+  commandline -f repaint
+end
+bind {{key}} bkmk_widget
+if bind -M insert >/dev/null 2>&1
+  bind -M insert {{key}} bkmk_widget
+end
+`
+
+var scripts = map[string]string{
+	"zsh":  zshScript,
+	"bash": bashScript,
+	"fish": fishScript,
+}
+
+// DefaultKey opens the picker unless another key is asked for. In emacs mode
+// it takes the place of backward-char, which the left arrow also does.
+const DefaultKey = "ctrl-b"
+
+// Script returns the integration script for the named shell, bound to
+// DefaultKey.
+func Script(name string) (string, error) {
+	return ScriptWithKey(name, DefaultKey)
+}
+
+// ScriptWithKey returns the integration script for the named shell, bound to
+// key: "ctrl-" or "alt-" followed by a letter.
+func ScriptWithKey(name, key string) (string, error) {
+	script, ok := scripts[name]
+	if !ok {
+		return "", fmt.Errorf("unsupported shell %q (supported: %s)", name, strings.Join(Supported(), ", "))
+	}
+	seq, err := keySequence(name, key)
+	if err != nil {
+		return "", err
+	}
+	return strings.ReplaceAll(script, "{{key}}", seq), nil
+}
+
+// keySequence spells key the way the named shell's bind command expects.
+func keySequence(shell, key string) (string, error) {
+	mod, letter, _ := strings.Cut(strings.ToLower(key), "-")
+	if len(letter) != 1 || letter[0] < 'a' || letter[0] > 'z' || (mod != "ctrl" && mod != "alt") {
+		return "", fmt.Errorf("invalid key %q (use ctrl-<letter> or alt-<letter>)", key)
+	}
+	switch {
+	case shell == "zsh" && mod == "ctrl":
+		return "^" + strings.ToUpper(letter), nil
+	case shell == "zsh":
+		return "^[" + letter, nil
+	case shell == "bash" && mod == "ctrl":
+		return `\C-` + letter, nil
+	case mod == "ctrl": // fish
+		return `\c` + letter, nil
+	default: // bash and fish
+		return `\e` + letter, nil
+	}
+}
+
+// Supported returns the names of shells with an integration script.
+func Supported() []string {
+	names := make([]string, 0, len(scripts))
+	for name := range scripts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,59 @@
+package shell
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScript(t *testing.T) {
+	for _, name := range []string{"zsh", "bash", "fish"} {
+		script, err := Script(name)
+		if err != nil {
+			t.Fatalf("Script(%q) failed: %v", name, err)
+		}
+		if !strings.Contains(script, "bkmk select <") {
+			t.Errorf("%s script should call 'bkmk select'", name)
+		}
+		if !strings.Contains(script, "/dev/tty") {
+			t.Errorf("%s script should attach the TUI to /dev/tty", name)
+		}
+	}
+}
+
+func TestScriptUnsupported(t *testing.T) {
+	_, err := Script("tcsh")
+	if err == nil {
+		t.Fatal("expected error for unsupported shell")
+	}
+	if !strings.Contains(err.Error(), "zsh") {
+		t.Errorf("expected supported shells in error, got: %v", err)
+	}
+}
+
+func TestScriptWithKey(t *testing.T) {
+	tests := []struct {
+		shell, key, want string
+	}{
+		{"zsh", "ctrl-b", "bindkey -M emacs '^B' bkmk-widget"},
+		{"zsh", "alt-k", "bindkey -M viins '^[k' bkmk-widget"},
+		{"bash", "ctrl-b", `bind -m emacs-standard -x '"\C-b": __bkmk_widget'`},
+		{"bash", "Alt-K", `bind -m vi-insert -x '"\ek": __bkmk_widget'`},
+		{"fish", "ctrl-g", `bind \cg bkmk_widget`},
+		{"fish", "alt-k", `bind -M insert \ek bkmk_widget`},
+	}
+	for _, tt := range tests {
+		script, err := ScriptWithKey(tt.shell, tt.key)
+		if err != nil {
+			t.Fatalf("ScriptWithKey(%q, %q) failed: %v", tt.shell, tt.key, err)
+		}
+		if !strings.Contains(script, tt.want) {
+			t.Errorf("ScriptWithKey(%q, %q) should contain %s, got:\n%s", tt.shell, tt.key, tt.want, script)
+		}
+	}
+
+	for _, key := range []string{"b", "ctrl-", "ctrl-bb", "shift-b", "ctrl-1"} {
+		if _, err := ScriptWithKey("zsh", key); err == nil {
+			t.Errorf("expected an error for key %q", key)
+		}
+	}
+}
@@ -1,10 +1,18 @@
 package tui
 
 import (
+	"slices"
+	"strings"
+	"time"
+
 	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/sammcj/bkmk/internal/config"
+	"github.com/sammcj/bkmk/internal/params"
+	"github.com/sammcj/bkmk/internal/runlog"
 	"github.com/sammcj/bkmk/internal/runner"
+	"github.com/sammcj/bkmk/internal/workflow"
 )
 
 func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
@@ -28,8 +36,19 @@ func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.handleHistoryAddDetailsKey(msg)
 	case viewActionSelect:
 		return m.handleActionSelectKey(msg)
+	case viewParamInput:
+		return m.handleParamInputKey(msg)
+	case viewRestoreConfirm:
+		return m.handleRestoreConfirmKey(msg)
+	case viewSuggest:
+		return m.handleSuggestKey(msg)
+	case viewWorkflow:
+		return m.handleWorkflowKey(msg)
+	case viewOutput:
+		return m.handleOutputKey(msg)
 	}
 
+	m.notice = ""
 	switch msg.String() {
 	case "ctrl+c":
 		m.quitting = true
@@ -53,11 +72,28 @@ func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.searchInput.SetValue("")
 			m.cursor = 0
 		case viewCommands:
-			m.mode = viewGroups
-			m.cursor = m.selectedGroup
+			m.leaveGroup()
 		case viewAllCommands:
 			m.mode = m.previousMode
 			m.cursor = 0
+		case viewTags:
+			m.mode = m.tagReturnMode
+			m.cursor = 0
+		case viewUsage:
+			m.mode = m.usageReturnMode
+			m.cursor = 0
+		case viewBackups:
+			m.mode = m.backupReturnMode
+			m.cursor = 0
+		case viewRuns:
+			m.mode = m.runsReturnMode
+			m.cursor = 0
+		case viewTagCommands:
+			m.mode = viewTags
+			m.cursor = slices.IndexFunc(m.tags, func(t config.TagCount) bool {
+				return t.Name == m.selectedTag
+			})
+			m.cursor = max(0, m.cursor)
 		}
 		return m, nil
 
@@ -86,7 +122,7 @@ func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 	case "o":
 		if m.mode == viewGroups || m.mode == viewCommands {
-			configPath, err := config.DefaultPath()
+			configPath, err := config.Path()
 			if err != nil {
 				return m, nil
 			}
@@ -95,7 +131,7 @@ func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				return m, nil
 			}
 			return m, tea.ExecProcess(editorCmd, func(err error) tea.Msg {
-				return nil
+				return editorClosedMsg{}
 			})
 		}
 
@@ -112,12 +148,69 @@ func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
+	case "t":
+		if m.mode == viewGroups || m.mode == viewCommands || m.mode == viewAllCommands {
+			m.tagReturnMode = m.mode
+			m.mode = viewTags
+			m.tags = m.config.Tags()
+			m.cursor = 0
+			return m, nil
+		}
+		if m.mode == viewTags {
+			m.mode = m.tagReturnMode
+			m.cursor = 0
+			return m, nil
+		}
+
+	case "u":
+		if m.mode == viewGroups || m.mode == viewCommands || m.mode == viewAllCommands {
+			m.usageReturnMode = m.mode
+			m.mode = viewUsage
+			m.loadUsageCommands()
+			m.cursor = 0
+			return m, nil
+		}
+		if m.mode == viewUsage {
+			m.mode = m.usageReturnMode
+			m.cursor = 0
+			return m, nil
+		}
+
+	case "L":
+		if m.mode == viewGroups || m.mode == viewCommands || m.mode == viewAllCommands {
+			m.runsReturnMode = m.mode
+			m.mode = viewRuns
+			m.loadRuns()
+			m.cursor = 0
+			return m, nil
+		}
+		if m.mode == viewRuns {
+			m.mode = m.runsReturnMode
+			m.cursor = 0
+			return m, nil
+		}
+
+	case "r":
+		if m.mode == viewRuns && m.cursor < len(m.runs) {
+			return m.rerun(m.runs[m.cursor])
+		}
+
+	case "b":
+		if m.mode == viewGroups || m.mode == viewCommands {
+			m.backupReturnMode = m.mode
+			m.mode = viewBackups
+			m.loadBackups()
+			m.cursor = 0
+			return m, nil
+		}
+
 	case "a":
 		if m.mode == viewGroups {
 			m.previousMode = viewGroups
 			m.mode = viewAddGroup
+			m.addGroupParent = ""
 			m.createFormInputs(
-				[]string{"Group name"},
+				[]string{"Group name (use / to nest, e.g. cloud/aws)"},
 				[]string{},
 			)
 			return m, textinput.Blink
@@ -126,7 +219,19 @@ func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.previousMode = viewCommands
 			m.mode = viewAddCommand
 			m.createFormInputs(
-				[]string{"Command name", "Command to run", "Description (optional)"},
+				[]string{"Command name", "Command to run", "Description (optional)", "Tags, comma separated (optional)"},
+				[]string{},
+			)
+			return m, textinput.Blink
+		}
+
+	case "A":
+		if m.mode == viewCommands {
+			m.previousMode = viewCommands
+			m.mode = viewAddGroup
+			m.addGroupParent = m.groupPath
+			m.createFormInputs(
+				[]string{"Subgroup name"},
 				[]string{},
 			)
 			return m, textinput.Blink
@@ -143,15 +248,32 @@ func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			)
 			return m, textinput.Blink
 		}
-		if m.mode == viewCommands && len(m.commands) > 0 && m.cursor < len(m.commands) {
-			cmd := m.commands[m.cursor]
+		if sub, ok := m.cursorSubgroup(); ok && m.mode == viewCommands {
+			m.editingGroup = config.JoinPath(m.groupPath, sub.Name)
+			m.previousMode = viewCommands
+			m.mode = viewEditGroup
+			m.createFormInputs(
+				[]string{"Group name"},
+				[]string{sub.Name},
+			)
+			return m, textinput.Blink
+		}
+		if cmd, ok := m.cursorCommand(); ok && m.mode == viewCommands {
+			if cmd.ReadOnly() {
+				m.notice = "Read-only: included from " + cmd.Source
+				return m, nil
+			}
+			if cmd.IsWorkflow() {
+				m.notice = "Workflows are edited in the config file (press o)"
+				return m, nil
+			}
 			m.editingCmd = &cmd
-			m.editingCmdIdx = m.cursor
+			m.editingCmdIdx = m.cursor - len(m.subgroups)
 			m.previousMode = viewCommands
 			m.mode = viewEditCommand
 			m.createFormInputs(
-				[]string{"Command name", "Command to run", "Description (optional)"},
-				[]string{cmd.Name, cmd.Command, cmd.Description},
+				[]string{"Command name", "Command to run", "Description (optional)", "Tags, comma separated (optional)"},
+				[]string{cmd.Name, singleLine(cmd.Command), cmd.Description, strings.Join(cmd.Tags, ", ")},
 			)
 			return m, textinput.Blink
 		}
@@ -164,10 +286,21 @@ func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.mode = viewDeleteConfirm
 			return m, nil
 		}
-		if m.mode == viewCommands && len(m.commands) > 0 && m.cursor < len(m.commands) && m.selectedGroupValid() {
+		if sub, ok := m.cursorSubgroup(); ok && m.mode == viewCommands {
+			m.deleteTarget = deleteGroup
+			m.deleteGroupName = config.JoinPath(m.groupPath, sub.Name)
+			m.previousMode = viewCommands
+			m.mode = viewDeleteConfirm
+			return m, nil
+		}
+		if cmd, ok := m.cursorCommand(); ok && m.mode == viewCommands && m.currentGroupValid() {
+			if cmd.ReadOnly() {
+				m.notice = "Read-only: included from " + cmd.Source
+				return m, nil
+			}
 			m.deleteTarget = deleteCommand
-			m.deleteGroupName = m.groups[m.selectedGroup].Name
-			m.deleteCmdName = m.commands[m.cursor].Name
+			m.deleteGroupName = m.groupPath
+			m.deleteCmdName = cmd.Name
 			m.previousMode = viewCommands
 			m.mode = viewDeleteConfirm
 			return m, nil
@@ -219,18 +352,24 @@ func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.handleSelect()
 
 	case "tab", "right", "l":
-		if m.mode == viewGroups && len(m.groups) > 0 && m.cursor < len(m.groups) {
-			m.mode = viewCommands
-			m.selectedGroup = m.cursor
-			m.commands = m.groups[m.cursor].Commands
+		if m.mode == viewUsage {
+			m.usageByRecent = !m.usageByRecent
+			m.loadUsageCommands()
 			m.cursor = 0
+			return m, nil
+		}
+		if m.mode == viewGroups && len(m.groups) > 0 && m.cursor < len(m.groups) {
+			m.enterGroup(m.groups[m.cursor].Name)
+			return m, nil
+		}
+		if sub, ok := m.cursorSubgroup(); ok && m.mode == viewCommands {
+			m.enterGroup(config.JoinPath(m.groupPath, sub.Name))
 		}
 		return m, nil
 
 	case "left":
 		if m.mode == viewCommands {
-			m.mode = viewGroups
-			m.cursor = m.selectedGroup
+			m.leaveGroup()
 		}
 		return m, nil
 	}
@@ -279,12 +418,10 @@ func (m Model) handleHistoryKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 	case "enter":
 		if len(m.filteredHistory) > 0 && m.cursor < len(m.filteredHistory) {
-			m.selectedHistCmd = m.filteredHistory[m.cursor].Command
 			m.historySearch.Blur()
 
 			// Move to group selection
-			m.mode = viewHistorySelectGroup
-			m.cursor = 0
+			m.pickGroupFor(m.filteredHistory[m.cursor].Command)
 			return m, nil
 		}
 	}
@@ -295,12 +432,72 @@ func (m Model) handleHistoryKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+func (m Model) handleSuggestKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.notice = ""
+	switch msg.String() {
+	case "ctrl+c", "q", "esc":
+		m.quitting = true
+		return m, tea.Quit
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		if m.cursor < m.maxCursor() {
+			m.cursor++
+		}
+	case "pgdown", "ctrl+down":
+		m.cursor = min(m.cursor+m.suggestPageSize(), m.maxCursor())
+	case "pgup", "ctrl+up":
+		m.cursor = max(m.cursor-m.suggestPageSize(), 0)
+	case " ", "x":
+		if m.cursor < len(m.suggestions) {
+			m.suggestions[m.cursor].selected = !m.suggestions[m.cursor].selected
+			if m.cursor < m.maxCursor() {
+				m.cursor++
+			}
+		}
+	case "a":
+		// Select all, or none if all are already selected
+		all := !slices.ContainsFunc(m.suggestions, func(s suggestionItem) bool { return !s.selected })
+		for i := range m.suggestions {
+			m.suggestions[i].selected = !all
+		}
+	case "t":
+		if m.cursor < len(m.suggestions) && m.suggestions[m.cursor].Template != "" {
+			m.suggestions[m.cursor].useTemplate = !m.suggestions[m.cursor].useTemplate
+		}
+	case "enter":
+		m.suggestQueue = nil
+		for _, s := range m.suggestions {
+			if s.selected {
+				m.suggestQueue = append(m.suggestQueue, s.bookmarkCommand())
+			}
+		}
+		// With nothing selected, bookmark the suggestion under the cursor
+		if len(m.suggestQueue) == 0 && m.cursor < len(m.suggestions) {
+			m.suggestQueue = []string{m.suggestions[m.cursor].bookmarkCommand()}
+		}
+		if len(m.suggestQueue) > 0 {
+			m.nextSuggestion()
+		}
+	}
+	return m, nil
+}
+
 func (m Model) handleHistorySelectGroupKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "ctrl+c":
 		m.quitting = true
 		return m, tea.Quit
 	case "esc":
+		if m.suggesting {
+			// Abandon the rest of the queue and go back to the list
+			m.suggestQueue = nil
+			m.selectedHistCmd = ""
+			m.nextSuggestion()
+			return m, nil
+		}
 		m.mode = viewHistory
 		m.historySearch.Focus()
 		m.cursor = 0
@@ -312,29 +509,31 @@ func (m Model) handleHistorySelectGroupKey(msg tea.KeyMsg) (tea.Model, tea.Cmd)
 		return m, nil
 	case "down", "j":
 		// +1 for "Create new group" option
-		maxCursor := len(m.groups)
+		maxCursor := len(m.groupPaths)
 		if m.cursor < maxCursor {
 			m.cursor++
 		}
 		return m, nil
 	case "enter":
-		if m.cursor == len(m.groups) {
+		if m.cursor == len(m.groupPaths) {
 			// Create new group option selected
 			m.mode = viewAddGroup
 			m.previousMode = viewHistorySelectGroup
+			m.addGroupParent = ""
+			// Offer the suggested group when it does not exist yet
+			var values []string
+			if group := m.config.SuggestGroup(m.selectedHistCmd); m.config.GetGroup(group) == nil {
+				values = []string{group}
+			}
 			m.createFormInputs(
-				[]string{"Group name"},
-				[]string{},
+				[]string{"Group name (use / to nest, e.g. cloud/aws)"},
+				values,
 			)
 			return m, textinput.Blink
 		}
 		// Existing group selected
-		m.selectedGroup = m.cursor
-		m.mode = viewHistoryAddDetails
-		m.createFormInputs(
-			[]string{"Command name", "Description (optional)"},
-			[]string{},
-		)
+		m.groupPath = m.groupPaths[m.cursor]
+		m.startHistoryAddDetails()
 		return m, textinput.Blink
 	}
 	return m, nil
@@ -348,7 +547,7 @@ func (m Model) handleHistoryAddDetailsKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "esc":
 		m.mode = viewHistorySelectGroup
 		m.formError = ""
-		m.cursor = m.selectedGroup
+		m.cursor = max(0, slices.Index(m.groupPaths, m.groupPath))
 		return m, nil
 	case "enter":
 		if m.formFocus < len(m.formInputs)-1 {
@@ -366,25 +565,30 @@ func (m Model) handleHistoryAddDetailsKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
-		if !m.selectedGroupValid() {
+		if !m.currentGroupValid() {
 			m.formError = "No group selected"
 			return m, nil
 		}
-		groupName := m.groups[m.selectedGroup].Name
-		if err := m.config.AddCommand(groupName, name, m.selectedHistCmd, description); err != nil {
+		if err := m.update(func(c *config.Config) error {
+			return c.AddCommand(m.groupPath, name, m.selectedHistCmd, description)
+		}); err != nil {
 			m.formError = err.Error()
 			return m, nil
 		}
-		if err := m.config.Save(); err != nil {
-			m.formError = "Failed to save: " + err.Error()
+		m.refreshData()
+
+		if m.suggesting {
+			m.suggestions = slices.DeleteFunc(m.suggestions, func(s suggestionItem) bool {
+				return s.bookmarkCommand() == m.selectedHistCmd
+			})
+			m.suggestAdded++
+			m.nextSuggestion()
 			return m, nil
 		}
-		m.refreshData()
 
-		// Go back to groups view with the group selected
-		m.mode = viewCommands
-		m.commands = m.groups[m.selectedGroup].Commands
-		m.cursor = len(m.commands) - 1
+		// Show the group with the new command selected
+		m.enterGroup(m.groupPath)
+		m.cursor = m.maxCursor()
 		m.selectedHistCmd = ""
 		return m, nil
 	case "tab":
@@ -419,29 +623,31 @@ func (m Model) handleAddGroupKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.formError = "Group name cannot be empty"
 			return m, nil
 		}
-		if err := m.config.AddGroup(name); err != nil {
+		path := config.JoinPath(m.addGroupParent, name)
+		if err := m.update(func(c *config.Config) error {
+			return c.AddGroup(path)
+		}); err != nil {
 			m.formError = err.Error()
 			return m, nil
 		}
-		if err := m.config.Save(); err != nil {
-			m.formError = "Failed to save: " + err.Error()
-			return m, nil
-		}
 		m.refreshData()
 
 		// If we came from history group selection, go to add details
 		if m.previousMode == viewHistorySelectGroup {
-			m.selectedGroup = len(m.groups) - 1
-			m.mode = viewHistoryAddDetails
-			m.createFormInputs(
-				[]string{"Command name", "Description (optional)"},
-				[]string{},
-			)
+			m.groupPath = path
+			m.startHistoryAddDetails()
 			return m, textinput.Blink
 		}
 
+		// Put the cursor on the new group, or the top-level group containing it
+		if m.previousMode == viewCommands {
+			m.mode = viewCommands
+			m.cursor = max(0, slices.IndexFunc(m.subgroups, func(g config.Group) bool { return g.Name == config.BaseName(path) }))
+			return m, nil
+		}
+		top := config.SplitPath(path)[0]
 		m.mode = viewGroups
-		m.cursor = len(m.groups) - 1
+		m.cursor = max(0, slices.IndexFunc(m.groups, func(g config.Group) bool { return g.Name == top }))
 		return m, nil
 	case "tab", "shift+tab":
 		return m, nil
@@ -468,16 +674,14 @@ func (m Model) handleEditGroupKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.formError = "Group name cannot be empty"
 			return m, nil
 		}
-		if err := m.config.RenameGroup(m.editingGroup, newName); err != nil {
+		if err := m.update(func(c *config.Config) error {
+			return c.RenameGroup(m.editingGroup, newName)
+		}); err != nil {
 			m.formError = err.Error()
 			return m, nil
 		}
-		if err := m.config.Save(); err != nil {
-			m.formError = "Failed to save: " + err.Error()
-			return m, nil
-		}
 		m.refreshData()
-		m.mode = viewGroups
+		m.mode = m.previousMode
 		m.editingGroup = ""
 		return m, nil
 	case "tab", "shift+tab":
@@ -507,8 +711,9 @@ func (m Model) handleAddCommandKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 		// Submit
 		name := m.formInputs[0].Value()
-		command := m.formInputs[1].Value()
+		command := multiLine(m.formInputs[1].Value())
 		description := m.formInputs[2].Value()
+		tags := config.ParseTags(m.formInputs[3].Value())
 
 		if name == "" {
 			m.formError = "Command name cannot be empty"
@@ -519,22 +724,19 @@ func (m Model) handleAddCommandKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
-		if !m.selectedGroupValid() {
+		if !m.currentGroupValid() {
 			m.formError = "No group selected"
 			return m, nil
 		}
-		groupName := m.groups[m.selectedGroup].Name
-		if err := m.config.AddCommand(groupName, name, command, description); err != nil {
+		if err := m.update(func(c *config.Config) error {
+			return c.AddCommandWithTags(m.groupPath, name, command, description, config.ActionNone, tags)
+		}); err != nil {
 			m.formError = err.Error()
 			return m, nil
 		}
-		if err := m.config.Save(); err != nil {
-			m.formError = "Failed to save: " + err.Error()
-			return m, nil
-		}
 		m.refreshData()
 		m.mode = viewCommands
-		m.cursor = len(m.commands) - 1
+		m.cursor = m.maxCursor()
 		return m, nil
 	case "tab":
 		m.formInputs[m.formFocus].Blur()
@@ -572,8 +774,9 @@ func (m Model) handleEditCommandKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 		// Submit
 		newName := m.formInputs[0].Value()
-		newCommand := m.formInputs[1].Value()
+		newCommand := multiLine(m.formInputs[1].Value())
 		newDescription := m.formInputs[2].Value()
+		newTags := config.ParseTags(m.formInputs[3].Value())
 
 		if newName == "" {
 			m.formError = "Command name cannot be empty"
@@ -584,21 +787,21 @@ func (m Model) handleEditCommandKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
-		if !m.selectedGroupValid() {
+		if !m.currentGroupValid() {
 			m.formError = "No group selected"
 			return m, nil
 		}
-		groupName := m.groups[m.selectedGroup].Name
 
 		// Update existing command (preserves ID)
-		if err := m.config.UpdateCommand(groupName, m.editingCmd.Name, newName, newCommand, newDescription); err != nil {
+		if err := m.update(func(c *config.Config) error {
+			if err := c.UpdateCommand(m.groupPath, m.editingCmd.Name, newName, newCommand, newDescription); err != nil {
+				return err
+			}
+			return c.SetCommandTags(m.editingCmd.ID, newTags)
+		}); err != nil {
 			m.formError = err.Error()
 			return m, nil
 		}
-		if err := m.config.Save(); err != nil {
-			m.formError = "Failed to save: " + err.Error()
-			return m, nil
-		}
 		m.refreshData()
 		m.mode = viewCommands
 		m.editingCmd = nil
@@ -629,31 +832,35 @@ func (m Model) handleDeleteConfirmKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.mode = m.previousMode
 		return m, nil
 	case "y", "Y", "enter":
-		var err error
-		if m.deleteTarget == deleteGroup {
-			err = m.config.RemoveGroup(m.deleteGroupName)
-		} else {
-			err = m.config.RemoveCommand(m.deleteGroupName, m.deleteCmdName)
-		}
-		if err != nil {
+		if err := m.update(func(c *config.Config) error {
+			if m.deleteTarget == deleteGroup {
+				return c.RemoveGroup(m.deleteGroupName)
+			}
+			return c.RemoveCommand(m.deleteGroupName, m.deleteCmdName)
+		}); err != nil {
 			m.formError = err.Error()
 			return m, nil
 		}
-		if err := m.config.Save(); err != nil {
-			m.formError = "Failed to save: " + err.Error()
-			return m, nil
-		}
 		m.refreshData()
-		if m.deleteTarget == deleteGroup {
-			m.mode = viewGroups
-			if m.cursor >= len(m.groups) {
-				m.cursor = max(0, len(m.groups)-1)
-			}
-		} else {
-			m.mode = viewCommands
-			if m.cursor >= len(m.commands) {
-				m.cursor = max(0, len(m.commands)-1)
-			}
+		m.mode = m.previousMode
+		m.cursor = min(m.cursor, m.maxCursor())
+		return m, nil
+	}
+	return m, nil
+}
+
+func (m Model) handleRestoreConfirmKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		m.quitting = true
+		return m, tea.Quit
+	case "esc", "n", "N":
+		m.mode = viewBackups
+		return m, nil
+	case "y", "Y", "enter":
+		if err := m.restoreBackup(); err != nil {
+			m.backupError = err.Error()
+			m.mode = viewBackups
 		}
 		return m, nil
 	}
@@ -664,77 +871,83 @@ func (m *Model) handleSelect() (tea.Model, tea.Cmd) {
 	switch m.mode {
 	case viewGroups:
 		if len(m.groups) > 0 && m.cursor < len(m.groups) {
-			m.mode = viewCommands
-			m.selectedGroup = m.cursor
-			m.commands = m.groups[m.cursor].Commands
-			m.cursor = 0
+			m.enterGroup(m.groups[m.cursor].Name)
 		}
 	case viewCommands:
-		if len(m.commands) > 0 && m.cursor < len(m.commands) {
-			cmd := m.commands[m.cursor]
-			m.actionCmd = &config.FlatCommand{
-				ID:            cmd.ID,
-				GroupName:     m.groups[m.selectedGroup].Name,
-				Name:          cmd.Name,
-				Command:       cmd.Command,
-				Description:   cmd.Description,
-				DefaultAction: cmd.DefaultAction,
-			}
-			// If default action is set, execute it directly
-			if cmd.DefaultAction == config.ActionCopy {
-				return m.executeAction(config.ActionCopy)
-			}
-			if cmd.DefaultAction == config.ActionRun {
-				return m.executeAction(config.ActionRun)
-			}
-			// Show action selection
-			m.previousMode = viewCommands
-			m.mode = viewActionSelect
-			m.actionCursor = 0
-			m.actionResult = ""
-			m.actionError = ""
+		if sub, ok := m.cursorSubgroup(); ok {
+			m.enterGroup(config.JoinPath(m.groupPath, sub.Name))
+			return m, nil
+		}
+		if cmd, ok := m.cursorCommand(); ok {
+			return m.openAction(cmd.Flatten(m.groupPath))
 		}
 	case viewSearch:
 		if len(m.filtered) > 0 && m.cursor < len(m.filtered) {
-			selected := m.filtered[m.cursor]
-			m.actionCmd = &selected
-			// If default action is set, execute it directly
-			if selected.DefaultAction == config.ActionCopy {
-				return m.executeAction(config.ActionCopy)
-			}
-			if selected.DefaultAction == config.ActionRun {
-				return m.executeAction(config.ActionRun)
-			}
-			// Show action selection
-			m.previousMode = viewSearch
-			m.mode = viewActionSelect
-			m.actionCursor = 0
-			m.actionResult = ""
-			m.actionError = ""
+			return m.openAction(m.filtered[m.cursor])
 		}
 	case viewAllCommands:
 		if len(m.flatCommands) > 0 && m.cursor < len(m.flatCommands) {
-			selected := m.flatCommands[m.cursor]
-			m.actionCmd = &selected
-			// If default action is set, execute it directly
-			if selected.DefaultAction == config.ActionCopy {
-				return m.executeAction(config.ActionCopy)
-			}
-			if selected.DefaultAction == config.ActionRun {
-				return m.executeAction(config.ActionRun)
-			}
-			// Show action selection
-			m.previousMode = viewAllCommands
-			m.mode = viewActionSelect
-			m.actionCursor = 0
-			m.actionResult = ""
-			m.actionError = ""
+			return m.openAction(m.flatCommands[m.cursor])
+		}
+	case viewTags:
+		if len(m.tags) > 0 && m.cursor < len(m.tags) {
+			m.selectedTag = m.tags[m.cursor].Name
+			m.tagCommands = m.config.CommandsWithTag(m.selectedTag)
+			m.mode = viewTagCommands
+			m.cursor = 0
+		}
+	case viewTagCommands:
+		if len(m.tagCommands) > 0 && m.cursor < len(m.tagCommands) {
+			return m.openAction(m.tagCommands[m.cursor])
+		}
+	case viewUsage:
+		if len(m.usageCommands) > 0 && m.cursor < len(m.usageCommands) {
+			return m.openAction(m.usageCommands[m.cursor])
+		}
+	case viewBackups:
+		if len(m.backups) > 0 && m.cursor < len(m.backups) {
+			m.backupError = ""
+			m.mode = viewRestoreConfirm
+		}
+	case viewRuns:
+		if len(m.runs) > 0 && m.cursor < len(m.runs) {
+			return m.showRun(m.runs[m.cursor])
 		}
 	}
 	return m, nil
 }
 
+// openAction runs the command's default action, or shows the action menu if
+// it has none. In select mode the command is always inserted.
+func (m *Model) openAction(selected config.FlatCommand) (tea.Model, tea.Cmd) {
+	m.actionCmd = &selected
+	m.runTarget = runOnExit
+	if m.selectMode {
+		return m.executeAction(config.ActionInsert)
+	}
+	// If default action is set, execute it directly. Dangerous commands are
+	// never run straight away unless they allow it.
+	switch selected.DefaultAction {
+	case config.ActionCopy, config.ActionInsert:
+		return m.executeAction(selected.DefaultAction)
+	case config.ActionRun:
+		if !m.config.RefusesDefaultRun(selected) {
+			return m.executeAction(selected.DefaultAction)
+		}
+	}
+	// Show action selection
+	m.previousMode = m.mode
+	m.mode = viewActionSelect
+	m.actionCursor = 0
+	m.actionResult = ""
+	m.actionError = ""
+	return m, nil
+}
+
 func (m Model) handleActionSelectKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.confirming {
+		return m.handleConfirmKey(msg)
+	}
 	switch msg.String() {
 	case "ctrl+c":
 		m.quitting = true
@@ -749,21 +962,37 @@ func (m Model) handleActionSelectKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 		return m, nil
 	case "down", "j":
-		if m.actionCursor < 2 { // run, copy, cancel
+		if m.actionCursor < 5 { // run, run here, run in terminal, copy, insert, cancel
 			m.actionCursor++
 		}
 		return m, nil
 	case "r":
 		return m.executeAction(config.ActionRun)
+	case "o":
+		m.runTarget = runInPane
+		return m.executeAction(config.ActionRun)
+	case "s":
+		m.runTarget = runInTerminal
+		return m.executeAction(config.ActionRun)
 	case "c":
 		return m.executeAction(config.ActionCopy)
+	case "i":
+		return m.executeAction(config.ActionInsert)
 	case "enter":
 		switch m.actionCursor {
 		case 0: // Run
 			return m.executeAction(config.ActionRun)
-		case 1: // Copy
+		case 1: // Run here, showing output
+			m.runTarget = runInPane
+			return m.executeAction(config.ActionRun)
+		case 2: // Run here in the terminal
+			m.runTarget = runInTerminal
+			return m.executeAction(config.ActionRun)
+		case 3: // Copy
 			return m.executeAction(config.ActionCopy)
-		case 2: // Cancel
+		case 4: // Insert
+			return m.executeAction(config.ActionInsert)
+		case 5: // Cancel
 			m.mode = m.previousMode
 			m.actionCmd = nil
 			return m, nil
@@ -776,22 +1005,468 @@ func (m *Model) executeAction(action config.ActionType) (tea.Model, tea.Cmd) {
 	if m.actionCmd == nil {
 		return m, nil
 	}
+	if m.actionCmd.IsWorkflow() {
+		return m.executeWorkflow(action)
+	}
+
+	// Prompt for placeholder values unless --set already supplied all of them
+	if list := params.Parse(m.actionCmd.Command); len(list) > 0 {
+		if rendered, err := params.Render(m.actionCmd.Command, m.paramValues); err == nil && m.allParamsSet(list) {
+			return m.performAction(action, rendered)
+		}
+		return m.startParamInput(action, list)
+	}
+
+	return m.performAction(action, m.actionCmd.Command)
+}
+
+// allParamsSet reports whether every placeholder has a value from --set.
+func (m Model) allParamsSet(list []params.Param) bool {
+	for _, p := range list {
+		if _, ok := m.paramValues[p.Name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *Model) startParamInput(action config.ActionType, list []params.Param) (tea.Model, tea.Cmd) {
+	m.paramList = list
+	m.paramAction = action
+	m.paramReturnMode = m.mode
+
+	placeholders := make([]string, len(list))
+	values := make([]string, len(list))
+	for i, p := range list {
+		placeholders[i] = p.Name
+		if p.HasDefault {
+			placeholders[i] = p.Name + " (default: " + p.Default + ")"
+		}
+		values[i] = m.paramValues[p.Name]
+	}
+	m.mode = viewParamInput
+	m.createFormInputs(placeholders, values)
+	return m, textinput.Blink
+}
+
+// performAction carries out action on the fully rendered command.
+func (m *Model) performAction(action config.ActionType, command string) (tea.Model, tea.Cmd) {
+	selected := *m.actionCmd
+	selected.Command = command
 
 	switch action {
 	case config.ActionCopy:
-		if err := runner.CopyToClipboard(m.actionCmd.Command); err != nil {
+		if err := runner.CopyToClipboard(command); err != nil {
 			m.actionError = err.Error()
 			return m, nil
 		}
-		m.selected = m.actionCmd
+		m.recordUsage(action)
+		m.selected = &selected
 		m.actionResult = "Copied to clipboard"
 		m.quitting = true
 		return m, tea.Quit
 	case config.ActionRun:
-		m.selected = m.actionCmd
+		if reason := m.config.Danger(selected); reason != "" && !m.confirmed {
+			return m.startConfirm(reason, command, nil)
+		}
+		switch m.runTarget {
+		case runInPane:
+			return m.startOutput(selected)
+		case runInTerminal:
+			return m.startInTerminal(selected)
+		}
+		m.recordUsage(action)
+		m.selected = &selected
 		m.actionResult = "run"
 		m.quitting = true
 		return m, tea.Quit
+	case config.ActionInsert:
+		m.recordUsage(action)
+		m.selected = &selected
+		m.actionResult = "insert"
+		m.quitting = true
+		return m, tea.Quit
 	}
 	return m, nil
 }
+
+// startConfirm asks in the action menu for the name of the dangerous command
+// in m.actionCmd to be typed before it runs. command is the rendered command
+// to run, or for a workflow values are its placeholder values.
+func (m *Model) startConfirm(reason, command string, values map[string]string) (tea.Model, tea.Cmd) {
+	switch m.mode {
+	case viewActionSelect:
+	case viewParamInput:
+		if m.paramReturnMode != viewActionSelect {
+			m.previousMode = m.paramReturnMode
+		}
+	default:
+		m.previousMode = m.mode
+	}
+	m.mode = viewActionSelect
+	m.actionCursor = 0
+	m.actionError = ""
+	m.confirming = true
+	m.confirmReason = reason
+	m.confirmCommand = command
+	m.confirmValues = values
+	m.createFormInputs([]string{m.actionCmd.Name}, nil)
+	return m, textinput.Blink
+}
+
+func (m Model) handleConfirmKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		m.quitting = true
+		return m, tea.Quit
+	case "esc":
+		m.confirming = false
+		m.formError = ""
+		return m, nil
+	case "enter":
+		if m.formInputs[0].Value() != m.actionCmd.Name {
+			m.formError = "Type " + m.actionCmd.Name + " to run it"
+			return m, nil
+		}
+		m.confirming = false
+		m.formError = ""
+		m.confirmed = true
+		var model tea.Model
+		var cmd tea.Cmd
+		if m.actionCmd.IsWorkflow() {
+			model, cmd = m.startWorkflow(m.confirmValues)
+		} else {
+			model, cmd = m.performAction(config.ActionRun, m.confirmCommand)
+		}
+		m.confirmed = false
+		return model, cmd
+	}
+
+	var cmd tea.Cmd
+	m.formInputs[0], cmd = m.formInputs[0].Update(msg)
+	return m, cmd
+}
+
+func (m Model) handleParamInputKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		m.quitting = true
+		return m, tea.Quit
+	case "esc":
+		m.mode = m.paramReturnMode
+		m.formError = ""
+		m.paramList = nil
+		if m.mode != viewActionSelect {
+			m.actionCmd = nil
+		}
+		return m, nil
+	case "enter":
+		if m.formFocus < len(m.formInputs)-1 {
+			m.formInputs[m.formFocus].Blur()
+			m.formFocus++
+			m.formInputs[m.formFocus].Focus()
+			return m, textinput.Blink
+		}
+		// Submit; a field left empty takes the placeholder's default
+		values := make(map[string]string, len(m.paramList))
+		for i, p := range m.paramList {
+			if v := m.formInputs[i].Value(); v != "" {
+				values[p.Name] = v
+			}
+		}
+		if m.actionCmd.IsWorkflow() {
+			return m.startWorkflow(values)
+		}
+		rendered, err := params.Render(m.actionCmd.Command, values)
+		if err != nil {
+			m.formError = err.Error()
+			return m, nil
+		}
+		m.formError = ""
+		model, cmd := m.performAction(m.paramAction, rendered)
+		if m.actionError != "" {
+			m.formError = m.actionError
+			m.actionError = ""
+		}
+		return model, cmd
+	case "tab":
+		m.formInputs[m.formFocus].Blur()
+		m.formFocus = (m.formFocus + 1) % len(m.formInputs)
+		m.formInputs[m.formFocus].Focus()
+		return m, textinput.Blink
+	case "shift+tab":
+		m.formInputs[m.formFocus].Blur()
+		m.formFocus = (m.formFocus - 1 + len(m.formInputs)) % len(m.formInputs)
+		m.formInputs[m.formFocus].Focus()
+		return m, textinput.Blink
+	}
+
+	var cmd tea.Cmd
+	m.formInputs[m.formFocus], cmd = m.formInputs[m.formFocus].Update(msg)
+	return m, cmd
+}
+
+// executeWorkflow runs the workflow in m.actionCmd, first asking for any
+// placeholder values its steps need. Workflows can only be run.
+func (m *Model) executeWorkflow(action config.ActionType) (tea.Model, tea.Cmd) {
+	fail := func(err string) (tea.Model, tea.Cmd) {
+		if m.mode == viewActionSelect {
+			m.actionError = err
+		} else {
+			m.notice = err
+			m.actionCmd = nil
+		}
+		return m, nil
+	}
+	if action != config.ActionRun {
+		return fail("Workflows can only be run")
+	}
+	steps, err := m.config.ResolveSteps(*m.actionCmd)
+	if err != nil {
+		return fail(err.Error())
+	}
+
+	commands := make([]string, len(steps))
+	for i, step := range steps {
+		commands[i] = step.Command
+	}
+	if list := params.Parse(strings.Join(commands, "\n")); len(list) > 0 && !m.allParamsSet(list) {
+		return m.startParamInput(action, list)
+	}
+	return m.startWorkflow(m.paramValues)
+}
+
+// startWorkflow fills in the placeholders of the workflow in m.actionCmd and
+// shows its progress as the steps run.
+func (m *Model) startWorkflow(values map[string]string) (tea.Model, tea.Cmd) {
+	steps, err := m.config.ResolveSteps(*m.actionCmd)
+	envs := make([]config.Environment, len(steps))
+	for i := 0; err == nil && i < len(steps); i++ {
+		envs[i], err = m.config.StepEnvironment(*m.actionCmd, steps[i])
+		if err == nil {
+			steps[i].Command, err = params.Render(steps[i].Command, values)
+		}
+	}
+	if err != nil {
+		m.formError = err.Error()
+		m.actionError = err.Error()
+		return m, nil
+	}
+	checked := *m.actionCmd
+	checked.Steps = steps
+	if reason := m.config.Danger(checked); reason != "" && !m.confirmed {
+		return m.startConfirm(reason, "", values)
+	}
+
+	m.workflowReturnMode = m.chosenFrom()
+	m.recordUsage(config.ActionRun)
+	m.workflowID = m.actionCmd.ID
+	m.workflowName = m.actionCmd.Name
+	m.workflowRun = workflow.New(steps)
+	m.workflowEnvs = envs
+	m.formError = ""
+	m.mode = viewWorkflow
+	return m, m.advanceWorkflow()
+}
+
+// chosenFrom returns the view the command in m.actionCmd was chosen in, past
+// any action menu or placeholder form, to return to once it has run.
+func (m Model) chosenFrom() viewMode {
+	mode := m.mode
+	if mode == viewParamInput {
+		mode = m.paramReturnMode
+	}
+	if mode == viewActionSelect {
+		mode = m.previousMode
+	}
+	return mode
+}
+
+// advanceWorkflow starts the next step, or waits for it to be confirmed.
+func (m *Model) advanceWorkflow() tea.Cmd {
+	i, ok := m.workflowRun.Next()
+	if !ok {
+		return nil
+	}
+	if m.workflowRun.Steps[i].Confirm {
+		m.workflowConfirm = true
+		return nil
+	}
+	return m.runStep(i)
+}
+
+// runStep hands the terminal to workflow step i until it exits.
+func (m *Model) runStep(i int) tea.Cmd {
+	cmd := runner.ShellCommandWith(m.workflowRun.Steps[i].Command, runner.Options(m.workflowEnvs[i]))
+	started := time.Now()
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return stepDoneMsg{err: err, started: started}
+	})
+}
+
+func (m Model) handleWorkflowKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if msg.String() == "ctrl+c" {
+		m.quitting = true
+		return m, tea.Quit
+	}
+
+	if m.workflowConfirm {
+		switch msg.String() {
+		case "y", "enter":
+			m.workflowConfirm = false
+			return m, m.runStep(m.workflowRun.Current())
+		case "n", "s":
+			m.workflowConfirm = false
+			m.workflowRun.Skip()
+			return m, m.advanceWorkflow()
+		case "q", "esc":
+			m.workflowConfirm = false
+			m.workflowRun.Abort()
+		}
+		return m, nil
+	}
+
+	if !m.workflowRun.Done() {
+		return m, nil // a step is running
+	}
+	switch msg.String() {
+	case "q":
+		m.quitting = true
+		return m, tea.Quit
+	case "enter", "esc":
+		m.mode = m.workflowReturnMode
+		m.workflowRun = nil
+		m.actionCmd = nil
+	}
+	return m, nil
+}
+
+// startOutput runs the rendered command cmd without leaving the TUI, showing
+// its output as it arrives.
+func (m *Model) startOutput(cmd config.FlatCommand) (tea.Model, tea.Cmd) {
+	env, err := m.config.Environment(cmd)
+	var p *runner.Process
+	if err == nil {
+		p, err = runner.Start(cmd.Command, runner.Options(env))
+	}
+	if err != nil {
+		m.actionError = err.Error()
+		return m, nil
+	}
+
+	m.recordUsage(config.ActionRun)
+	m.outputReturnMode = m.chosenFrom()
+	m.outputRun = runlog.Entry{ID: cmd.ID, Name: cmd.Name, Command: cmd.Command, Dir: runlog.WorkDir(env.Dir), Started: p.Started}
+	m.output = nil
+	m.outputView = viewport.New(m.width, m.outputHeight())
+	m.process = p
+	m.processDone = false
+	m.interrupted = false
+	m.formError = ""
+	m.mode = viewOutput
+	return m, waitForOutput(p)
+}
+
+// startInTerminal suspends the TUI to run the rendered command cmd with the
+// terminal to itself, for interactive programs, then comes back to where it
+// was chosen.
+func (m *Model) startInTerminal(cmd config.FlatCommand) (tea.Model, tea.Cmd) {
+	env, err := m.config.Environment(cmd)
+	if err != nil {
+		m.actionError = err.Error()
+		return m, nil
+	}
+
+	m.recordUsage(config.ActionRun)
+	m.mode = m.chosenFrom()
+	m.actionCmd = nil
+	m.formError = ""
+	run := runlog.Entry{ID: cmd.ID, Name: cmd.Name, Command: cmd.Command, Dir: runlog.WorkDir(env.Dir), Started: time.Now()}
+	return m, tea.ExecProcess(runner.ShellCommandWith(cmd.Command, runner.Options(env)), func(err error) tea.Msg {
+		return terminalDoneMsg{run: run, err: err}
+	})
+}
+
+func (m Model) handleOutputKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if !m.processDone {
+		// Ctrl+C goes to the command: first to interrupt it, then to kill it
+		if msg.String() == "ctrl+c" {
+			if m.interrupted {
+				_ = m.process.Kill()
+			} else {
+				_ = m.process.Interrupt()
+				m.interrupted = true
+			}
+			return m, nil
+		}
+	} else {
+		switch msg.String() {
+		case "ctrl+c", "q":
+			m.quitting = true
+			return m, tea.Quit
+		case "enter", "esc":
+			m.mode = m.outputReturnMode
+			m.process = nil
+			m.output = nil
+			m.actionCmd = nil
+			if m.mode == viewRuns {
+				m.loadRuns()
+			}
+			return m, nil
+		}
+	}
+
+	switch msg.String() {
+	case "g", "home":
+		m.outputView.GotoTop()
+		return m, nil
+	case "G", "end":
+		m.outputView.GotoBottom()
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.outputView, cmd = m.outputView.Update(msg)
+	return m, cmd
+}
+
+// showRun shows the output kept for a run from the log in the output pane.
+func (m *Model) showRun(run runlog.Entry) (tea.Model, tea.Cmd) {
+	m.outputRun = run
+	m.outputReturnMode = m.mode
+	m.output = nil
+	m.outputView = viewport.New(m.width, m.outputHeight())
+	m.process = nil
+	m.processDone = true
+	m.interrupted = false
+
+	switch {
+	case run.Output == "":
+		m.appendOutput([]string{"(no output captured)"})
+	case run.Truncated:
+		m.appendOutput(append([]string{"(only the end of the output was kept)"}, strings.Split(run.Output, "\n")...))
+	default:
+		m.appendOutput(strings.Split(run.Output, "\n"))
+	}
+	m.mode = viewOutput
+	return m, nil
+}
+
+// rerun runs the command of a logged run again in the output pane, in the
+// directory it ran in and with its bookmark's environment.
+func (m *Model) rerun(run runlog.Entry) (tea.Model, tea.Cmd) {
+	cmd := config.FlatCommand{ID: run.ID, Name: run.Label()}
+	if c, path := m.config.GetCommandByID(run.ID); c != nil && !c.IsWorkflow() {
+		cmd = c.Flatten(path)
+	}
+	cmd.Command = run.Command
+	cmd.Cwd = run.Dir
+	if _, err := m.config.Environment(cmd); err != nil {
+		m.notice = err.Error()
+		return m, nil
+	}
+
+	m.actionCmd = &cmd
+	m.previousMode = m.mode
+	m.runTarget = runInPane
+	return m.performAction(config.ActionRun, cmd.Command)
+}
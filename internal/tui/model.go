@@ -1,11 +1,24 @@
 package tui
 
 import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
 	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/sahilm/fuzzy"
 	"github.com/sammcj/bkmk/internal/config"
 	"github.com/sammcj/bkmk/internal/history"
+	"github.com/sammcj/bkmk/internal/params"
+	"github.com/sammcj/bkmk/internal/runlog"
+	"github.com/sammcj/bkmk/internal/runner"
+	"github.com/sammcj/bkmk/internal/usage"
+	"github.com/sammcj/bkmk/internal/workflow"
 )
 
 type viewMode int
@@ -24,8 +37,52 @@ const (
 	viewHistorySelectGroup
 	viewHistoryAddDetails
 	viewActionSelect
+	viewParamInput
+	viewTags
+	viewTagCommands
+	viewUsage
+	viewBackups
+	viewRestoreConfirm
+	viewSuggest
+	viewWorkflow
+	viewOutput
+	viewRuns
+)
+
+// runTarget is where a command chosen to run goes.
+type runTarget int
+
+const (
+	runOnExit     runTarget = iota // quit and leave running it to the caller
+	runInPane                      // run it here, showing its output
+	runInTerminal                  // suspend the TUI and hand it the terminal
 )
 
+// maxOutputLines is how much of a command's output the output pane keeps.
+const maxOutputLines = 10000
+
+// backupEntry is a config backup with a summary of how the current config
+// differs from it.
+type backupEntry struct {
+	config.Backup
+	summary string
+}
+
+// suggestionItem is a frequently used command offered in the suggest view.
+type suggestionItem struct {
+	history.FrequentCommand
+	selected    bool
+	useTemplate bool // bookmark Template rather than Command
+}
+
+// bookmarkCommand returns the command the suggestion would be saved as.
+func (s suggestionItem) bookmarkCommand() string {
+	if s.useTemplate && s.Template != "" {
+		return s.Template
+	}
+	return s.Command
+}
+
 type deleteTarget int
 
 const (
@@ -40,15 +97,21 @@ type Model struct {
 	flatCommands []config.FlatCommand
 	filtered     []config.FlatCommand
 
-	cursor        int
-	selectedGroup int
-	mode          viewMode
-	previousMode  viewMode
-	searchInput   textinput.Model
-	width         int
-	height        int
-	selected      *config.FlatCommand
-	quitting      bool
+	cursor       int
+	mode         viewMode
+	previousMode viewMode
+	searchInput  textinput.Model
+	width        int
+	height       int
+	selected     *config.FlatCommand
+	quitting     bool
+
+	// Group navigation: viewCommands lists the subgroups and then the
+	// commands of the group at groupPath
+	groupPath      string
+	subgroups      []config.Group
+	groupPaths     []string // every group, for picking where to bookmark
+	addGroupParent string
 
 	// Form inputs for add/edit
 	formInputs    []textinput.Model
@@ -69,6 +132,7 @@ type Model struct {
 	historySearch   textinput.Model
 	selectedHistCmd string
 	historyError    string
+	historyFilter   history.Filter
 
 	// Start in history mode flag
 	startInHistory bool
@@ -79,11 +143,127 @@ type Model struct {
 	actionResult string
 	actionError  string
 
+	// Placeholder prompting before run/copy
+	paramValues     map[string]string
+	paramList       []params.Param
+	paramAction     config.ActionType
+	paramReturnMode viewMode
+
+	// Typed confirmation in the action menu before a dangerous command runs:
+	// the rendered command, or a workflow's placeholder values, waiting on it
+	confirming     bool
+	confirmReason  string
+	confirmCommand string
+	confirmValues  map[string]string
+	confirmed      bool // set while the confirmed run starts
+
+	// Tag browser
+	tags          []config.TagCount
+	selectedTag   string
+	tagCommands   []config.FlatCommand
+	tagReturnMode viewMode
+
+	// Usage tracking for frecency ranking and the recent/most used view
+	usage           *usage.Store
+	usageCommands   []config.FlatCommand
+	usageByRecent   bool
+	usageReturnMode viewMode
+
+	// Backup browser
+	backups          []backupEntry
+	backupError      string
+	backupReturnMode viewMode
+
+	// Suggestions from shell history, bookmarked one after another through
+	// the history group and details views
+	suggestions  []suggestionItem
+	suggestQueue []string
+	suggesting   bool
+	suggestAdded int
+
+	// Workflow being run step by step, with the view to return to afterwards
+	workflowID         int
+	workflowName       string
+	workflowRun        *workflow.Run
+	workflowEnvs       []config.Environment // where each step runs
+	workflowConfirm    bool                 // waiting for the running step to be confirmed
+	workflowReturnMode viewMode
+
+	// Command run inside the TUI, its output streamed into a scrollable pane.
+	// outputRun describes the run, complete once processDone is set; the pane
+	// also shows the output of runs from the log.
+	runTarget        runTarget
+	outputRun        runlog.Entry
+	output           []string
+	outputView       viewport.Model
+	process          *runner.Process
+	processDone      bool
+	interrupted      bool
+	outputReturnMode viewMode
+
+	// Log of past runs, for the runs view and each command's last exit status
+	runLog         *runlog.Log
+	runs           []runlog.Entry // newest first
+	runsReturnMode viewMode
+
+	// Select mode: enter inserts the command instead of showing actions
+	selectMode bool
+
 	// Config path for display
 	configPath string
+
+	// reloadError is why the config file could not be reloaded after it
+	// changed on disk, shown as a banner until it loads again
+	reloadError string
+
+	// notice explains why the last key did nothing, e.g. editing a read-only command
+	notice string
+}
+
+// Option configures a Model at construction time.
+type Option func(*Model)
+
+// WithParamValues pre-fills placeholder values, e.g. from --set on the command line.
+// Commands whose placeholders are all supplied run without prompting.
+func WithParamValues(values map[string]string) Option {
+	return func(m *Model) {
+		m.paramValues = values
+	}
+}
+
+// WithSelectMode makes selecting a command return it with the insert action,
+// for shell widgets that place the command into the prompt buffer.
+func WithSelectMode() Option {
+	return func(m *Model) {
+		m.selectMode = true
+	}
 }
 
-func New(cfg *config.Config) Model {
+// WithUsage records actions in store and uses it to rank search results by
+// frecency and to list recent and most used commands.
+func WithUsage(store *usage.Store) Option {
+	return func(m *Model) {
+		m.usage = store
+	}
+}
+
+// WithRunLog records runs started from the TUI in log, and lists them in the
+// runs view.
+func WithRunLog(log *runlog.Log) Option {
+	return func(m *Model) {
+		m.runLog = log
+	}
+}
+
+// WithHistoryFilter limits the history browser to commands matching filter,
+// such as those that succeeded in the current directory.
+func WithHistoryFilter(filter history.Filter) Option {
+	return func(m *Model) {
+		m.historyFilter = filter
+	}
+}
+
+func New(cfg *config.Config, opts ...Option) Model {
 	ti := textinput.New()
 	ti.Placeholder = "Search commands..."
 	ti.CharLimit = 256
@@ -94,11 +274,12 @@ func New(cfg *config.Config) Model {
 	histSearch.CharLimit = 256
 	histSearch.Width = 50
 
-	cfgPath, _ := config.DefaultPath()
+	cfgPath, _ := config.Path()
 
-	return Model{
+	m := Model{
 		config:        cfg,
 		groups:        cfg.Groups,
+		groupPaths:    cfg.GroupPaths(),
 		flatCommands:  cfg.FlatCommands(),
 		filtered:      cfg.FlatCommands(),
 		searchInput:   ti,
@@ -108,10 +289,14 @@ func New(cfg *config.Config) Model {
 		height:        24,
 		configPath:    cfgPath,
 	}
+	for _, opt := range opts {
+		opt(&m)
+	}
+	return m
 }
 
-func NewWithHistory(cfg *config.Config) Model {
-	m := New(cfg)
+func NewWithHistory(cfg *config.Config, opts ...Option) Model {
+	m := New(cfg, opts...)
 	m.startInHistory = true
 	m.mode = viewHistory
 	if err := m.loadHistory(); err != nil {
@@ -121,23 +306,245 @@ func NewWithHistory(cfg *config.Config) Model {
 	return m
 }
 
-// NewWithLastCommand creates a TUI that starts directly in group selection
-// with the provided command pre-filled, for quickly bookmarking a command.
-func NewWithLastCommand(cfg *config.Config, command string) Model {
-	m := New(cfg)
+// NewWithSuggestions creates a TUI that starts by listing frequently used
+// commands, any number of which can be picked and bookmarked in turn.
+func NewWithSuggestions(cfg *config.Config, suggestions []history.FrequentCommand, opts ...Option) Model {
+	m := New(cfg, opts...)
+	m.mode = viewSuggest
+	m.suggestions = make([]suggestionItem, len(suggestions))
+	for i, s := range suggestions {
+		m.suggestions[i] = suggestionItem{FrequentCommand: s, useTemplate: s.Template != ""}
+	}
+	return m
+}
+
+// nextSuggestion starts bookmarking the next queued suggestion, or returns to
+// the suggestion list once the queue is empty.
+func (m *Model) nextSuggestion() {
+	m.cursor = 0
+	if len(m.suggestQueue) == 0 {
+		m.suggesting = false
+		m.mode = viewSuggest
+		if m.suggestAdded > 0 {
+			m.notice = fmt.Sprintf("Bookmarked %d suggestion(s)", m.suggestAdded)
+		}
+		m.suggestAdded = 0
+		return
+	}
+	m.suggesting = true
+	m.pickGroupFor(m.suggestQueue[0])
+	m.suggestQueue = m.suggestQueue[1:]
+}
+
+// pickGroupFor asks which group to bookmark command in, starting with the
+// cursor on the group suggested for it, or on "Create new group" if that
+// group does not exist yet.
+func (m *Model) pickGroupFor(command string) {
 	m.selectedHistCmd = command
 	m.mode = viewHistorySelectGroup
-	m.cursor = 0
+	m.cursor = slices.Index(m.groupPaths, m.config.SuggestGroup(command))
+	if m.cursor == -1 {
+		m.cursor = len(m.groupPaths)
+	}
+}
+
+// startHistoryAddDetails asks for the name and description of the command
+// being bookmarked in m.groupPath, with a name suggested from the command.
+func (m *Model) startHistoryAddDetails() {
+	m.mode = viewHistoryAddDetails
+	m.createFormInputs(
+		[]string{"Command name", "Description (optional)"},
+		[]string{m.config.SuggestName(m.groupPath, m.selectedHistCmd)},
+	)
+}
+
+// NewWithLastCommand creates a TUI that starts directly in group selection
+// with the provided command pre-filled, for quickly bookmarking a command.
+func NewWithLastCommand(cfg *config.Config, command string, opts ...Option) Model {
+	m := New(cfg, opts...)
+	m.pickGroupFor(command)
 	return m
 }
 
 func (m *Model) refreshData() {
 	m.groups = m.config.Groups
+	m.groupPaths = m.config.GroupPaths()
 	m.flatCommands = m.config.FlatCommands()
 	m.filtered = m.flatCommands
-	if m.selectedGroup < len(m.groups) && m.selectedGroup >= 0 {
-		m.commands = m.groups[m.selectedGroup].Commands
+	m.loadGroup()
+}
+
+// enterGroup shows the subgroups and commands of the group at path.
+func (m *Model) enterGroup(path string) {
+	m.groupPath = path
+	m.mode = viewCommands
+	m.cursor = 0
+	m.loadGroup()
+}
+
+// loadGroup refreshes subgroups and commands from the group at groupPath.
+func (m *Model) loadGroup() {
+	m.subgroups, m.commands = nil, nil
+	if g := m.config.GetGroup(m.groupPath); g != nil {
+		m.subgroups = g.Groups
+		m.commands = g.Commands
+	}
+}
+
+// leaveGroup goes up one level, to the parent group or the top-level group
+// list, with the cursor on the group that was left.
+func (m *Model) leaveGroup() {
+	name := config.BaseName(m.groupPath)
+	byName := func(g config.Group) bool { return g.Name == name }
+	parent := config.ParentPath(m.groupPath)
+	if parent == "" {
+		m.mode = viewGroups
+		m.groupPath = ""
+		m.loadGroup()
+		m.cursor = max(0, slices.IndexFunc(m.groups, byName))
+		return
+	}
+	m.enterGroup(parent)
+	m.cursor = max(0, slices.IndexFunc(m.subgroups, byName))
+}
+
+// cursorSubgroup returns the subgroup under the cursor in viewCommands.
+func (m Model) cursorSubgroup() (config.Group, bool) {
+	if m.cursor < 0 || m.cursor >= len(m.subgroups) {
+		return config.Group{}, false
+	}
+	return m.subgroups[m.cursor], true
+}
+
+// cursorCommand returns the command under the cursor in viewCommands, which
+// lists subgroups first.
+func (m Model) cursorCommand() (config.Command, bool) {
+	i := m.cursor - len(m.subgroups)
+	if i < 0 || i >= len(m.commands) {
+		return config.Command{}, false
+	}
+	return m.commands[i], true
+}
+
+// loadUsageCommands lists used commands, most recent or most used first.
+// Stats for commands that no longer exist are skipped.
+func (m *Model) loadUsageCommands() {
+	m.usageCommands = nil
+	if m.usage == nil {
+		return
+	}
+	byID := make(map[int]config.FlatCommand, len(m.flatCommands))
+	for _, cmd := range m.flatCommands {
+		byID[cmd.ID] = cmd
+	}
+
+	stats := m.usage.All()
+	if m.usageByRecent {
+		usage.SortByRecent(stats)
+	} else {
+		usage.SortByUses(stats)
+	}
+	for _, stat := range stats {
+		if cmd, ok := byID[stat.ID]; ok && stat.Uses() > 0 {
+			m.usageCommands = append(m.usageCommands, cmd)
+		}
+	}
+}
+
+// update applies change to the config and saves it. If another process has
+// saved the file since it was loaded, the change is replayed on top of that
+// version instead of overwriting it, so both edits are kept.
+func (m *Model) update(change func(*config.Config) error) error {
+	if err := change(m.config); err != nil {
+		return err
+	}
+	err := m.config.Save()
+	if errors.Is(err, config.ErrModified) {
+		var latest *config.Config
+		if latest, err = config.Update(change); err == nil {
+			m.config = latest
+		} else if reloaded, loadErr := config.Load(); loadErr == nil {
+			// Drop the change that could not be saved
+			m.config = reloaded
+			m.refreshData()
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save: %w", err)
+	}
+	return nil
+}
+
+// loadBackups lists the config's backups, newest first, each with a summary
+// of the changes made since.
+func (m *Model) loadBackups() {
+	m.backups, m.backupError = nil, ""
+	path, err := config.Path()
+	if err != nil {
+		m.backupError = err.Error()
+		return
+	}
+	backups, err := config.ListBackupsFor(path)
+	if err != nil {
+		m.backupError = err.Error()
+		return
+	}
+	for _, b := range backups {
+		summary := "unreadable"
+		if diff, err := config.DiffBackup(path, b.Path); err == nil {
+			summary = diff.Summary()
+		}
+		m.backups = append(m.backups, backupEntry{Backup: b, summary: summary})
+	}
+}
+
+// restoreBackup replaces the config with the backup under the cursor and
+// reloads it, returning to the group list.
+func (m *Model) restoreBackup() error {
+	b := m.backups[m.cursor]
+	if err := config.RestoreBackup(b.Path); err != nil {
+		return err
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	m.config = cfg
+	m.groupPath = ""
+	m.refreshData()
+	m.mode = viewGroups
+	m.cursor = 0
+	m.notice = "Restored backup from " + b.Time.Format("2006-01-02 15:04:05")
+	return nil
+}
+
+// recordRun adds a finished run to the log. Like usage tracking, logging is
+// best-effort and a failed write is ignored.
+func (m *Model) recordRun(run runlog.Entry) {
+	if m.runLog == nil {
+		return
+	}
+	_ = m.runLog.Append(run)
+}
+
+// loadRuns lists the runs in the log, newest first.
+func (m *Model) loadRuns() {
+	m.runs = nil
+	if m.runLog == nil {
+		return
 	}
+	m.runs = slices.Clone(m.runLog.Entries())
+	slices.Reverse(m.runs)
+}
+
+// recordUsage counts an action towards the selected command's frecency.
+// Usage tracking is best-effort, so a failed save never blocks the action.
+func (m *Model) recordUsage(action config.ActionType) {
+	if m.usage == nil || m.actionCmd == nil {
+		return
+	}
+	m.usage.Record(m.actionCmd.ID, action, time.Now())
+	_ = m.usage.Save()
 }
 
 func (m *Model) createFormInputs(placeholders []string, values []string) {
@@ -146,7 +553,8 @@ func (m *Model) createFormInputs(placeholders []string, values []string) {
 	for i, placeholder := range placeholders {
 		ti := textinput.New()
 		ti.Placeholder = placeholder
-		ti.CharLimit = 512
+		// No limit: a long command would be cut short and saved that way
+		ti.CharLimit = 0
 		ti.Width = inputWidth
 		if i < len(values) {
 			ti.SetValue(values[i])
@@ -161,7 +569,7 @@ func (m *Model) createFormInputs(placeholders []string, values []string) {
 }
 
 func (m *Model) loadHistory() error {
-	entries, err := history.ReadHistory(500)
+	entries, err := history.ReadHistoryWith(m.historyFilter, 500)
 	if err != nil {
 		return err
 	}
@@ -193,17 +601,165 @@ func (m *Model) updateHistoryFilter() {
 	}
 }
 
+// configPollInterval is how often the config file is checked for changes
+// made outside the TUI.
+const configPollInterval = time.Second
+
+// stepDoneMsg reports that the running workflow step exited.
+type stepDoneMsg struct {
+	err     error
+	started time.Time
+}
+
+// outputMsg carries lines of output from the command running in the pane.
+type outputMsg struct{ lines []string }
+
+// processDoneMsg reports that the command running in the pane exited.
+type processDoneMsg struct {
+	err  error
+	took time.Duration
+}
+
+// terminalDoneMsg reports that a command given the terminal exited.
+type terminalDoneMsg struct {
+	run runlog.Entry // as started
+	err error
+}
+
+// configTickMsg triggers a periodic check of the config file.
+type configTickMsg struct{}
+
+// editorClosedMsg is sent when the editor opened with 'o' exits.
+type editorClosedMsg struct{}
+
+func watchConfig() tea.Cmd {
+	return tea.Tick(configPollInterval, func(time.Time) tea.Msg {
+		return configTickMsg{}
+	})
+}
+
+// checkConfig reloads the config if its file changed outside the TUI. An
+// invalid file leaves the current config in place and shows why in a banner
+// until the file is fixed.
+func (m *Model) checkConfig() {
+	changed, err := m.config.ChangedOnDisk()
+	if err != nil {
+		m.reloadError = err.Error()
+		return
+	}
+	if !changed {
+		return
+	}
+	cfg, err := config.LoadFrom(m.config.LoadedFrom())
+	if err != nil {
+		m.reloadError = err.Error()
+		return
+	}
+	m.reloadError = ""
+	m.reloadConfig(cfg)
+}
+
+// reloadConfig switches to cfg, keeping the cursor on the same group or
+// command where it still exists.
+func (m *Model) reloadConfig(cfg *config.Config) {
+	var groupName string
+	cmdID := 0
+	switch m.mode {
+	case viewGroups:
+		if m.cursor < len(m.groups) {
+			groupName = m.groups[m.cursor].Name
+		}
+	case viewCommands:
+		if sub, ok := m.cursorSubgroup(); ok {
+			groupName = sub.Name
+		} else if cmd, ok := m.cursorCommand(); ok {
+			cmdID = cmd.ID
+		}
+	}
+
+	m.config = cfg
+	m.refreshData()
+	if m.mode == viewCommands && !m.currentGroupValid() {
+		m.mode = viewGroups
+		m.groupPath = ""
+		m.loadGroup()
+	}
+
+	byName := func(g config.Group) bool { return g.Name == groupName }
+	switch m.mode {
+	case viewGroups:
+		if i := slices.IndexFunc(m.groups, byName); i != -1 {
+			m.cursor = i
+		}
+	case viewCommands:
+		if i := slices.IndexFunc(m.subgroups, byName); groupName != "" && i != -1 {
+			m.cursor = i
+		} else if i := slices.IndexFunc(m.commands, func(c config.Command) bool { return c.ID == cmdID }); cmdID != 0 && i != -1 {
+			m.cursor = len(m.subgroups) + i
+		}
+	case viewSearch:
+		m.updateFilter()
+	case viewTags:
+		m.tags = m.config.Tags()
+	case viewTagCommands:
+		m.tagCommands = m.config.CommandsWithTag(m.selectedTag)
+	case viewUsage:
+		m.loadUsageCommands()
+	}
+	// Views such as the action menu or output pane have no list of their own
+	// and leave the cursor for the list they return to
+	if m.showingList() {
+		m.cursor = min(m.cursor, m.maxCursor())
+	}
+}
+
 func (m Model) Init() tea.Cmd {
 	if m.startInHistory {
-		return textinput.Blink
+		return tea.Batch(textinput.Blink, watchConfig())
 	}
-	return nil
+	return watchConfig()
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		return m.handleKey(msg)
+	case configTickMsg:
+		m.checkConfig()
+		return m, watchConfig()
+	case editorClosedMsg:
+		m.checkConfig()
+		return m, nil
+	case stepDoneMsg:
+		if m.workflowRun == nil {
+			return m, nil
+		}
+		i := m.workflowRun.Current()
+		step := m.workflowRun.Steps[i]
+		m.recordRun(runlog.Entry{
+			ID: m.workflowID, Name: m.workflowName, Step: step.Label(), Command: step.Command,
+			Dir: runlog.WorkDir(m.workflowEnvs[i].Dir), Started: msg.started,
+			Duration: time.Since(msg.started), Exit: runner.ExitCode(msg.err),
+		})
+		m.workflowRun.Finish(msg.err)
+		return m, m.advanceWorkflow()
+	case outputMsg:
+		m.appendOutput(msg.lines)
+		return m, waitForOutput(m.process)
+	case processDoneMsg:
+		m.processDone = true
+		m.outputRun.Duration = msg.took
+		m.outputRun.Exit = runner.ExitCode(msg.err)
+		m.outputRun.Output = strings.Join(m.output, "\n")
+		m.recordRun(m.outputRun)
+		return m, nil
+	case terminalDoneMsg:
+		run := msg.run
+		run.Duration = time.Since(run.Started)
+		run.Exit = runner.ExitCode(msg.err)
+		m.recordRun(run)
+		m.notice = fmt.Sprintf("%s exited %d after %s", run.Name, run.Exit, run.Duration.Round(time.Millisecond))
+		return m, nil
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
@@ -212,6 +768,8 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		for i := range m.formInputs {
 			m.formInputs[i].Width = msg.Width - 4
 		}
+		m.outputView.Width = msg.Width
+		m.outputView.Height = m.outputHeight()
 		// Clear screen on resize to prevent content duplication
 		return m, tea.ClearScreen
 	}
@@ -228,7 +786,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.historySearch, cmd = m.historySearch.Update(msg)
 		m.updateHistoryFilter()
 		return m, cmd
-	case viewAddGroup, viewEditGroup, viewAddCommand, viewEditCommand, viewHistoryAddDetails:
+	case viewAddGroup, viewEditGroup, viewAddCommand, viewEditCommand, viewHistoryAddDetails, viewParamInput:
 		if m.formFocus < len(m.formInputs) {
 			var cmd tea.Cmd
 			m.formInputs[m.formFocus], cmd = m.formInputs[m.formFocus].Update(msg)
@@ -249,9 +807,22 @@ func (m *Model) updateFilter() {
 	searchItems := make([]string, len(m.flatCommands))
 	for i, cmd := range m.flatCommands {
 		searchItems[i] = cmd.GroupName + " " + cmd.Name + " " + cmd.Command + " " + cmd.Description
+		for _, tag := range cmd.Tags {
+			searchItems[i] += " #" + tag
+		}
 	}
 
 	matches := fuzzy.Find(query, searchItems)
+	// Blend frecency into the match score so frequently used commands rise
+	if m.usage != nil {
+		now := time.Now()
+		for i := range matches {
+			matches[i].Score += usage.Boost(m.usage.Score(m.flatCommands[matches[i].Index].ID, now))
+		}
+		sort.SliceStable(matches, func(i, j int) bool {
+			return matches[i].Score > matches[j].Score
+		})
+	}
 	m.filtered = make([]config.FlatCommand, len(matches))
 	for i, match := range matches {
 		m.filtered[i] = m.flatCommands[match.Index]
@@ -262,8 +833,19 @@ func (m *Model) updateFilter() {
 	}
 }
 
-func (m Model) selectedGroupValid() bool {
-	return m.selectedGroup >= 0 && m.selectedGroup < len(m.groups)
+func (m Model) currentGroupValid() bool {
+	return m.config.GetGroup(m.groupPath) != nil
+}
+
+// showingList reports whether the current view is a list moved through with
+// the cursor, as opposed to a form, menu or pane.
+func (m Model) showingList() bool {
+	switch m.mode {
+	case viewGroups, viewCommands, viewSearch, viewAllCommands, viewTags, viewTagCommands,
+		viewUsage, viewBackups, viewHistory, viewHistorySelectGroup, viewSuggest, viewRuns:
+		return true
+	}
+	return false
 }
 
 func (m Model) maxCursor() int {
@@ -271,22 +853,34 @@ func (m Model) maxCursor() int {
 	case viewGroups:
 		return max(0, len(m.groups)-1)
 	case viewCommands:
-		return max(0, len(m.commands)-1)
+		return max(0, len(m.subgroups)+len(m.commands)-1)
 	case viewSearch:
 		return max(0, len(m.filtered)-1)
 	case viewAllCommands:
 		return max(0, len(m.flatCommands)-1)
+	case viewTags:
+		return max(0, len(m.tags)-1)
+	case viewTagCommands:
+		return max(0, len(m.tagCommands)-1)
+	case viewUsage:
+		return max(0, len(m.usageCommands)-1)
+	case viewBackups:
+		return max(0, len(m.backups)-1)
 	case viewHistory:
 		return max(0, len(m.filteredHistory)-1)
 	case viewHistorySelectGroup:
-		return len(m.groups) // +1 for "create new" option
+		return len(m.groupPaths) // +1 for "create new" option
+	case viewSuggest:
+		return max(0, len(m.suggestions)-1)
+	case viewRuns:
+		return max(0, len(m.runs)-1)
 	}
 	return 0
 }
 
 // historyPageSize returns the number of visible history items for page up/down
 func (m Model) historyPageSize() int {
-	reservedLines := 8
+	reservedLines := 9
 	pageSize := m.height - reservedLines
 	if pageSize < 5 {
 		pageSize = 5
@@ -294,6 +888,44 @@ func (m Model) historyPageSize() int {
 	return pageSize
 }
 
+// suggestPageSize is how many suggestions fit on screen at once.
+func (m Model) suggestPageSize() int {
+	return max(5, (m.height-8)/2)
+}
+
+// outputHeight is how many lines of output the output pane shows at once,
+// leaving room for the title, command and status.
+func (m Model) outputHeight() int {
+	return max(3, m.height-8)
+}
+
+// appendOutput adds lines to the output pane, following the end of the
+// output unless it has been scrolled back.
+func (m *Model) appendOutput(lines []string) {
+	follow := m.outputView.AtBottom()
+	for _, line := range lines {
+		m.output = append(m.output, strings.ReplaceAll(line, "\t", "    "))
+	}
+	if over := len(m.output) - maxOutputLines; over > 0 {
+		m.output = slices.Delete(m.output, 0, over)
+	}
+	m.outputView.SetContent(strings.Join(m.output, "\n"))
+	if follow {
+		m.outputView.GotoBottom()
+	}
+}
+
+// waitForOutput waits for the next output from p, or for it to exit.
+func waitForOutput(p *runner.Process) tea.Cmd {
+	return func() tea.Msg {
+		if lines, ok := p.ReadLines(); ok {
+			return outputMsg{lines: lines}
+		}
+		err := p.Wait()
+		return processDoneMsg{err: err, took: time.Since(p.Started)}
+	}
+}
+
 func (m Model) Selected() *config.FlatCommand {
 	return m.selected
 }
@@ -1,9 +1,19 @@
 package tui
 
 import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/sammcj/bkmk/internal/config"
+	"github.com/sammcj/bkmk/internal/history"
+	"github.com/sammcj/bkmk/internal/runlog"
+	"github.com/sammcj/bkmk/internal/runner"
+	"github.com/sammcj/bkmk/internal/usage"
 )
 
 func TestNew(t *testing.T) {
@@ -90,3 +100,860 @@ func TestNewWithLastCommand_EmptyGroups(t *testing.T) {
 		t.Errorf("Expected 0 groups, got %d", len(m.groups))
 	}
 }
+
+func TestExecuteAction_PromptsForPlaceholders(t *testing.T) {
+	cfg := &config.Config{
+		Groups: []config.Group{
+			{Name: "k8s", Commands: []config.Command{
+				{ID: 1, Name: "logs", Command: "kubectl logs -n {{ns:default}} {{pod}}"},
+			}},
+		},
+	}
+
+	m := New(cfg)
+	m.mode = viewCommands
+	m.commands = cfg.Groups[0].Commands
+	m.actionCmd = &config.FlatCommand{ID: 1, GroupName: "k8s", Name: "logs", Command: cfg.Groups[0].Commands[0].Command}
+
+	model, _ := m.executeAction(config.ActionRun)
+	pm := model.(*Model)
+	if pm.mode != viewParamInput {
+		t.Fatalf("expected viewParamInput mode, got %v", pm.mode)
+	}
+	if len(pm.formInputs) != 2 {
+		t.Fatalf("expected 2 inputs, got %d", len(pm.formInputs))
+	}
+	if pm.formInputs[0].Value() != "" {
+		t.Errorf("expected empty ns input, got %q", pm.formInputs[0].Value())
+	}
+
+	// Submitting without a value for pod should fail validation
+	pm.formFocus = 1
+	model, _ = pm.handleParamInputKey(tea.KeyMsg{Type: tea.KeyEnter})
+	vm := model.(Model)
+	if vm.formError == "" {
+		t.Fatal("expected error for missing pod value")
+	}
+
+	vm.formInputs[1].SetValue("api 1")
+	model, _ = vm.handleParamInputKey(tea.KeyMsg{Type: tea.KeyEnter})
+	done := model.(*Model)
+	if done.Selected() == nil {
+		t.Fatal("expected a selected command after submitting placeholders")
+	}
+	if got, want := done.Selected().Command, "kubectl logs -n default 'api 1'"; got != want {
+		t.Errorf("rendered command = %q, want %q", got, want)
+	}
+	if done.ActionResult() != "run" {
+		t.Errorf("expected run action, got %q", done.ActionResult())
+	}
+}
+
+func TestExecuteAction_ParamValuesSkipPrompt(t *testing.T) {
+	cfg := &config.Config{}
+	m := New(cfg, WithParamValues(map[string]string{"ns": "staging", "pod": "web-0"}))
+	m.actionCmd = &config.FlatCommand{ID: 1, Name: "logs", Command: "kubectl logs -n {{ns}} {{pod}}"}
+
+	model, _ := m.executeAction(config.ActionRun)
+	pm := model.(*Model)
+	if pm.mode == viewParamInput {
+		t.Fatal("expected no prompt when all values are supplied")
+	}
+	if pm.Selected() == nil || pm.Selected().Command != "kubectl logs -n staging web-0" {
+		t.Errorf("unexpected selected command: %+v", pm.Selected())
+	}
+}
+
+func TestSelectModeInserts(t *testing.T) {
+	cfg := &config.Config{
+		Groups: []config.Group{
+			{Name: "docker", Commands: []config.Command{
+				{ID: 1, Name: "ps", Command: "docker ps -a", DefaultAction: config.ActionRun},
+			}},
+		},
+	}
+
+	m := New(cfg, WithSelectMode())
+	m.enterGroup("docker")
+
+	model, _ := m.handleSelect()
+	pm := model.(*Model)
+	if pm.ActionResult() != "insert" {
+		t.Errorf("expected insert action in select mode, got %q", pm.ActionResult())
+	}
+	if pm.Selected() == nil || pm.Selected().Command != "docker ps -a" {
+		t.Errorf("unexpected selected command: %+v", pm.Selected())
+	}
+}
+
+func TestTagBrowsing(t *testing.T) {
+	cfg := &config.Config{
+		Groups: []config.Group{
+			{Name: "docker", Commands: []config.Command{
+				{ID: 1, Name: "logs", Command: "docker compose logs", Tags: []string{"debugging", "docker"}},
+			}},
+			{Name: "k8s", Commands: []config.Command{
+				{ID: 2, Name: "events", Command: "kubectl get events", Tags: []string{"debugging"}},
+				{ID: 3, Name: "pods", Command: "kubectl get pods"},
+			}},
+		},
+	}
+
+	m := New(cfg)
+	result, _ := m.handleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("t")})
+	m = result.(Model)
+	if m.mode != viewTags {
+		t.Fatalf("expected viewTags, got %v", m.mode)
+	}
+	if len(m.tags) != 2 || m.tags[0].Name != "debugging" {
+		t.Fatalf("unexpected tags: %+v", m.tags)
+	}
+
+	result, _ = m.handleKey(tea.KeyMsg{Type: tea.KeyEnter})
+	m = *result.(*Model)
+	if m.mode != viewTagCommands {
+		t.Fatalf("expected viewTagCommands, got %v", m.mode)
+	}
+	if len(m.tagCommands) != 2 {
+		t.Errorf("expected 2 commands tagged debugging, got %d", len(m.tagCommands))
+	}
+
+	result, _ = m.handleKey(tea.KeyMsg{Type: tea.KeyEsc})
+	m = result.(Model)
+	if m.mode != viewTags {
+		t.Errorf("esc should return to the tag list, got %v", m.mode)
+	}
+	result, _ = m.handleKey(tea.KeyMsg{Type: tea.KeyEsc})
+	m = result.(Model)
+	if m.mode != viewGroups {
+		t.Errorf("esc should return to where tags were opened, got %v", m.mode)
+	}
+}
+
+func TestUpdateFilter_MatchesTags(t *testing.T) {
+	cfg := &config.Config{
+		Groups: []config.Group{
+			{Name: "docker", Commands: []config.Command{
+				{ID: 1, Name: "logs", Command: "docker compose logs", Tags: []string{"troubleshoot"}},
+				{ID: 2, Name: "ps", Command: "docker ps"},
+			}},
+		},
+	}
+
+	m := New(cfg)
+	m.searchInput.SetValue("#troubleshoot")
+	m.updateFilter()
+	if len(m.filtered) != 1 || m.filtered[0].ID != 1 {
+		t.Errorf("expected only the tagged command, got %+v", m.filtered)
+	}
+}
+
+func TestNestedGroupNavigation(t *testing.T) {
+	cfg := &config.Config{
+		Groups: []config.Group{
+			{Name: "cloud", Commands: []config.Command{
+				{ID: 1, Name: "status", Command: "cloud status"},
+			}, Groups: []config.Group{
+				{Name: "aws", Commands: []config.Command{
+					{ID: 2, Name: "whoami", Command: "aws sts get-caller-identity"},
+				}},
+			}},
+		},
+	}
+
+	m := New(cfg)
+	result, _ := m.handleSelect()
+	m = *result.(*Model)
+	if m.mode != viewCommands || m.groupPath != "cloud" {
+		t.Fatalf("expected to enter cloud, got mode %v path %q", m.mode, m.groupPath)
+	}
+	if len(m.subgroups) != 1 || len(m.commands) != 1 || m.maxCursor() != 1 {
+		t.Fatalf("expected subgroup then command, got %d subgroups, %d commands", len(m.subgroups), len(m.commands))
+	}
+
+	// The subgroup is listed first, so selecting it descends
+	result, _ = m.handleSelect()
+	m = *result.(*Model)
+	if m.groupPath != "cloud/aws" {
+		t.Fatalf("expected to descend into cloud/aws, got %q", m.groupPath)
+	}
+	if header := m.renderHeader(); !strings.Contains(header, "cloud › aws") {
+		t.Errorf("expected breadcrumbs in header, got %q", header)
+	}
+
+	result, _ = m.handleKey(tea.KeyMsg{Type: tea.KeyEsc})
+	m = result.(Model)
+	if m.mode != viewCommands || m.groupPath != "cloud" || m.cursor != 0 {
+		t.Fatalf("esc should ascend to cloud with aws selected, got mode %v path %q cursor %d", m.mode, m.groupPath, m.cursor)
+	}
+
+	// Selecting the command after the subgroup opens it with its full path
+	m.cursor = 1
+	action := m
+	result, _ = action.handleSelect()
+	pm := result.(*Model)
+	if pm.mode != viewActionSelect || pm.actionCmd.GroupName != "cloud" || pm.actionCmd.ID != 1 {
+		t.Errorf("expected action menu for cloud/status, got mode %v cmd %+v", pm.mode, pm.actionCmd)
+	}
+
+	result, _ = m.handleKey(tea.KeyMsg{Type: tea.KeyEsc})
+	m = result.(Model)
+	if m.mode != viewGroups {
+		t.Errorf("esc from a top-level group should return to the group list, got %v", m.mode)
+	}
+}
+
+func TestUpdateFilter_FrecencyRanking(t *testing.T) {
+	cfg := &config.Config{
+		Groups: []config.Group{
+			{Name: "docker", Commands: []config.Command{
+				{ID: 1, Name: "logs-tail", Command: "docker logs --tail 100"},
+				{ID: 2, Name: "logs", Command: "docker compose logs -f"},
+			}},
+		},
+	}
+
+	store, err := usage.LoadFrom(filepath.Join(t.TempDir(), "usage.json"))
+	if err != nil {
+		t.Fatalf("LoadFrom failed: %v", err)
+	}
+	for range 20 {
+		store.Record(2, config.ActionRun, time.Now())
+	}
+
+	m := New(cfg, WithUsage(store))
+	m.searchInput.SetValue("logs")
+	m.updateFilter()
+	if len(m.filtered) != 2 || m.filtered[0].ID != 2 {
+		t.Errorf("expected frequently used command first, got %+v", m.filtered)
+	}
+
+	// Selecting a command records its use
+	m.actionCmd = &m.filtered[1]
+	m.performAction(config.ActionInsert, m.actionCmd.Command)
+	if store.Get(1).Inserts != 1 {
+		t.Errorf("expected insert recorded, got %+v", store.Get(1))
+	}
+}
+
+func TestReadOnlyCommandsRefuseEditAndDelete(t *testing.T) {
+	cfg := &config.Config{
+		Groups: []config.Group{
+			{Name: "team", Commands: []config.Command{
+				{ID: 1, Name: "deploy", Command: "make deploy", Source: "/shared/team.yaml"},
+			}},
+		},
+	}
+
+	m := New(cfg)
+	m.enterGroup("team")
+	if view := m.viewCommands(); !strings.Contains(view, "read-only: team.yaml") {
+		t.Errorf("expected read-only marker in view, got %q", view)
+	}
+
+	for _, key := range []string{"e", "d"} {
+		result, _ := m.handleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+		got := result.(Model)
+		if got.mode != viewCommands {
+			t.Errorf("%q on a read-only command should stay in the list, got mode %v", key, got.mode)
+		}
+		if !strings.Contains(got.notice, "/shared/team.yaml") {
+			t.Errorf("%q should explain where the command comes from, got %q", key, got.notice)
+		}
+	}
+}
+
+func TestBackupBrowserRestores(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv(config.EnvConfig, path)
+
+	cfg := &config.Config{Groups: []config.Group{}, NextID: 1}
+	if err := cfg.AddCommand("git", "st", "git status", ""); err != nil {
+		t.Fatalf("AddCommand failed: %v", err)
+	}
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if err := cfg.AddCommand("git", "co", "git checkout", ""); err != nil {
+		t.Fatalf("AddCommand failed: %v", err)
+	}
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	m := New(cfg)
+	result, _ := m.handleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("b")})
+	m = result.(Model)
+	if m.mode != viewBackups || len(m.backups) != 1 {
+		t.Fatalf("expected backup view with one backup, got mode %v and %d backups", m.mode, len(m.backups))
+	}
+	if m.backups[0].summary != "+1 -0 ~0" {
+		t.Errorf("expected summary of the added command, got %q", m.backups[0].summary)
+	}
+
+	result, _ = m.handleKey(tea.KeyMsg{Type: tea.KeyEnter})
+	m = *result.(*Model)
+	if m.mode != viewRestoreConfirm {
+		t.Fatalf("expected restore confirmation, got mode %v", m.mode)
+	}
+	result, _ = m.handleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+	m = result.(Model)
+	if m.mode != viewGroups || m.backupError != "" {
+		t.Fatalf("expected group list after restore, got mode %v error %q", m.mode, m.backupError)
+	}
+	if len(m.flatCommands) != 1 || m.flatCommands[0].Name != "st" {
+		t.Errorf("expected restored config with only st, got %+v", m.flatCommands)
+	}
+}
+
+func TestUpdateReplaysOnExternalChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv(config.EnvConfig, path)
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	m := New(cfg)
+
+	// Another bkmk process adds a command after the TUI loaded the config
+	if _, err := config.UpdateAt(path, func(c *config.Config) error {
+		return c.AddCommand("git", "st", "git status", "")
+	}); err != nil {
+		t.Fatalf("UpdateAt failed: %v", err)
+	}
+
+	if err := m.update(func(c *config.Config) error {
+		return c.AddCommand("git", "lg", "git log", "")
+	}); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	m.refreshData()
+	if len(m.flatCommands) != 2 {
+		t.Errorf("expected both commands after replay, got %+v", m.flatCommands)
+	}
+}
+
+func TestConfigReloadsWhenChangedOnDisk(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write config: %v", err)
+		}
+	}
+	write(`groups:
+  - name: git
+    commands:
+      - {id: 1, name: st, command: git status}
+      - {id: 2, name: lg, command: git log}
+`)
+	cfg, err := config.LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom failed: %v", err)
+	}
+
+	m := New(cfg)
+	m.enterGroup("git")
+	m.cursor = 1 // lg
+
+	// A command is added above the selected one in another editor
+	write(`groups:
+  - name: git
+    commands:
+      - {id: 3, name: co, command: git checkout}
+      - {id: 1, name: st, command: git status}
+      - {id: 2, name: lg, command: git log}
+`)
+	result, _ := m.Update(configTickMsg{})
+	m = result.(Model)
+	if len(m.commands) != 3 {
+		t.Fatalf("expected reloaded commands, got %+v", m.commands)
+	}
+	if cmd, _ := m.cursorCommand(); cmd.ID != 2 {
+		t.Errorf("expected cursor to stay on lg, got %+v", cmd)
+	}
+
+	// A reload while a menu is open keeps the place in the list behind it
+	m.mode = viewActionSelect
+	write(`groups:
+  - name: git
+    commands:
+      - {id: 3, name: co, command: git checkout}
+      - {id: 1, name: st, command: git status}
+      - {id: 2, name: lg, command: git log --oneline}
+`)
+	result, _ = m.Update(configTickMsg{})
+	m = result.(Model)
+	m.mode = viewCommands
+	if cmd, _ := m.cursorCommand(); cmd.ID != 2 {
+		t.Errorf("expected cursor to stay on lg behind the action menu, got %+v", cmd)
+	}
+
+	// An invalid file keeps the current config and explains why
+	write("groups:\n  - name: git\n    bogus: true\n")
+	result, _ = m.Update(configTickMsg{})
+	m = result.(Model)
+	if len(m.commands) != 3 || !strings.Contains(m.reloadError, "invalid config key") {
+		t.Fatalf("expected config kept with an error banner, got %d commands and %q", len(m.commands), m.reloadError)
+	}
+	if view := m.View(); !strings.Contains(view, "Config not reloaded") {
+		t.Errorf("expected reload banner in view, got %q", view)
+	}
+
+	// Fixing the file clears the banner; removing the group returns to the list
+	write("groups:\n  - name: docker\n    commands: []\n")
+	result, _ = m.Update(configTickMsg{})
+	m = result.(Model)
+	if m.reloadError != "" || m.mode != viewGroups || len(m.groups) != 1 {
+		t.Errorf("expected clean reload back to the group list, got mode %v error %q", m.mode, m.reloadError)
+	}
+}
+
+func TestMultilineCommandSurvivesEdit(t *testing.T) {
+	t.Setenv(config.EnvConfig, filepath.Join(t.TempDir(), "config.yaml"))
+	multi := "for f in *.go; do\n  gofmt -l \"$f\"\ndone"
+	cfg := &config.Config{
+		Groups: []config.Group{
+			{Name: "go", Commands: []config.Command{{ID: 1, Name: "fmt", Command: multi}}},
+		},
+		NextID: 2,
+	}
+
+	m := New(cfg)
+	m.enterGroup("go")
+	if view := m.viewCommands(); !strings.Contains(view, "      gofmt -l") {
+		t.Errorf("expected every line of the command indented in the list, got %q", view)
+	}
+
+	result, _ := m.handleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("e")})
+	m = result.(Model)
+	if got := m.formInputs[1].Value(); got != "for f in *.go; do↵  gofmt -l \"$f\"↵done" {
+		t.Fatalf("expected line breaks marked in the input, got %q", got)
+	}
+
+	for m.mode == viewEditCommand {
+		result, _ = m.handleKey(tea.KeyMsg{Type: tea.KeyEnter})
+		m = result.(Model)
+		if m.formError != "" {
+			t.Fatalf("edit failed: %s", m.formError)
+		}
+	}
+	if got := m.config.AllCommands()[0].Command; got != multi {
+		t.Errorf("expected the command to keep its lines, got %q", got)
+	}
+}
+
+func TestLongCommandSurvivesEdit(t *testing.T) {
+	t.Setenv(config.EnvConfig, filepath.Join(t.TempDir(), "config.yaml"))
+	long := "echo " + strings.Repeat("x", 2000)
+	cfg := &config.Config{
+		Groups: []config.Group{
+			{Name: "misc", Commands: []config.Command{{ID: 1, Name: "long", Command: long}}},
+		},
+		NextID: 2,
+	}
+
+	m := New(cfg)
+	m.enterGroup("misc")
+	result, _ := m.handleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("e")})
+	m = result.(Model)
+	for m.mode == viewEditCommand {
+		result, _ = m.handleKey(tea.KeyMsg{Type: tea.KeyEnter})
+		m = result.(Model)
+		if m.formError != "" {
+			t.Fatalf("edit failed: %s", m.formError)
+		}
+	}
+	if got := m.config.AllCommands()[0].Command; got != long {
+		t.Errorf("expected the command to survive the edit, got %d characters", len(got))
+	}
+}
+
+func TestSuggestBookmarksSelectedInTurn(t *testing.T) {
+	t.Setenv(config.EnvConfig, filepath.Join(t.TempDir(), "config.yaml"))
+	cfg := &config.Config{
+		Groups: []config.Group{{Name: "git"}},
+		NextID: 1,
+	}
+	suggestions := []history.FrequentCommand{
+		{Command: "git log -n 10", Count: 7, Template: "git log -n {{n:10}}", Variants: 2},
+		{Command: "git status -sb", Count: 5},
+		{Command: "git fetch --all", Count: 3},
+	}
+
+	m := NewWithSuggestions(cfg, suggestions)
+	if m.mode != viewSuggest {
+		t.Fatalf("expected to start in viewSuggest, got %v", m.mode)
+	}
+
+	key := func(k tea.KeyMsg) {
+		t.Helper()
+		result, _ := m.handleKey(k)
+		m = result.(Model)
+		if m.formError != "" {
+			t.Fatalf("unexpected form error: %s", m.formError)
+		}
+	}
+	runes := func(s string) tea.KeyMsg { return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)} }
+
+	// Select the first two, keeping the template for the first
+	key(runes(" "))
+	key(runes(" "))
+	key(tea.KeyMsg{Type: tea.KeyEnter})
+
+	for _, want := range []string{"git log -n {{n:10}}", "git status -sb"} {
+		if m.mode != viewHistorySelectGroup || m.selectedHistCmd != want {
+			t.Fatalf("expected to pick a group for %q, got mode %v with %q", want, m.mode, m.selectedHistCmd)
+		}
+		key(tea.KeyMsg{Type: tea.KeyEnter}) // the git group, suggested for both
+		key(tea.KeyMsg{Type: tea.KeyEnter}) // the suggested name
+		key(tea.KeyMsg{Type: tea.KeyEnter})
+	}
+
+	if m.mode != viewSuggest {
+		t.Fatalf("expected to return to the suggestions, got %v", m.mode)
+	}
+	if len(m.suggestions) != 1 || m.suggestions[0].Command != "git fetch --all" {
+		t.Errorf("expected only the unbookmarked suggestion left, got %+v", m.suggestions)
+	}
+	if !strings.Contains(m.notice, "2") {
+		t.Errorf("expected a notice counting the bookmarks, got %q", m.notice)
+	}
+
+	var got []string
+	for _, c := range m.config.AllCommands() {
+		got = append(got, c.Command)
+	}
+	if !slices.Equal(got, []string{"git log -n {{n:10}}", "git status -sb"}) {
+		t.Errorf("expected both suggestions bookmarked, got %q", got)
+	}
+}
+
+func TestSuggestEscapeAbandonsQueue(t *testing.T) {
+	cfg := &config.Config{Groups: []config.Group{{Name: "git"}}}
+	m := NewWithSuggestions(cfg, []history.FrequentCommand{
+		{Command: "git log -n 10", Count: 7, Template: "git log -n {{n:10}}", Variants: 2},
+		{Command: "git status -sb", Count: 5},
+	})
+
+	result, _ := m.handleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("t")})
+	m = result.(Model)
+	result, _ = m.handleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")})
+	m = result.(Model)
+	result, _ = m.handleKey(tea.KeyMsg{Type: tea.KeyEnter})
+	m = result.(Model)
+	if m.selectedHistCmd != "git log -n 10" {
+		t.Errorf("expected t to switch to the plain command, got %q", m.selectedHistCmd)
+	}
+
+	result, _ = m.handleKey(tea.KeyMsg{Type: tea.KeyEsc})
+	m = result.(Model)
+	if m.mode != viewSuggest || len(m.suggestQueue) != 0 || m.suggesting {
+		t.Errorf("expected esc to return to the suggestions with nothing queued, got mode %v queue %q", m.mode, m.suggestQueue)
+	}
+	if len(m.suggestions) != 2 {
+		t.Errorf("expected no suggestions removed, got %d", len(m.suggestions))
+	}
+}
+
+func TestLastCommandSuggestsGroupAndName(t *testing.T) {
+	cfg := &config.Config{
+		Groups: []config.Group{
+			{Name: "docker", Commands: []config.Command{}},
+			{Name: "git", Commands: []config.Command{}},
+		},
+	}
+
+	m := NewWithLastCommand(cfg, "git status -sb")
+	if m.cursor != 1 {
+		t.Fatalf("expected the cursor on the git group, got %d", m.cursor)
+	}
+	result, _ := m.handleKey(tea.KeyMsg{Type: tea.KeyEnter})
+	m = result.(Model)
+	if m.mode != viewHistoryAddDetails || m.formInputs[0].Value() != "git-status-sb" {
+		t.Errorf("expected the name suggested from the command, got mode %v with %q", m.mode, m.formInputs[0].Value())
+	}
+
+	m = NewWithLastCommand(cfg, "terraform plan -out tfplan")
+	if m.cursor != len(m.groupPaths) {
+		t.Fatalf("expected the cursor on Create new group, got %d", m.cursor)
+	}
+	result, _ = m.handleKey(tea.KeyMsg{Type: tea.KeyEnter})
+	m = result.(Model)
+	if m.mode != viewAddGroup || m.formInputs[0].Value() != "terraform" {
+		t.Errorf("expected a new group named after the program, got mode %v with %q", m.mode, m.formInputs[0].Value())
+	}
+}
+
+func TestWorkflowProgress(t *testing.T) {
+	cfg := &config.Config{
+		Groups: []config.Group{
+			{Name: "deploy", Commands: []config.Command{
+				{ID: 1, Name: "build", Command: "make build"},
+				{ID: 2, Name: "release", DefaultAction: config.ActionRun, Steps: []config.Step{
+					{Ref: 1},
+					{Name: "push", Command: "make push", Confirm: true},
+					{Name: "roll", Command: "make roll"},
+				}},
+			}},
+		},
+		NextID: 3,
+	}
+
+	m := New(cfg)
+	m.enterGroup("deploy")
+	m.cursor = 1
+	result, cmd := m.handleKey(tea.KeyMsg{Type: tea.KeyEnter})
+	m = *result.(*Model)
+	if m.mode != viewWorkflow || cmd == nil {
+		t.Fatalf("expected the workflow to start running, got mode %v", m.mode)
+	}
+	if m.workflowRun.Steps[0].Command != "make build" {
+		t.Errorf("expected the ref resolved, got %q", m.workflowRun.Steps[0].Command)
+	}
+
+	// The first step exits non-zero; the workflow stops by default
+	result, _ = m.Update(stepDoneMsg{err: runner.RunCommand("exit 2")})
+	m = result.(Model)
+	if !m.workflowRun.Done() || m.workflowConfirm {
+		t.Fatal("expected the workflow to stop after the failed step")
+	}
+	view := m.viewWorkflow()
+	for _, want := range []string{"failed, exit 2", "skipped", "exit code 2"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("expected %q in the progress view, got:\n%s", want, view)
+		}
+	}
+
+	result, _ = m.handleKey(tea.KeyMsg{Type: tea.KeyEsc})
+	m = result.(Model)
+	if m.mode != viewCommands || m.workflowRun != nil {
+		t.Errorf("expected esc to return to the group, got mode %v", m.mode)
+	}
+
+	// Run again, this time succeeding and declining the confirmation
+	result, _ = m.handleKey(tea.KeyMsg{Type: tea.KeyEnter})
+	m = *result.(*Model)
+	result, cmd = m.Update(stepDoneMsg{})
+	m = result.(Model)
+	if !m.workflowConfirm || cmd != nil {
+		t.Fatal("expected to wait for confirmation before the push step")
+	}
+	result, cmd = m.handleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	m = result.(Model)
+	if cmd == nil || m.workflowRun.Current() != 2 {
+		t.Fatalf("expected the roll step to start, at step %d", m.workflowRun.Current())
+	}
+	result, _ = m.Update(stepDoneMsg{})
+	m = result.(Model)
+	if !strings.Contains(m.viewWorkflow(), "Workflow finished") {
+		t.Errorf("expected the workflow to finish, got:\n%s", m.viewWorkflow())
+	}
+}
+
+func TestActionMenuShowsEnvironment(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Config{
+		Groups: []config.Group{
+			{Name: "api", Cwd: dir, Env: map[string]string{"STAGE": "dev"}, Commands: []config.Command{
+				{ID: 1, Name: "migrate", Command: "make migrate", Env: map[string]string{"STAGE": "test"}},
+				{ID: 2, Name: "gone", Command: "ls", Cwd: filepath.Join(dir, "missing")},
+			}},
+		},
+	}
+
+	m := New(cfg)
+	result, _ := m.handleSelect()
+	m = *result.(*Model)
+	action := m
+	result, _ = action.handleSelect()
+	view := result.(*Model).View()
+	if !strings.Contains(view, "in "+dir) || !strings.Contains(view, "env STAGE=test") {
+		t.Errorf("expected the directory and env in the action menu, got:\n%s", view)
+	}
+
+	m.cursor = 1
+	result, _ = m.handleSelect()
+	if view := result.(*Model).View(); !strings.Contains(view, "does not exist") {
+		t.Errorf("expected a missing directory warning, got:\n%s", view)
+	}
+}
+
+func TestDangerousCommandNeedsTypedConfirmation(t *testing.T) {
+	cfg := &config.Config{
+		Groups: []config.Group{
+			{Name: "k8s", Commands: []config.Command{
+				{ID: 1, Name: "drop-ns", Command: "kubectl delete ns {{ns}}", DefaultAction: config.ActionRun},
+			}},
+		},
+	}
+
+	m := New(cfg, WithParamValues(map[string]string{"ns": "staging"}))
+	result, _ := m.handleSelect()
+	m = *result.(*Model)
+	result, _ = m.handleSelect()
+	pm := result.(*Model)
+	if pm.mode != viewActionSelect || pm.Selected() != nil {
+		t.Fatalf("expected the default run to be refused, got mode %v selected %+v", pm.mode, pm.Selected())
+	}
+	if view := pm.View(); !strings.Contains(view, "default run is ignored") {
+		t.Errorf("expected a dangerous warning, got:\n%s", view)
+	}
+
+	result, _ = pm.handleActionSelectKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	pm = result.(*Model)
+	if !pm.confirming || pm.Selected() != nil {
+		t.Fatal("expected run to ask for confirmation")
+	}
+	if view := pm.View(); !strings.Contains(view, "kubectl delete ns staging") {
+		t.Errorf("expected the rendered command in the confirmation, got:\n%s", view)
+	}
+
+	pm.formInputs[0].SetValue("drop")
+	result, _ = pm.handleActionSelectKey(tea.KeyMsg{Type: tea.KeyEnter})
+	vm := result.(Model)
+	if vm.formError == "" || vm.Selected() != nil {
+		t.Fatal("expected the wrong name to be refused")
+	}
+
+	vm.formInputs[0].SetValue("drop-ns")
+	result, _ = vm.handleActionSelectKey(tea.KeyMsg{Type: tea.KeyEnter})
+	done := result.(*Model)
+	if done.Selected() == nil || done.Selected().Command != "kubectl delete ns staging" || done.ActionResult() != "run" {
+		t.Fatalf("expected the confirmed command to run, got %+v", done.Selected())
+	}
+	if done.confirmed {
+		t.Error("expected the confirmation not to carry over")
+	}
+}
+
+// runOutput feeds the messages of cmd back into model until the command
+// running in the output pane exits.
+func runOutput(t *testing.T, model tea.Model, cmd tea.Cmd) Model {
+	t.Helper()
+	for cmd != nil {
+		model, cmd = model.Update(cmd())
+	}
+	m, ok := model.(Model)
+	if !ok {
+		m = *model.(*Model)
+	}
+	if !m.processDone {
+		t.Fatal("expected the command to have exited")
+	}
+	return m
+}
+
+func TestRunInOutputPane(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Config{
+		Groups: []config.Group{
+			{Name: "g", Commands: []config.Command{
+				{ID: 1, Name: "build", Command: "echo building; pwd; echo oops >&2; exit 2", Cwd: dir},
+			}},
+		},
+	}
+
+	m := New(cfg)
+	result, _ := m.handleSelect()
+	m = *result.(*Model)
+	result, _ = m.handleSelect()
+	result, cmd := result.(*Model).handleActionSelectKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("o")})
+	if pm := result.(*Model); pm.mode != viewOutput || pm.Selected() != nil || pm.quitting {
+		t.Fatalf("expected the output pane without quitting, got mode %v", pm.mode)
+	}
+
+	done := runOutput(t, result, cmd)
+	if got := strings.Join(done.output, ","); got != "building,"+dir+",oops" {
+		t.Errorf("output = %q", got)
+	}
+	if view := done.View(); !strings.Contains(view, "Exit 2 after") {
+		t.Errorf("expected the exit code and duration, got:\n%s", view)
+	}
+
+	result, _ = done.handleOutputKey(tea.KeyMsg{Type: tea.KeyEsc})
+	if back := result.(Model); back.mode != viewCommands || back.groupPath != "g" {
+		t.Errorf("expected to return to the group, got mode %v", back.mode)
+	}
+}
+
+func TestOutputPaneForwardsCtrlC(t *testing.T) {
+	cfg := &config.Config{
+		Groups: []config.Group{
+			{Name: "g", Commands: []config.Command{
+				{ID: 1, Name: "wait", Command: "echo waiting; sleep 10"},
+			}},
+		},
+	}
+
+	m := New(cfg)
+	m.actionCmd = &config.FlatCommand{ID: 1, GroupName: "g", Name: "wait", Command: "echo waiting; sleep 10"}
+	m.runTarget = runInPane
+	result, cmd := m.executeAction(config.ActionRun)
+	result, cmd = result.Update(cmd()) // the first line of output
+
+	result, _ = result.(Model).handleOutputKey(tea.KeyMsg{Type: tea.KeyCtrlC})
+	if pm := result.(Model); pm.quitting || !pm.interrupted {
+		t.Fatal("expected ctrl+c to interrupt the command rather than quit")
+	}
+
+	done := runOutput(t, result, cmd)
+	if view := done.View(); !strings.Contains(view, "Interrupted after") {
+		t.Errorf("expected the command to be interrupted, got:\n%s", view)
+	}
+}
+
+func TestRunsAreLoggedAndListed(t *testing.T) {
+	log, err := runlog.LoadFrom(filepath.Join(t.TempDir(), runlog.FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		Groups: []config.Group{
+			{Name: "g", Commands: []config.Command{
+				{ID: 1, Name: "check", Command: "echo checking; exit 4"},
+			}},
+		},
+	}
+
+	m := New(cfg, WithRunLog(log))
+	result, _ := m.handleSelect()
+	m = *result.(*Model)
+	action := m
+	result, _ = action.handleSelect()
+	result, cmd := result.(*Model).handleActionSelectKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("o")})
+	done := runOutput(t, result, cmd)
+
+	run, ok := log.Last(1)
+	if !ok || run.Exit != 4 || run.Output != "checking" || run.Command != "echo checking; exit 4" || run.Dir == "" {
+		t.Fatalf("expected the run to be logged, got %+v", run)
+	}
+	result, _ = done.handleOutputKey(tea.KeyMsg{Type: tea.KeyEsc})
+	m = result.(Model)
+	if view := m.View(); !strings.Contains(view, "✗ 4") {
+		t.Errorf("expected the last exit status next to the command, got:\n%s", view)
+	}
+
+	result, _ = m.handleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("L")})
+	m = result.(Model)
+	if m.mode != viewRuns || len(m.runs) != 1 {
+		t.Fatalf("expected the runs view with one run, got mode %v and %d runs", m.mode, len(m.runs))
+	}
+
+	// Enter shows the kept output
+	shown := m
+	result, _ = shown.handleSelect()
+	pm := result.(*Model)
+	if pm.mode != viewOutput || !strings.Contains(pm.View(), "checking") || !strings.Contains(pm.View(), "Exit 4") {
+		t.Errorf("expected the logged output, got:\n%s", pm.View())
+	}
+
+	// r runs it again, adding to the log, and returns to the list
+	result, cmd = m.handleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	done = runOutput(t, result, cmd)
+	result, _ = done.handleOutputKey(tea.KeyMsg{Type: tea.KeyEsc})
+	if m = result.(Model); m.mode != viewRuns || len(m.runs) != 2 {
+		t.Errorf("expected to return to two runs, got mode %v and %d runs", m.mode, len(m.runs))
+	}
+}
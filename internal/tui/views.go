@@ -2,9 +2,18 @@ package tui
 
 import (
 	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/sammcj/bkmk/internal/config"
+	"github.com/sammcj/bkmk/internal/history"
+	"github.com/sammcj/bkmk/internal/runlog"
+	"github.com/sammcj/bkmk/internal/workflow"
 )
 
 func (m Model) renderHeader() string {
@@ -15,7 +24,14 @@ func (m Model) renderHeader() string {
 	configStyle := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("241"))
 
+	crumbStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("141"))
+
 	left := titleStyle.Render("bkmk: Command Bookmarks")
+	// Breadcrumbs for the group being browsed
+	if m.mode == viewCommands && m.groupPath != "" {
+		left += crumbStyle.Render(" › " + strings.Join(config.SplitPath(m.groupPath), " › "))
+	}
 	right := configStyle.Render("Config: " + m.configPath)
 
 	leftWidth := lipgloss.Width(left)
@@ -62,6 +78,33 @@ func (m Model) View() string {
 		content = m.viewHistoryAddDetails()
 	case viewActionSelect:
 		content = m.viewActionSelect()
+	case viewParamInput:
+		content = m.viewParamInput()
+	case viewTags:
+		content = m.viewTags()
+	case viewTagCommands:
+		content = m.viewTagCommands()
+	case viewUsage:
+		content = m.viewUsage()
+	case viewBackups:
+		content = m.viewBackups()
+	case viewRestoreConfirm:
+		content = m.viewRestoreConfirm()
+	case viewSuggest:
+		content = m.viewSuggest()
+	case viewWorkflow:
+		content = m.viewWorkflow()
+	case viewOutput:
+		content = m.viewOutput()
+	case viewRuns:
+		content = m.viewRuns()
+	}
+
+	if m.reloadError != "" {
+		bannerStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("196")).
+			Width(max(m.width, 20))
+		content = bannerStyle.Render("Config not reloaded: "+m.reloadError) + "\n\n" + content
 	}
 
 	return content
@@ -78,6 +121,9 @@ func (m Model) viewGroups() string {
 		Foreground(lipgloss.Color("241")).
 		MarginTop(1)
 
+	noticeStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("214"))
+
 	s := m.renderHeader() + "\n"
 
 	if len(m.groups) == 0 {
@@ -90,17 +136,18 @@ func (m Model) viewGroups() string {
 				cursor = "> "
 				style = selectedStyle
 			}
-			cmdCount := len(g.Commands)
-			plural := "s"
-			if cmdCount == 1 {
-				plural = ""
+			name := g.Name
+			if len(g.Groups) > 0 {
+				name += "/"
 			}
-			countStr := fmt.Sprintf("%d", cmdCount)
-			s += style.Render(cursor+g.Name) + lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Render(" ("+countStr+" cmd"+plural+")") + "\n"
+			s += style.Render(cursor+name) + lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Render(" ("+groupSummary(g)+")") + "\n"
 		}
 	}
 
-	s += "\n" + helpStyle.Render("j/k navigate | enter select | a add | e edit | d delete | s show all | h history | o open config | / search | q quit")
+	if m.notice != "" {
+		s += "\n" + noticeStyle.Render(m.notice) + "\n"
+	}
+	s += "\n" + helpStyle.Render("j/k navigate | enter select | a add | e edit | d delete | s show all | t tags | u recent | L runs | h history | b backups | o open config | / search | q quit")
 
 	return s
 }
@@ -116,6 +163,13 @@ func (m Model) viewCommands() string {
 		Foreground(lipgloss.Color("170")).
 		Bold(true)
 
+	subgroupStyle := lipgloss.NewStyle().
+		PaddingLeft(2).
+		Foreground(lipgloss.Color("141"))
+
+	countStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("241"))
+
 	cmdStyle := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("245"))
 
@@ -127,18 +181,37 @@ func (m Model) viewCommands() string {
 		Foreground(lipgloss.Color("241")).
 		MarginTop(1)
 
+	noticeStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("214"))
+
 	s := m.renderHeader()
-	if !m.selectedGroupValid() {
+	if !m.currentGroupValid() {
 		s += groupStyle.Render("No group selected") + "\n\n"
 		return s
 	}
-	s += groupStyle.Render("Group: "+m.groups[m.selectedGroup].Name) + "\n\n"
+	s += "\n"
+
+	for i, sub := range m.subgroups {
+		cursor := "  "
+		style := subgroupStyle
+		if m.cursor == i {
+			cursor = "> "
+			style = selectedStyle
+		}
+		s += style.Render(cursor+sub.Name+"/") + countStyle.Render(" ("+groupSummary(sub)+")") + "\n"
+	}
+	if len(m.subgroups) > 0 {
+		s += "\n"
+	}
 
 	if len(m.commands) == 0 {
-		s += itemStyle.Render("No commands in this group. Press 'a' to add one.") + "\n"
+		if len(m.subgroups) == 0 {
+			s += itemStyle.Render("No commands in this group. Press 'a' to add one.") + "\n"
+		}
 	} else {
 		idStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("244"))
-		for i, cmd := range m.commands {
+		for j, cmd := range m.commands {
+			i := len(m.subgroups) + j
 			cursor := "  "
 			style := itemStyle
 			if m.cursor == i {
@@ -146,8 +219,8 @@ func (m Model) viewCommands() string {
 				style = selectedStyle
 			}
 			idTag := idStyle.Render(fmt.Sprintf("[%d] ", cmd.ID))
-			line := style.Render(cursor) + idTag + style.Render(cmd.Name)
-			line += "\n" + itemStyle.Render("    ") + cmdStyle.Render(cmd.Command)
+			line := style.Render(cursor) + idTag + style.Render(cmd.Name) + m.renderLastRun(cmd.ID) + renderTags(cmd.Tags) + renderSource(cmd.Source)
+			line += renderCommandLines(cmd.Summary(), itemStyle, cmdStyle)
 			if cmd.Description != "" {
 				line += "\n" + itemStyle.Render("    ") + descStyle.Render(cmd.Description)
 			}
@@ -155,11 +228,131 @@ func (m Model) viewCommands() string {
 		}
 	}
 
-	s += helpStyle.Render("j/k navigate | enter select | a add | e edit | d delete | s show all | h history | o open config | esc back | q quit")
+	if m.notice != "" {
+		s += noticeStyle.Render(m.notice) + "\n"
+	}
+	s += helpStyle.Render("j/k navigate | enter select | a add | A add subgroup | e edit | d delete | s show all | t tags | u recent | L runs | h history | b backups | o open config | esc back | q quit")
+
+	return s
+}
+
+// timeAgo formats how long ago t was, e.g. "5m ago" or "3d ago".
+func timeAgo(t, now time.Time) string {
+	d := now.Sub(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+}
+
+// groupSummary describes a group's size, e.g. "3 cmds" or "1 cmd, 2 groups".
+func groupSummary(g config.Group) string {
+	plural := func(n int, word string) string {
+		if n == 1 {
+			return fmt.Sprintf("%d %s", n, word)
+		}
+		return fmt.Sprintf("%d %ss", n, word)
+	}
+	summary := plural(len(g.Commands), "cmd")
+	if len(g.Groups) > 0 {
+		summary += ", " + plural(len(g.Groups), "group")
+	}
+	return summary
+}
+
+// renderTags renders tags as dimmed #tag labels, prefixed with a space.
+func renderTags(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	tagStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("72"))
+	labels := make([]string, len(tags))
+	for i, tag := range tags {
+		labels[i] = "#" + tag
+	}
+	return " " + tagStyle.Render(strings.Join(labels, " "))
+}
+
+// renderSource marks a command from an included file as read-only, naming the
+// file, prefixed with a space.
+func renderSource(source string) string {
+	if source == "" {
+		return ""
+	}
+	sourceStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+	return " " + sourceStyle.Render("[read-only: "+filepath.Base(source)+"]")
+}
 
+// renderLastRun marks how the last run of a command exited, if it was run.
+func (m Model) renderLastRun(id int) string {
+	if m.runLog == nil {
+		return ""
+	}
+	run, ok := m.runLog.Last(id)
+	if !ok {
+		return ""
+	}
+	return " " + runStatus(run)
+}
+
+// runStatus renders a ✓ for a run that succeeded, or a ✗ and its exit code.
+func runStatus(run runlog.Entry) string {
+	if run.Failed() {
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render(fmt.Sprintf("✗ %d", run.Exit))
+	}
+	return lipgloss.NewStyle().Foreground(lipgloss.Color("42")).Render("✓")
+}
+
+// newlineMarker stands in for line breaks where a command must fit on one
+// line: in history rows and in the single-line command input of the forms.
+const newlineMarker = "↵"
+
+// maxPreviewLines caps how much of a long multi-line command a preview shows.
+const maxPreviewLines = 8
+
+// singleLine shows a multi-line command on one line.
+func singleLine(cmd string) string {
+	return strings.ReplaceAll(cmd, "\n", newlineMarker)
+}
+
+// multiLine turns a command edited with singleLine back into separate lines.
+func multiLine(value string) string {
+	return strings.ReplaceAll(value, newlineMarker, "\n")
+}
+
+// renderCommandLines renders a command below its list item, indenting every
+// line of a multi-line command.
+func renderCommandLines(cmd string, indent, cmdStyle lipgloss.Style) string {
+	var s string
+	for line := range strings.SplitSeq(cmd, "\n") {
+		s += "\n" + indent.Render("    ") + cmdStyle.Render(line)
+	}
 	return s
 }
 
+// previewCommand labels cmd for a form or menu header, truncating long lines
+// to width and lining up the lines of a multi-line command.
+func previewCommand(cmd string, width int) string {
+	const label = "Command: "
+	lines := strings.Split(cmd, "\n")
+	if len(lines) > maxPreviewLines {
+		more := len(lines) - maxPreviewLines + 1
+		lines = append(lines[:maxPreviewLines-1], fmt.Sprintf("... %d more lines", more))
+	}
+	for i, line := range lines {
+		if width > 20 {
+			lines[i] = ansi.Truncate(line, width-4-len(label), "...")
+		}
+	}
+	return label + strings.Join(lines, "\n"+strings.Repeat(" ", len(label)))
+}
+
 func (m Model) viewSearch() string {
 	titleStyle := lipgloss.NewStyle().
 		Bold(true).
@@ -205,8 +398,8 @@ func (m Model) viewSearch() string {
 				style = selectedStyle
 			}
 			idTag := idStyle.Render(fmt.Sprintf("[%d] ", cmd.ID))
-			line := style.Render(cursor) + idTag + style.Render(cmd.Name) + " " + groupTagStyle.Render(cmd.GroupName)
-			line += "\n" + itemStyle.Render("    ") + cmdStyle.Render(cmd.Command)
+			line := style.Render(cursor) + idTag + style.Render(cmd.Name) + " " + groupTagStyle.Render(cmd.GroupName) + renderTags(cmd.Tags) + renderSource(cmd.Source)
+			line += renderCommandLines(cmd.Summary(), itemStyle, cmdStyle)
 			if cmd.Description != "" {
 				line += "\n" + itemStyle.Render("    ") + descStyle.Render(cmd.Description)
 			}
@@ -286,8 +479,8 @@ func (m Model) viewAllCommands() string {
 				style = selectedStyle
 			}
 			idTag := idStyle.Render(fmt.Sprintf("[%d] ", cmd.ID))
-			line := style.Render(cursor) + idTag + style.Render(cmd.Name) + " " + groupTagStyle.Render(cmd.GroupName)
-			line += "\n" + itemStyle.Render("    ") + cmdStyle.Render(cmd.Command)
+			line := style.Render(cursor) + idTag + style.Render(cmd.Name) + " " + groupTagStyle.Render(cmd.GroupName) + renderTags(cmd.Tags) + renderSource(cmd.Source)
+			line += renderCommandLines(cmd.Summary(), itemStyle, cmdStyle)
 			if cmd.Description != "" {
 				line += "\n" + itemStyle.Render("    ") + descStyle.Render(cmd.Description)
 			}
@@ -310,6 +503,332 @@ func (m Model) viewAllCommands() string {
 		}
 	}
 
+	s += helpStyle.Render("j/k navigate | enter select | t tags | u recent | esc back | q quit")
+
+	return s
+}
+
+func (m Model) viewTags() string {
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("205")).
+		MarginBottom(1)
+
+	itemStyle := lipgloss.NewStyle().PaddingLeft(2)
+	selectedStyle := lipgloss.NewStyle().
+		PaddingLeft(2).
+		Foreground(lipgloss.Color("170")).
+		Bold(true)
+
+	helpStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("241")).
+		MarginTop(1)
+
+	s := titleStyle.Render("bkmk: Tags") + "\n\n"
+
+	if len(m.tags) == 0 {
+		s += itemStyle.Render("No tags yet. Edit a command to add some.") + "\n"
+	} else {
+		for i, tag := range m.tags {
+			cursor := "  "
+			style := itemStyle
+			if m.cursor == i {
+				cursor = "> "
+				style = selectedStyle
+			}
+			plural := "s"
+			if tag.Count == 1 {
+				plural = ""
+			}
+			s += style.Render(cursor+"#"+tag.Name) + lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Render(fmt.Sprintf(" (%d cmd%s)", tag.Count, plural)) + "\n"
+		}
+	}
+
+	s += "\n" + helpStyle.Render("j/k navigate | enter select | esc back | q quit")
+
+	return s
+}
+
+func (m Model) viewUsage() string {
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("205")).
+		MarginBottom(1)
+
+	itemStyle := lipgloss.NewStyle().PaddingLeft(2)
+	selectedStyle := lipgloss.NewStyle().
+		PaddingLeft(2).
+		Foreground(lipgloss.Color("170")).
+		Bold(true)
+
+	groupTagStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("141")).
+		Background(lipgloss.Color("236")).
+		Padding(0, 1)
+
+	cmdStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("245"))
+
+	statStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("241")).
+		Italic(true)
+
+	helpStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("241")).
+		MarginTop(1)
+
+	title := "bkmk: Most Used"
+	if m.usageByRecent {
+		title = "bkmk: Recently Used"
+	}
+	s := titleStyle.Render(title) + "\n\n"
+
+	if len(m.usageCommands) == 0 {
+		s += itemStyle.Render("Nothing used yet. Run or copy a command and it will show up here.") + "\n\n"
+	} else {
+		idStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("244"))
+		now := time.Now()
+
+		// Calculate available lines for items, three lines per command
+		reservedLines := 6
+		displayCount := max(2, (m.height-reservedLines)/3)
+		totalItems := len(m.usageCommands)
+		displayCount = min(displayCount, totalItems)
+
+		// Calculate scroll offset to keep cursor visible
+		offset := 0
+		if m.cursor >= displayCount {
+			offset = m.cursor - displayCount + 1
+		}
+
+		for i := offset; i < min(offset+displayCount, totalItems); i++ {
+			cmd := m.usageCommands[i]
+			cursor := "  "
+			style := itemStyle
+			if m.cursor == i {
+				cursor = "> "
+				style = selectedStyle
+			}
+			stat := m.usage.Get(cmd.ID)
+			idTag := idStyle.Render(fmt.Sprintf("[%d] ", cmd.ID))
+			line := style.Render(cursor) + idTag + style.Render(cmd.Name) + " " + groupTagStyle.Render(cmd.GroupName) + renderTags(cmd.Tags) + renderSource(cmd.Source)
+			line += renderCommandLines(cmd.Summary(), itemStyle, cmdStyle)
+			line += "\n" + itemStyle.Render("    ") + statStyle.Render(fmt.Sprintf("%d runs, %d copies, %d inserts · %s",
+				stat.Runs, stat.Copies, stat.Inserts, timeAgo(stat.LastUsed, now)))
+			s += line + "\n\n"
+		}
+	}
+
+	s += helpStyle.Render("j/k navigate | enter select | tab most used/recent | esc back | q quit")
+
+	return s
+}
+
+func (m Model) viewRuns() string {
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("205")).
+		MarginBottom(1)
+
+	itemStyle := lipgloss.NewStyle().PaddingLeft(2)
+	selectedStyle := lipgloss.NewStyle().
+		PaddingLeft(2).
+		Foreground(lipgloss.Color("170")).
+		Bold(true)
+
+	cmdStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("245"))
+
+	statStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("241")).
+		Italic(true)
+
+	noticeStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("214"))
+
+	helpStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("241")).
+		MarginTop(1)
+
+	s := titleStyle.Render("bkmk: Past Runs") + "\n\n"
+
+	if len(m.runs) == 0 {
+		s += itemStyle.Render("No runs yet. Run a command and it will show up here.") + "\n\n"
+	} else {
+		idStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("244"))
+		now := time.Now()
+
+		// Two lines per run, plus a blank line between them
+		reservedLines := 6
+		displayCount := min(max(2, (m.height-reservedLines)/3), len(m.runs))
+		offset := 0
+		if m.cursor >= displayCount {
+			offset = m.cursor - displayCount + 1
+		}
+
+		maxCmdWidth := m.width - 8
+		for i := offset; i < min(offset+displayCount, len(m.runs)); i++ {
+			run := m.runs[i]
+			cursor := "  "
+			style := itemStyle
+			if m.cursor == i {
+				cursor = "> "
+				style = selectedStyle
+			}
+			line := style.Render(cursor) + runStatus(run) + " " + idStyle.Render(fmt.Sprintf("[%d] ", run.ID)) + style.Render(run.Label())
+			line += " " + statStyle.Render(fmt.Sprintf("%s · took %s", timeAgo(run.Started, now), run.Duration.Round(time.Millisecond)))
+			cmd := "$ " + singleLine(run.Command)
+			if run.Dir != "" {
+				cmd += "  (in " + run.Dir + ")"
+			}
+			if maxCmdWidth > 10 {
+				cmd = ansi.Truncate(cmd, maxCmdWidth, "...")
+			}
+			line += "\n" + itemStyle.Render("    ") + cmdStyle.Render(cmd)
+			s += line + "\n\n"
+		}
+	}
+
+	if m.notice != "" {
+		s += noticeStyle.Render(m.notice) + "\n"
+	}
+	s += helpStyle.Render("j/k navigate | enter show output | r re-run | esc back | q quit")
+
+	return s
+}
+
+func (m Model) viewBackups() string {
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("205")).
+		MarginBottom(1)
+
+	itemStyle := lipgloss.NewStyle().PaddingLeft(2)
+	selectedStyle := lipgloss.NewStyle().
+		PaddingLeft(2).
+		Foreground(lipgloss.Color("170")).
+		Bold(true)
+
+	summaryStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("241"))
+
+	errorStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("196"))
+
+	helpStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("241")).
+		MarginTop(1)
+
+	s := titleStyle.Render("bkmk: Config Backups") + "\n\n"
+
+	if len(m.backups) == 0 {
+		s += itemStyle.Render("No backups yet. One is taken each time the config is saved.") + "\n"
+	} else {
+		now := time.Now()
+		reservedLines := 7
+		displayCount := min(max(3, m.height-reservedLines), len(m.backups))
+		offset := 0
+		if m.cursor >= displayCount {
+			offset = m.cursor - displayCount + 1
+		}
+
+		for i := offset; i < min(offset+displayCount, len(m.backups)); i++ {
+			b := m.backups[i]
+			cursor := "  "
+			style := itemStyle
+			if m.cursor == i {
+				cursor = "> "
+				style = selectedStyle
+			}
+			s += style.Render(cursor+b.Time.Format("2006-01-02 15:04:05")) +
+				summaryStyle.Render(fmt.Sprintf("  %-9s %s", timeAgo(b.Time, now), b.summary)) + "\n"
+		}
+		s += "\n" + summaryStyle.Render("Changes in the current config since each backup: + added, - removed, ~ changed") + "\n"
+	}
+
+	if m.backupError != "" {
+		s += "\n" + errorStyle.Render("Error: "+m.backupError) + "\n"
+	}
+
+	s += helpStyle.Render("j/k navigate | enter restore | esc back | q quit")
+
+	return s
+}
+
+func (m Model) viewRestoreConfirm() string {
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("196")).
+		MarginBottom(1)
+
+	messageStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("255")).
+		MarginBottom(1)
+
+	helpStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("241")).
+		MarginTop(1)
+
+	b := m.backups[m.cursor]
+	s := titleStyle.Render("bkmk: Confirm Restore") + "\n\n"
+	s += messageStyle.Render(fmt.Sprintf("Restore the config from %s (%s)?", b.Time.Format("2006-01-02 15:04:05"), b.summary)) + "\n"
+	s += messageStyle.Render("The current config is backed up first.") + "\n"
+	s += "\n" + helpStyle.Render("y confirm | n/esc cancel")
+
+	return s
+}
+
+func (m Model) viewTagCommands() string {
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("205")).
+		MarginBottom(1)
+
+	itemStyle := lipgloss.NewStyle().PaddingLeft(2)
+	selectedStyle := lipgloss.NewStyle().
+		PaddingLeft(2).
+		Foreground(lipgloss.Color("170")).
+		Bold(true)
+
+	groupTagStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("141")).
+		Background(lipgloss.Color("236")).
+		Padding(0, 1)
+
+	cmdStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("245"))
+
+	descStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("241")).
+		Italic(true)
+
+	helpStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("241")).
+		MarginTop(1)
+
+	s := titleStyle.Render("bkmk: Tag #"+m.selectedTag) + "\n\n"
+
+	if len(m.tagCommands) == 0 {
+		s += itemStyle.Render("No commands with this tag.") + "\n"
+	} else {
+		idStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("244"))
+		for i, cmd := range m.tagCommands {
+			cursor := "  "
+			style := itemStyle
+			if m.cursor == i {
+				cursor = "> "
+				style = selectedStyle
+			}
+			idTag := idStyle.Render(fmt.Sprintf("[%d] ", cmd.ID))
+			line := style.Render(cursor) + idTag + style.Render(cmd.Name) + " " + groupTagStyle.Render(cmd.GroupName) + renderTags(cmd.Tags) + renderSource(cmd.Source)
+			line += renderCommandLines(cmd.Summary(), itemStyle, cmdStyle)
+			if cmd.Description != "" {
+				line += "\n" + itemStyle.Render("    ") + descStyle.Render(cmd.Description)
+			}
+			s += line + "\n\n"
+		}
+	}
+
 	s += helpStyle.Render("j/k navigate | enter select | esc back | q quit")
 
 	return s
@@ -351,8 +870,8 @@ func (m Model) viewHistory() string {
 	} else {
 		// Calculate available lines for history items
 		// Header: title (1) + blank (1) + search (1) + blank (1) = 4
-		// Footer: blank (1) + help (1) + optional "more" line (1) = 3
-		reservedLines := 8
+		// Footer: blank (1) + help (1) + optional "more" line (1) + details (1) = 4
+		reservedLines := 9
 		availableLines := m.height - reservedLines
 		if availableLines < 5 {
 			availableLines = 5 // minimum visible items
@@ -397,10 +916,10 @@ func (m Model) viewHistory() string {
 			timeWidth := len(timeStr)
 
 			// Truncate long commands for display (account for timestamp width)
-			cmd := entry.Command
+			cmd := singleLine(entry.Command)
 			maxCmdWidth := m.width - 10 - timeWidth
-			if len(cmd) > maxCmdWidth && maxCmdWidth > 10 {
-				cmd = cmd[:maxCmdWidth-3] + "..."
+			if maxCmdWidth > 10 {
+				cmd = ansi.Truncate(cmd, maxCmdWidth, "...")
 			}
 
 			if timeStr != "" {
@@ -426,11 +945,262 @@ func (m Model) viewHistory() string {
 		}
 	}
 
+	if m.cursor < len(m.filteredHistory) {
+		if details := historyDetails(m.filteredHistory[m.cursor]); details != "" {
+			s += lipgloss.NewStyle().Foreground(lipgloss.Color("246")).Render(details) + "\n"
+		}
+	}
+
 	s += "\n" + helpStyle.Render("↑/↓ navigate | pgup/pgdn page | enter select | esc back")
 
 	return s
 }
 
+func (m Model) viewSuggest() string {
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("205")).
+		MarginBottom(1)
+
+	itemStyle := lipgloss.NewStyle().PaddingLeft(2)
+	selectedStyle := lipgloss.NewStyle().
+		PaddingLeft(2).
+		Foreground(lipgloss.Color("170")).
+		Bold(true)
+
+	cmdStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("252"))
+
+	statStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("241")).
+		Italic(true)
+
+	noticeStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("214"))
+
+	helpStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("241")).
+		MarginTop(1)
+
+	s := titleStyle.Render("bkmk: Suggested Bookmarks") + "\n\n"
+
+	if len(m.suggestions) == 0 {
+		s += itemStyle.Render("No more suggestions. Frequently used commands will show up here.") + "\n"
+	} else {
+		// Two lines per suggestion: the command, then its count or template
+		displayCount := min(m.suggestPageSize(), len(m.suggestions))
+		totalItems := len(m.suggestions)
+
+		// Calculate scroll offset to keep cursor visible
+		offset := 0
+		if m.cursor >= displayCount {
+			offset = m.cursor - displayCount + 1
+		}
+
+		maxCmdWidth := m.width - 12
+		endIdx := min(offset+displayCount, totalItems)
+		for i := offset; i < endIdx; i++ {
+			sg := m.suggestions[i]
+			cursor := "  "
+			style := itemStyle
+			if m.cursor == i {
+				cursor = "> "
+				style = selectedStyle
+			}
+			check := "[ ] "
+			if sg.selected {
+				check = "[x] "
+			}
+
+			cmd := singleLine(sg.bookmarkCommand())
+			if maxCmdWidth > 10 {
+				cmd = ansi.Truncate(cmd, maxCmdWidth, "...")
+			}
+			stat := fmt.Sprintf("used %d times", sg.Count)
+			if sg.Template != "" {
+				if sg.useTemplate {
+					stat += fmt.Sprintf(" across %d variants, e.g. %s", sg.Variants, singleLine(sg.Command))
+				} else {
+					stat += fmt.Sprintf(" across %d variants (t for template)", sg.Variants)
+				}
+				if maxCmdWidth > 10 {
+					stat = ansi.Truncate(stat, maxCmdWidth, "...")
+				}
+			}
+			s += style.Render(cursor+check) + cmdStyle.Render(cmd) + "\n"
+			s += itemStyle.Render("      ") + statStyle.Render(stat) + "\n"
+		}
+
+		if offset > 0 || endIdx < totalItems {
+			s += itemStyle.Render(fmt.Sprintf("%d-%d of %d", offset+1, endIdx, totalItems)) + "\n"
+		}
+	}
+
+	if m.notice != "" {
+		s += "\n" + noticeStyle.Render(m.notice) + "\n"
+	}
+
+	s += helpStyle.Render("j/k navigate | space select | a all | t template/command | enter bookmark | q quit")
+
+	return s
+}
+
+func (m Model) viewWorkflow() string {
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("205")).
+		MarginBottom(1)
+
+	itemStyle := lipgloss.NewStyle().PaddingLeft(2)
+	cmdStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("245"))
+
+	statusStyles := map[workflow.Status]lipgloss.Style{
+		workflow.Pending:   lipgloss.NewStyle().Foreground(lipgloss.Color("241")),
+		workflow.Running:   lipgloss.NewStyle().Foreground(lipgloss.Color("170")).Bold(true),
+		workflow.Succeeded: lipgloss.NewStyle().Foreground(lipgloss.Color("42")),
+		workflow.Failed:    lipgloss.NewStyle().Foreground(lipgloss.Color("196")),
+		workflow.Skipped:   lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Italic(true),
+	}
+	statusIcons := map[workflow.Status]string{
+		workflow.Pending:   "·",
+		workflow.Running:   "▶",
+		workflow.Succeeded: "✓",
+		workflow.Failed:    "✗",
+		workflow.Skipped:   "-",
+	}
+
+	promptStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("214")).
+		Bold(true)
+
+	helpStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("241")).
+		MarginTop(1)
+
+	s := titleStyle.Render("bkmk: Workflow "+m.workflowName) + "\n\n"
+	if m.workflowRun == nil {
+		return s
+	}
+
+	maxCmdWidth := m.width - 10
+	for i, step := range m.workflowRun.Steps {
+		res := m.workflowRun.Results[i]
+		style := statusStyles[res.Status]
+		status := res.Status.String()
+		if res.Status == workflow.Failed {
+			status = fmt.Sprintf("failed, exit %d", res.Exit)
+		}
+		s += itemStyle.Render(style.Render(fmt.Sprintf("%s %d. %s", statusIcons[res.Status], i+1, step.Label()))) +
+			" " + style.Render("("+status+")") + "\n"
+		cmd := singleLine(step.Command)
+		if maxCmdWidth > 10 {
+			cmd = ansi.Truncate(cmd, maxCmdWidth, "...")
+		}
+		s += itemStyle.Render("    ") + cmdStyle.Render(cmd) + "\n"
+	}
+	s += "\n"
+
+	switch {
+	case m.workflowConfirm:
+		i := m.workflowRun.Current()
+		s += promptStyle.Render(fmt.Sprintf("Run step %d, %s?", i+1, m.workflowRun.Steps[i].Label())) + "\n"
+		s += helpStyle.Render("y run | n skip | q stop here")
+	case m.workflowRun.Done():
+		if code := m.workflowRun.ExitCode(); code != 0 {
+			s += statusStyles[workflow.Failed].Render(fmt.Sprintf("Workflow failed with exit code %d", code)) + "\n"
+		} else {
+			s += statusStyles[workflow.Succeeded].Render("Workflow finished") + "\n"
+		}
+		s += helpStyle.Render("enter/esc back | q quit")
+	default:
+		s += helpStyle.Render("running...")
+	}
+
+	return s
+}
+
+func (m Model) viewOutput() string {
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("205")).
+		MarginBottom(1)
+
+	cmdStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("245"))
+
+	successStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
+	failStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+	runningStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("170")).Bold(true)
+
+	helpStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("241")).
+		MarginTop(1)
+
+	s := titleStyle.Render("bkmk: Run "+m.outputRun.Label()) + "\n\n"
+	cmd := singleLine(m.outputRun.Command)
+	if m.width > 10 {
+		cmd = ansi.Truncate(cmd, m.width-2, "...")
+	}
+	s += cmdStyle.Render("$ "+cmd) + "\n\n"
+	s += m.outputView.View() + "\n\n"
+
+	took := m.outputRun.Duration.Round(time.Millisecond).String()
+	switch {
+	case !m.processDone && m.interrupted:
+		s += runningStyle.Render("Interrupting...") + "\n"
+		s += helpStyle.Render("j/k scroll | ctrl+c kill")
+	case !m.processDone:
+		s += runningStyle.Render("Running...") + "\n"
+		s += helpStyle.Render("j/k scroll | g/G top/bottom | ctrl+c interrupt")
+	default:
+		code := m.outputRun.Exit
+		switch {
+		case m.interrupted && code != 0:
+			s += failStyle.Render(fmt.Sprintf("✗ Interrupted after %s (exit %d)", took, code)) + "\n"
+		case code != 0:
+			s += failStyle.Render(fmt.Sprintf("✗ Exit %d after %s", code, took)) + "\n"
+		default:
+			s += successStyle.Render("✓ Exit 0 after "+took) + "\n"
+		}
+		s += helpStyle.Render("j/k scroll | g/G top/bottom | enter/esc back | q quit")
+	}
+
+	return s
+}
+
+// environmentLines describes the working directory and variables a command
+// runs with, sorted by name.
+func environmentLines(env config.Environment) []string {
+	var lines []string
+	if env.Dir != "" {
+		lines = append(lines, "in "+env.Dir)
+	}
+	for _, name := range slices.Sorted(maps.Keys(env.Env)) {
+		lines = append(lines, "env "+name+"="+env.Env[name])
+	}
+	return lines
+}
+
+// historyDetails describes where and how a history entry ran, for sources
+// that record it.
+func historyDetails(entry history.Entry) string {
+	var parts []string
+	if entry.Dir != "" {
+		parts = append(parts, "in "+entry.Dir)
+	}
+	if entry.Host != "" {
+		parts = append(parts, "on "+entry.Host)
+	}
+	if entry.Failed() {
+		parts = append(parts, fmt.Sprintf("exit %d", entry.Exit))
+	}
+	if entry.Duration > 0 {
+		parts = append(parts, "took "+entry.Duration.Round(time.Millisecond).String())
+	}
+	return strings.Join(parts, " | ")
+}
+
 func (m Model) viewHistorySelectGroup() string {
 	titleStyle := lipgloss.NewStyle().
 		Bold(true).
@@ -459,27 +1229,26 @@ func (m Model) viewHistorySelectGroup() string {
 	s := titleStyle.Render("bkmk: Select Group") + "\n\n"
 
 	// Show selected command preview
-	cmdPreview := m.selectedHistCmd
-	if len(cmdPreview) > m.width-4 && m.width > 20 {
-		cmdPreview = cmdPreview[:m.width-7] + "..."
+	s += cmdPreviewStyle.Render(previewCommand(m.selectedHistCmd, m.width)) + "\n\n"
+	if m.suggesting && len(m.suggestQueue) > 0 {
+		s += itemStyle.Render(fmt.Sprintf("%d more suggestion(s) to bookmark after this", len(m.suggestQueue))) + "\n\n"
 	}
-	s += cmdPreviewStyle.Render("Command: "+cmdPreview) + "\n\n"
 
 	// List groups
-	for i, g := range m.groups {
+	for i, path := range m.groupPaths {
 		cursor := "  "
 		style := itemStyle
 		if m.cursor == i {
 			cursor = "> "
 			style = selectedStyle
 		}
-		s += style.Render(cursor+g.Name) + "\n"
+		s += style.Render(cursor+path) + "\n"
 	}
 
 	// "Create new group" option
 	cursor := "  "
 	style := newGroupStyle
-	if m.cursor == len(m.groups) {
+	if m.cursor == len(m.groupPaths) {
 		cursor = "> "
 		style = lipgloss.NewStyle().
 			PaddingLeft(2).
@@ -525,17 +1294,13 @@ func (m Model) viewHistoryAddDetails() string {
 
 	s := titleStyle.Render("bkmk: Add from History") + "\n"
 	groupName := "Unknown"
-	if m.selectedGroupValid() {
-		groupName = m.groups[m.selectedGroup].Name
+	if m.currentGroupValid() {
+		groupName = m.groupPath
 	}
 	s += groupStyle.Render("Group: "+groupName) + "\n"
 
 	// Show selected command preview
-	cmdPreview := m.selectedHistCmd
-	if len(cmdPreview) > m.width-4 && m.width > 20 {
-		cmdPreview = cmdPreview[:m.width-7] + "..."
-	}
-	s += cmdPreviewStyle.Render("Command: "+cmdPreview) + "\n\n"
+	s += cmdPreviewStyle.Render(previewCommand(m.selectedHistCmd, m.width)) + "\n\n"
 
 	labels := []string{"Name:", "Description:"}
 	for i, label := range labels {
@@ -575,6 +1340,9 @@ func (m Model) viewAddGroup() string {
 		MarginTop(1)
 
 	s := titleStyle.Render("bkmk: Add Group") + "\n\n"
+	if m.addGroupParent != "" {
+		s += lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Render("Parent: "+m.addGroupParent) + "\n\n"
+	}
 	s += labelStyle.Render("Name:") + "\n"
 	s += m.formInputs[0].View() + "\n"
 
@@ -639,12 +1407,12 @@ func (m Model) viewAddCommand() string {
 		Foreground(lipgloss.Color("241")).
 		MarginTop(1)
 
-	labels := []string{"Name:", "Command:", "Description:"}
+	labels := []string{"Name:", "Command:", "Description:", "Tags:"}
 
 	s := titleStyle.Render("bkmk: Add Command") + "\n"
 	groupName := "Unknown"
-	if m.selectedGroupValid() {
-		groupName = m.groups[m.selectedGroup].Name
+	if m.currentGroupValid() {
+		groupName = m.groupPath
 	}
 	s += lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Render("Group: "+groupName) + "\n\n"
 
@@ -687,12 +1455,12 @@ func (m Model) viewEditCommand() string {
 		Foreground(lipgloss.Color("241")).
 		MarginTop(1)
 
-	labels := []string{"Name:", "Command:", "Description:"}
+	labels := []string{"Name:", "Command:", "Description:", "Tags:"}
 
 	s := titleStyle.Render("bkmk: Edit Command") + "\n"
 	groupName := "Unknown"
-	if m.selectedGroupValid() {
-		groupName = m.groups[m.selectedGroup].Name
+	if m.currentGroupValid() {
+		groupName = m.groupPath
 	}
 	s += lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Render("Group: "+groupName) + "\n\n"
 
@@ -709,6 +1477,9 @@ func (m Model) viewEditCommand() string {
 		s += errorStyle.Render("Error: "+m.formError) + "\n"
 	}
 
+	if strings.Contains(m.formInputs[1].Value(), newlineMarker) {
+		s += helpStyle.Render(newlineMarker+" in the command marks a line break") + "\n"
+	}
 	s += helpStyle.Render("tab next field | enter submit | esc cancel")
 
 	return s
@@ -728,6 +1499,9 @@ func (m Model) viewDeleteConfirm() string {
 		Foreground(lipgloss.Color("241")).
 		MarginTop(1)
 
+	errorStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("196"))
+
 	s := titleStyle.Render("bkmk: Confirm Delete") + "\n\n"
 
 	if m.deleteTarget == deleteGroup {
@@ -735,6 +1509,9 @@ func (m Model) viewDeleteConfirm() string {
 	} else {
 		s += messageStyle.Render(fmt.Sprintf("Delete command '%s' from group '%s'?", m.deleteCmdName, m.deleteGroupName)) + "\n"
 	}
+	if m.formError != "" {
+		s += errorStyle.Render("Error: "+m.formError) + "\n"
+	}
 
 	s += "\n" + helpStyle.Render("y confirm | n/esc cancel")
 
@@ -768,12 +1545,46 @@ func (m Model) viewActionSelect() string {
 	s := titleStyle.Render("bkmk: Select Action") + "\n\n"
 
 	if m.actionCmd != nil {
-		// Show command preview
-		cmdPreview := m.actionCmd.Command
-		if len(cmdPreview) > m.width-4 && m.width > 20 {
-			cmdPreview = cmdPreview[:m.width-7] + "..."
+		// Show command preview, as it will run once confirmed
+		preview := m.actionCmd.Summary()
+		if m.confirming && m.confirmCommand != "" {
+			preview = m.confirmCommand
+		}
+		s += cmdPreviewStyle.Render(previewCommand(preview, m.width)) + "\n\n"
+
+		// And where it will run, when that is not here
+		env, err := m.config.Environment(*m.actionCmd)
+		if err != nil {
+			s += errorStyle.Render("Error: "+err.Error()) + "\n\n"
+		} else if lines := environmentLines(env); len(lines) > 0 {
+			envStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("141"))
+			for _, line := range lines {
+				s += itemStyle.Render(envStyle.Render(ansi.Truncate(line, max(m.width-4, 10), "..."))) + "\n"
+			}
+			s += "\n"
+		}
+
+		reason := m.config.Danger(*m.actionCmd)
+		if m.confirming {
+			reason = m.confirmReason
+		}
+		if reason != "" {
+			warning := "⚠ Dangerous: " + reason
+			if m.config.RefusesDefaultRun(*m.actionCmd) {
+				warning += "; its default run is ignored"
+			}
+			s += lipgloss.NewStyle().Foreground(lipgloss.Color("214")).Bold(true).Render(warning) + "\n\n"
 		}
-		s += cmdPreviewStyle.Render("Command: "+cmdPreview) + "\n\n"
+	}
+
+	if m.confirming {
+		s += "Type " + lipgloss.NewStyle().Bold(true).Render(m.actionCmd.Name) + " to run it:\n"
+		s += m.formInputs[0].View() + "\n"
+		if m.formError != "" {
+			s += errorStyle.Render("Error: "+m.formError) + "\n"
+		}
+		s += helpStyle.Render("enter run | esc back")
+		return s
 	}
 
 	actions := []struct {
@@ -781,7 +1592,10 @@ func (m Model) viewActionSelect() string {
 		name string
 	}{
 		{"r", "Run command"},
+		{"o", "Run here, showing output"},
+		{"s", "Run here in the terminal"},
 		{"c", "Copy to clipboard"},
+		{"i", "Insert into prompt (print on exit)"},
 		{"", "Cancel"},
 	}
 
@@ -803,7 +1617,56 @@ func (m Model) viewActionSelect() string {
 		s += "\n" + errorStyle.Render("Error: "+m.actionError)
 	}
 
-	s += "\n" + helpStyle.Render("j/k navigate | enter select | r run | c copy | esc cancel")
+	s += "\n" + helpStyle.Render("j/k navigate | enter select | r run | o run here | s run in terminal | c copy | i insert | esc cancel")
+
+	return s
+}
+
+func (m Model) viewParamInput() string {
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("205")).
+		MarginBottom(1)
+
+	cmdPreviewStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("245")).
+		MarginBottom(1)
+
+	labelStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("141"))
+
+	focusedLabelStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("170")).
+		Bold(true)
+
+	errorStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("196")).
+		MarginTop(1)
+
+	helpStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("241")).
+		MarginTop(1)
+
+	s := titleStyle.Render("bkmk: Fill in Placeholders") + "\n\n"
+
+	if m.actionCmd != nil {
+		s += cmdPreviewStyle.Render(previewCommand(m.actionCmd.Summary(), m.width)) + "\n\n"
+	}
+
+	for i, p := range m.paramList {
+		style := labelStyle
+		if i == m.formFocus {
+			style = focusedLabelStyle
+		}
+		s += style.Render(p.Name+":") + "\n"
+		s += m.formInputs[i].View() + "\n\n"
+	}
+
+	if m.formError != "" {
+		s += errorStyle.Render("Error: "+m.formError) + "\n"
+	}
+
+	s += helpStyle.Render("tab next field | enter submit | esc cancel")
 
 	return s
 }
@@ -0,0 +1,235 @@
+// Package usage records how often and how recently each bookmark is used.
+// It is kept in its own state file so the hand-edited config is never
+// rewritten just because a command was run.
+package usage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/sammcj/bkmk/internal/config"
+)
+
+// FileName is the usage state file, stored next to the config.
+const FileName = "usage.json"
+
+// Stat is the usage of one bookmark, keyed by its ID.
+type Stat struct {
+	ID       int       `json:"id"`
+	Runs     int       `json:"runs"`
+	Copies   int       `json:"copies"`
+	Inserts  int       `json:"inserts"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+// Uses returns the total number of times the bookmark was used.
+func (s Stat) Uses() int {
+	return s.Runs + s.Copies + s.Inserts
+}
+
+// Store holds usage stats for all bookmarks.
+type Store struct {
+	path  string
+	stats map[int]*Stat
+	// pending are the uses recorded since the store was loaded or last
+	// saved, which Save adds to whatever the file holds by then.
+	pending map[int]*Stat
+}
+
+type storeFile struct {
+	Commands []Stat `json:"commands"`
+}
+
+// DefaultPath returns the usage file path in the directory of the config in use.
+func DefaultPath() (string, error) {
+	cfgPath, err := config.Path()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(cfgPath), FileName), nil
+}
+
+// Load reads the usage file from the default location.
+func Load() (*Store, error) {
+	path, err := DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	return LoadFrom(path)
+}
+
+// LoadFrom reads the usage file at path. A missing file is an empty store.
+func LoadFrom(path string) (*Store, error) {
+	stats, err := readStats(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{path: path, stats: stats, pending: make(map[int]*Stat)}, nil
+}
+
+// readStats reads the stats in the usage file at path, if there is one.
+func readStats(path string) (map[int]*Stat, error) {
+	stats := make(map[int]*Stat)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return stats, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file storeFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse usage file %s: %w", path, err)
+	}
+	for _, stat := range file.Commands {
+		stats[stat.ID] = &stat
+	}
+	return stats, nil
+}
+
+// Save adds the uses recorded since the last save to the file it was loaded
+// from. The file is re-read under a lock first, so uses another bkmk process
+// saved in the meantime are kept, and the store picks them up.
+func (s *Store) Save() error {
+	unlock, err := config.Lock(s.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	stats, err := readStats(s.path)
+	if err != nil {
+		return err
+	}
+	for _, use := range s.pending {
+		add(stats, *use)
+	}
+
+	merged := &Store{stats: stats}
+	file := storeFile{Commands: merged.All()}
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := config.WriteFileAtomic(s.path, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	s.stats = stats
+	s.pending = make(map[int]*Stat)
+	return nil
+}
+
+// Record counts one use of the bookmark with the given ID.
+func (s *Store) Record(id int, action config.ActionType, at time.Time) {
+	use := Stat{ID: id, LastUsed: at}
+	switch action {
+	case config.ActionRun:
+		use.Runs = 1
+	case config.ActionCopy:
+		use.Copies = 1
+	case config.ActionInsert:
+		use.Inserts = 1
+	}
+	add(s.stats, use)
+	add(s.pending, use)
+}
+
+// add adds the counts in use to the stat for its ID in stats.
+func add(stats map[int]*Stat, use Stat) {
+	stat, ok := stats[use.ID]
+	if !ok {
+		stat = &Stat{ID: use.ID}
+		stats[use.ID] = stat
+	}
+	stat.Runs += use.Runs
+	stat.Copies += use.Copies
+	stat.Inserts += use.Inserts
+	if use.LastUsed.After(stat.LastUsed) {
+		stat.LastUsed = use.LastUsed
+	}
+}
+
+// Get returns the stats for a bookmark, or a zero Stat if it was never used.
+func (s *Store) Get(id int) Stat {
+	if stat, ok := s.stats[id]; ok {
+		return *stat
+	}
+	return Stat{ID: id}
+}
+
+// All returns every recorded stat ordered by ID.
+func (s *Store) All() []Stat {
+	all := make([]Stat, 0, len(s.stats))
+	for _, stat := range s.stats {
+		all = append(all, *stat)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].ID < all[j].ID })
+	return all
+}
+
+// Score returns the bookmark's frecency: its use count weighted by how
+// recently it was last used. Unused bookmarks score 0.
+func (s *Store) Score(id int, now time.Time) float64 {
+	stat, ok := s.stats[id]
+	if !ok || stat.Uses() == 0 {
+		return 0
+	}
+
+	age := now.Sub(stat.LastUsed)
+	weight := 0.25
+	switch {
+	case age < time.Hour:
+		weight = 4
+	case age < 24*time.Hour:
+		weight = 2
+	case age < 7*24*time.Hour:
+		weight = 1
+	case age < 30*24*time.Hour:
+		weight = 0.5
+	}
+	return float64(stat.Uses()) * weight
+}
+
+// Boost converts a frecency score into a bonus on the scale of fuzzy match
+// scores, growing slowly so heavy use cannot drown out a much better match.
+func Boost(score float64) int {
+	return int(math.Round(10 * math.Log1p(score)))
+}
+
+// SortByRecent orders stats by last use, most recent first.
+func SortByRecent(stats []Stat) {
+	sort.SliceStable(stats, func(i, j int) bool {
+		return stats[i].LastUsed.After(stats[j].LastUsed)
+	})
+}
+
+// SortByUses orders stats by total uses, most used first, breaking ties by
+// last use.
+func SortByUses(stats []Stat) {
+	sort.SliceStable(stats, func(i, j int) bool {
+		if stats[i].Uses() != stats[j].Uses() {
+			return stats[i].Uses() > stats[j].Uses()
+		}
+		return stats[i].LastUsed.After(stats[j].LastUsed)
+	})
+}
+
+// Track loads the default store, records one use and saves it. It is meant
+// for one-shot CLI commands; errors are returned for the caller to ignore or
+// report as it sees fit.
+func Track(id int, action config.ActionType) error {
+	s, err := Load()
+	if err != nil {
+		return err
+	}
+	s.Record(id, action, time.Now())
+	return s.Save()
+}
@@ -0,0 +1,123 @@
+package usage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sammcj/bkmk/internal/config"
+)
+
+func TestLoadMissingFile(t *testing.T) {
+	s, err := LoadFrom(filepath.Join(t.TempDir(), "usage.json"))
+	if err != nil {
+		t.Fatalf("LoadFrom failed: %v", err)
+	}
+	if len(s.All()) != 0 {
+		t.Error("expected empty store for missing file")
+	}
+}
+
+func TestRecordSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "usage.json")
+	s, _ := LoadFrom(path)
+
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	s.Record(3, config.ActionRun, now.Add(-time.Hour))
+	s.Record(3, config.ActionRun, now)
+	s.Record(3, config.ActionCopy, now.Add(-2*time.Hour))
+	s.Record(5, config.ActionInsert, now)
+
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom failed: %v", err)
+	}
+
+	got := loaded.Get(3)
+	if got.Runs != 2 || got.Copies != 1 || got.Uses() != 3 {
+		t.Errorf("unexpected stats: %+v", got)
+	}
+	if !got.LastUsed.Equal(now) {
+		t.Errorf("LastUsed should be the latest use, got %v", got.LastUsed)
+	}
+	if loaded.Get(5).Inserts != 1 {
+		t.Errorf("expected insert recorded, got %+v", loaded.Get(5))
+	}
+	if loaded.Get(99).Uses() != 0 {
+		t.Error("expected zero stats for unused command")
+	}
+}
+
+func TestSaveKeepsConcurrentUses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+	tui, _ := LoadFrom(path)
+	cli, _ := LoadFrom(path)
+
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	tui.Record(3, config.ActionCopy, now)
+	cli.Record(3, config.ActionRun, now.Add(time.Minute))
+	if err := cli.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if err := tui.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if got := tui.Get(3); got.Runs != 1 || got.Copies != 1 {
+		t.Errorf("store should pick up the other's uses on save, got %+v", got)
+	}
+
+	// A second save must not count the first one's uses again
+	tui.Record(3, config.ActionCopy, now)
+	if err := tui.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded, _ := LoadFrom(path)
+	got := loaded.Get(3)
+	if got.Runs != 1 || got.Copies != 2 || !got.LastUsed.Equal(now.Add(time.Minute)) {
+		t.Errorf("unexpected stats after concurrent saves: %+v", got)
+	}
+}
+
+func TestScoreFavoursFrequentAndRecent(t *testing.T) {
+	s, _ := LoadFrom(filepath.Join(t.TempDir(), "usage.json"))
+	now := time.Now()
+
+	for range 10 {
+		s.Record(1, config.ActionRun, now.Add(-2*time.Hour))
+	}
+	s.Record(2, config.ActionRun, now.Add(-2*time.Hour))
+	for range 10 {
+		s.Record(3, config.ActionRun, now.Add(-60*24*time.Hour))
+	}
+
+	if s.Score(1, now) <= s.Score(2, now) {
+		t.Error("more frequent command should score higher")
+	}
+	if s.Score(1, now) <= s.Score(3, now) {
+		t.Error("more recent command should score higher at equal frequency")
+	}
+	if s.Score(4, now) != 0 || Boost(0) != 0 {
+		t.Error("unused command should not be boosted")
+	}
+}
+
+func TestSortOrders(t *testing.T) {
+	now := time.Now()
+	stats := []Stat{
+		{ID: 1, Runs: 1, LastUsed: now},
+		{ID: 2, Runs: 5, LastUsed: now.Add(-time.Hour)},
+		{ID: 3, Runs: 1, LastUsed: now.Add(-time.Minute)},
+	}
+
+	SortByUses(stats)
+	if stats[0].ID != 2 || stats[1].ID != 1 {
+		t.Errorf("SortByUses order wrong: %+v", stats)
+	}
+	SortByRecent(stats)
+	if stats[0].ID != 1 || stats[2].ID != 2 {
+		t.Errorf("SortByRecent order wrong: %+v", stats)
+	}
+}
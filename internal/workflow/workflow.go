@@ -0,0 +1,112 @@
+// Package workflow tracks the progress of running a workflow bookmark's
+// steps, deciding which step runs next from the outcome of those before it.
+package workflow
+
+import (
+	"github.com/sammcj/bkmk/internal/config"
+	"github.com/sammcj/bkmk/internal/runner"
+)
+
+// Status is where a step is in a run.
+type Status int
+
+const (
+	Pending Status = iota
+	Running
+	Succeeded
+	Failed
+	Skipped
+)
+
+func (s Status) String() string {
+	switch s {
+	case Running:
+		return "running"
+	case Succeeded:
+		return "ok"
+	case Failed:
+		return "failed"
+	case Skipped:
+		return "skipped"
+	}
+	return "pending"
+}
+
+// Result is the outcome of one step.
+type Result struct {
+	Status Status
+	Exit   int // exit code, for steps that ran
+}
+
+// Run is a workflow in progress. Steps come from config.ResolveSteps, so each
+// has its command and failure policy filled in.
+type Run struct {
+	Steps   []config.Step
+	Results []Result
+	next    int
+}
+
+// New starts a run of steps, all pending.
+func New(steps []config.Step) *Run {
+	return &Run{Steps: steps, Results: make([]Result, len(steps))}
+}
+
+// Next returns the index of the step to run next and marks it running, or
+// false once every step has finished or been skipped.
+func (r *Run) Next() (int, bool) {
+	if r.next >= len(r.Steps) {
+		return 0, false
+	}
+	r.Results[r.next].Status = Running
+	return r.next, true
+}
+
+// Current returns the index of the step Next last started.
+func (r *Run) Current() int {
+	return r.next
+}
+
+// Finish records the outcome of the running step, given the error from
+// running it. A failure skips the remaining steps unless the step's policy
+// is to continue.
+func (r *Run) Finish(err error) {
+	i := r.next
+	r.next++
+	if err == nil {
+		r.Results[i] = Result{Status: Succeeded}
+		return
+	}
+	r.Results[i] = Result{Status: Failed, Exit: runner.ExitCode(err)}
+	if r.Steps[i].OnFailure != config.FailContinue {
+		r.Abort()
+	}
+}
+
+// Skip passes over the running step without running it, as when its
+// confirmation is declined.
+func (r *Run) Skip() {
+	r.Results[r.next].Status = Skipped
+	r.next++
+}
+
+// Abort skips every step that has not run yet.
+func (r *Run) Abort() {
+	for ; r.next < len(r.Steps); r.next++ {
+		r.Results[r.next].Status = Skipped
+	}
+}
+
+// Done reports whether no steps are left to run.
+func (r *Run) Done() bool {
+	return r.next >= len(r.Steps)
+}
+
+// ExitCode is the exit code of the first step that failed, or 0 if none did.
+func (r *Run) ExitCode() int {
+	for _, res := range r.Results {
+		if res.Status == Failed {
+			return res.Exit
+		}
+	}
+	return 0
+}
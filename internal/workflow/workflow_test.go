@@ -0,0 +1,67 @@
+package workflow
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sammcj/bkmk/internal/config"
+	"github.com/sammcj/bkmk/internal/runner"
+)
+
+func statuses(r *Run) []Status {
+	var got []Status
+	for _, res := range r.Results {
+		got = append(got, res.Status)
+	}
+	return got
+}
+
+func TestRunStopsOnFailure(t *testing.T) {
+	r := New([]config.Step{
+		{Command: "true", OnFailure: config.FailStop},
+		{Command: "exit 3", OnFailure: config.FailStop},
+		{Command: "true", OnFailure: config.FailStop},
+	})
+
+	for i, ok := r.Next(); ok; i, ok = r.Next() {
+		if r.Results[i].Status != Running {
+			t.Fatalf("step %d should be running, got %v", i, r.Results[i].Status)
+		}
+		r.Finish(runner.RunCommand(r.Steps[i].Command))
+	}
+
+	if want := []Status{Succeeded, Failed, Skipped}; !reflect.DeepEqual(statuses(r), want) {
+		t.Errorf("statuses = %v, want %v", statuses(r), want)
+	}
+	if r.ExitCode() != 3 || r.Results[1].Exit != 3 {
+		t.Errorf("expected exit code 3 from the failed step, got %d", r.ExitCode())
+	}
+}
+
+func TestRunContinuesAndSkips(t *testing.T) {
+	r := New([]config.Step{
+		{Command: "false", OnFailure: config.FailContinue},
+		{Command: "true", Confirm: true, OnFailure: config.FailStop},
+		{Command: "true", OnFailure: config.FailStop},
+	})
+
+	r.Next()
+	r.Finish(runner.RunCommand("false"))
+	r.Next()
+	r.Skip() // confirmation declined
+	if r.Done() {
+		t.Fatal("expected a step left to run")
+	}
+	r.Next()
+	r.Finish(nil)
+
+	if want := []Status{Failed, Skipped, Succeeded}; !reflect.DeepEqual(statuses(r), want) {
+		t.Errorf("statuses = %v, want %v", statuses(r), want)
+	}
+	if _, ok := r.Next(); ok || !r.Done() {
+		t.Error("expected the run to be done")
+	}
+	if r.ExitCode() != 1 {
+		t.Errorf("ExitCode() = %d, want 1", r.ExitCode())
+	}
+}